@@ -0,0 +1,154 @@
+// Package analysis answers the questions that editors and the REPL ask about source code, like the
+// names that can be completed at some position or the description of a symbol, from its syntax tree
+// and the resolution of its symbols.
+//
+// Code being edited is often incomplete, so a file whose forms are not closed is analyzed as if
+// they were.
+package analysis
+
+import (
+	"errors"
+	"mooss/harp/ast"
+	"mooss/harp/diag"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"mooss/harp/resolve"
+)
+
+// File is a parsed and resolved source.
+type File struct {
+	Source *lex.Source
+	Forms  []ast.Expr
+	Info   *resolve.Info
+
+	// Err is the syntax error of the source, nil when it parses.
+	// When the error is fixed by closing the unclosed forms, Forms are the forms once closed,
+	// otherwise there are no forms.
+	Err error
+
+	// docs maps the names of definitions to their documentation.
+	docs ast.Table[string]
+
+	// parameters maps the names of functions to their parameters, the rest parameter being written
+	// `& name`.
+	parameters ast.Table[[]string]
+
+	// visible maps the names of definitions to the offset from which they are visible, when it is
+	// not the end of the name.
+	visible ast.Table[int]
+}
+
+// NewFile parses and resolves a source, in an environment defining the predeclared names.
+func NewFile(src *lex.Source, predeclared []string) *File {
+	file := &File{
+		Source:     src,
+		docs:       ast.Table[string]{},
+		parameters: ast.Table[[]string]{},
+		visible:    ast.Table[int]{},
+	}
+	file.Forms, file.Err = parse.NewParser(lex.NewSourceLexer(src)).Parse()
+	if file.Err != nil {
+		file.Forms = closed(file.Err, src.Content)
+	}
+	file.Info = resolve.Resolve(file.Forms, predeclared)
+
+	for _, form := range file.Forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			switch node := expr.(type) {
+			case ast.Def:
+				file.document(node.Name, node.Meta)
+				file.visible.Set(node.Name, node.End().Offset)
+				if lambda, ok := node.Value.(ast.Lambda); ok {
+					file.sign(node.Name, lambda.Parameters, lambda.Rest)
+				}
+			case ast.Fun:
+				file.document(node.Name, node.Meta)
+				file.sign(node.Name, node.Parameters, node.Rest)
+			case ast.Struct:
+				file.document(node.Name, node.Meta)
+				file.visible.Set(node.Name, node.End().Offset)
+			case ast.Let:
+				file.bound(node.Bindings)
+			case ast.Loop:
+				file.bound(node.Bindings)
+			}
+			return true
+		})
+	}
+	return file
+}
+
+// closed returns the forms of input with its unclosed forms closed, or no forms when err is not
+// fixed by doing so.
+func closed(err error, input string) []ast.Expr {
+	if !errors.Is(err, parse.EofInForm) {
+		return []ast.Expr{}
+	}
+	fixes := diag.Suggest(err, input)
+	if len(fixes) != 1 {
+		return []ast.Expr{}
+	}
+
+	forms, err := parse.NewParser(lex.NewLexer(diag.Apply(input, fixes[0].Edits))).Parse()
+	if err != nil {
+		return []ast.Expr{}
+	}
+	return forms
+}
+
+// document records the :doc metadata of a definition.
+func (file *File) document(name ast.Symbol, meta *ast.Map) {
+	if meta == nil {
+		return
+	}
+	for _, entry := range meta.Entries {
+		key, isKeyword := entry.Key.(ast.Keyword)
+		doc, isString := entry.Value.(ast.String)
+		if isKeyword && isString && key.Name == "doc" {
+			file.docs.Set(name, doc.Value)
+		}
+	}
+}
+
+// bound records that bindings are visible after their value.
+func (file *File) bound(bindings []ast.Binding) {
+	for _, binding := range bindings {
+		file.visible.Set(binding.Variable, binding.Value.End().Offset)
+	}
+}
+
+// visibleFrom returns the offset from which a local definition is visible.
+func (file *File) visibleFrom(def *resolve.Definition) int {
+	if offset, ok := file.visible.Get(def.Symbol); ok {
+		return offset
+	}
+	return def.Symbol.End().Offset
+}
+
+// Doc returns the documentation of a definition, empty when it has none.
+func (file *File) Doc(def *resolve.Definition) string {
+	doc, _ := file.docs.Get(def.Symbol)
+	return doc
+}
+
+// scopeAt returns the innermost scope containing offset.
+func (file *File) scopeAt(offset int) *resolve.Scope {
+	res := file.Info.File
+	for _, form := range file.Forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			if expr == nil || !inside(expr, offset) {
+				return false
+			}
+			if scope, ok := file.Info.Scopes.Get(expr); ok {
+				res = scope
+			}
+			return true
+		})
+	}
+	return res
+}
+
+// inside tells whether offset is strictly inside of a node, e.g. between its delimiters.
+func inside(node ast.Node, offset int) bool {
+	return node.Pos().Offset < offset && offset < node.End().Offset
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"mooss/harp/resolve"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Completion is a name that can be written at some position.
+type Completion struct {
+	Name string
+	Kind resolve.Kind
+	// Doc is the documentation of the definition, empty when it has none.
+	Doc string
+
+	Definition *resolve.Definition
+}
+
+// CompletionsAt returns the names visible at offset that complete the symbol ending at offset (see
+// Prefix), sorted by name.
+// Names are visible when they are defined in an enclosing scope, once defined: after the value of a
+// binding or of a def, but right after the name of a function or of a parameter. Since functions
+// can refer to the definitions that follow them, top-level names are also visible before their
+// definition. A name defined in several scopes is completed with its closest
+// definition.
+func CompletionsAt(file *File, offset int) []Completion {
+	input := file.Source.Content
+	if offset < 0 || offset > len(input) {
+		return nil
+	}
+	prefix := Prefix(input, offset)
+	if strings.HasPrefix(prefix, ":") { // Keywords are not defined.
+		return nil
+	}
+
+	res := []Completion{}
+	seen := map[string]bool{}
+	for scope := file.scopeAt(offset); scope != nil; scope = scope.Parent {
+		global := scope == file.Info.File || scope == file.Info.Universe
+		for _, name := range scope.Names() {
+			def, _ := scope.Lookup(name)
+			switch {
+			case seen[name] || !strings.HasPrefix(name, prefix):
+				continue
+			case def.Kind == resolve.Predeclared:
+			case global && def.Symbol.Pos().Offset < offset && offset <= file.visibleFrom(def):
+				continue // Being defined, e.g. in its own value.
+			case !global && file.visibleFrom(def) > offset:
+				continue
+			}
+			seen[name] = true
+			res = append(res, Completion{Name: name, Kind: def.Kind, Doc: file.Doc(def), Definition: def})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// delimiters are the characters ending symbols.
+const delimiters = " \t\r\n,()[]{}\"'`@^#;\\"
+
+// Prefix returns the part of the symbol or keyword ending at offset that precedes offset, empty
+// when offset does not follow one.
+func Prefix(input string, offset int) string {
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(input[:start])
+		if strings.ContainsRune(delimiters, r) {
+			break
+		}
+		start -= size
+	}
+	return input[start:offset]
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"mooss/harp/lex"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// file parses input, where | marks the offset of the completion, and returns the offset.
+func file(input string) (*File, int) {
+	offset := strings.Index(input, "|")
+	src := &lex.Source{Content: input[:offset] + input[offset+1:]}
+	return NewFile(src, []string{"print", "+", "nil"}), offset
+}
+
+func TestCompletionsAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Everything", "|", []string{"+", "nil", "print"}},
+		{"Prefix", "(pr|)", []string{"print"}},
+		{"Globals", "(def alpha 1)\n(fun apply [f] f)\n(a|)", []string{"alpha", "apply"}},
+		{"Later global", "(a|)\n(def alpha 1)", []string{"alpha"}},
+		{"Parameters", "(fun f [first & rest] |)", []string{"+", "f", "first", "nil", "print", "rest"}},
+		{"Out of scope", "(let [local 1] local)\n(l|)", []string{}},
+		{"Previous bindings", "(let [one 1 other (o|)])", []string{"one"}},
+		{"Own value", "(def total (+ t|))", []string{}},
+		{"Body of let", "(let [one 1 other 2] (o|))", []string{"one", "other"}},
+		{"Unclosed", "(fun f [arg]\n  (let [also 1]\n    (a|", []string{"also", "arg"}},
+		{"Name being defined", "(def alp|)", []string{}},
+		{"Keyword", "(f :pr|)", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, offset := file(tt.input)
+			var got []string
+			for _, completion := range CompletionsAt(f, offset) {
+				got = append(got, completion.Name)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompletionDetails(t *testing.T) {
+	f, offset := file("(def ^{:doc \"Twice x.\"} x 1)\n(let [x 2] (fun g [] (x|)))")
+	got := CompletionsAt(f, offset)
+	if len(got) != 1 {
+		t.Fatalf("expected a single completion, got %v", got)
+	}
+
+	// The local x hides the documented global.
+	if got[0].Kind.String() != "local" || got[0].Doc != "" || got[0].Definition.Symbol.Pos().Line != 2 {
+		t.Errorf("expected the local x, got %+v", got[0])
+	}
+
+	f, offset = file("(def ^{:doc \"Twice x.\"} x 1)\n(x|)")
+	if got := CompletionsAt(f, offset); len(got) != 1 || got[0].Doc != "Twice x." {
+		t.Errorf("expected the documentation of x, got %+v", got)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	for input, expected := range map[string]string{"|": "", "(fo|": "fo", "(f :k|": ":k", "'sym|": "sym", "(a b|c)": "b", "[é|": "é"} {
+		offset := strings.Index(input, "|")
+		if got := Prefix(input[:offset]+input[offset+1:], offset); got != expected {
+			t.Errorf("expected %q in %q, got %q", expected, input, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"fmt"
+	"mooss/harp/ast"
+	"mooss/harp/resolve"
+	"strings"
+)
+
+// Hover describes the symbol under some position.
+type Hover struct {
+	// Symbol is the symbol under the position, a use or a definition.
+	Symbol     ast.Symbol
+	Definition *resolve.Definition
+
+	// Signature shows how to call a function, e.g. `(f a & rest)`, and is the name of the symbol
+	// for other definitions.
+	Signature string
+	// Doc is the documentation of the definition, empty when it has none.
+	Doc string
+}
+
+// HoverAt describes the symbol whose text contains offset or ends at offset, like a cursor right
+// after a name, and returns false when there is no such symbol or when it is not bound.
+func HoverAt(file *File, offset int) (Hover, bool) {
+	var symbol ast.Symbol
+	for _, form := range file.Forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			if expr == nil || offset < expr.Pos().Offset || offset > expr.End().Offset {
+				return false
+			}
+			if s, ok := expr.(ast.Symbol); ok {
+				symbol = s
+			}
+			return true
+		})
+	}
+
+	def, ok := file.Info.Uses.Get(symbol)
+	if !ok {
+		if def, ok = file.Info.Defs.Get(symbol); !ok {
+			return Hover{}, false
+		}
+	}
+	return Hover{Symbol: symbol, Definition: def, Signature: file.signature(def), Doc: file.Doc(def)}, true
+}
+
+// String renders the hover as plain text: the signature, where the name is defined, then the
+// documentation if any.
+func (h Hover) String() string {
+	var res strings.Builder
+	res.WriteString(h.Signature + "\n")
+	if h.Definition.Kind == resolve.Predeclared {
+		res.WriteString("predeclared")
+	} else {
+		fmt.Fprintf(&res, "%s defined at line %d column %d", h.Definition.Kind, h.Definition.Symbol.Pos().Line,
+			h.Definition.Symbol.Pos().Column)
+	}
+	if h.Doc != "" {
+		res.WriteString("\n\n" + h.Doc)
+	}
+	return res.String()
+}
@@ -0,0 +1,45 @@
+package analysis
+
+import "testing"
+
+func TestHoverAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Function",
+			input:    "(fun ^{:doc \"Adds.\"} add [a & more] a)\n(a|dd 1 2)",
+			expected: "(add a & more)\nfunction defined at line 1 column 21\n\nAdds.",
+		},
+		{
+			name:     "Lambda",
+			input:    "(def twice (lambda [x] (+ x x)))\n(twice| 1)",
+			expected: "(twice x)\nglobal defined at line 1 column 5",
+		},
+		{"Definition", "(def |x 1)", "x\nglobal defined at line 1 column 5"},
+		{"Parameter", "(fun f [n] (+ |n 1))", "n\nparameter defined at line 1 column 8"},
+		{"Predeclared", "(|print 1)", "print\npredeclared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, offset := file(tt.input)
+			hover, ok := HoverAt(f, offset)
+			if !ok {
+				t.Fatal("expected a hover")
+			}
+			if got := hover.String(); got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+
+	for _, input := range []string{"(unbound| 1)", "(f 1| 2)", "|"} {
+		f, offset := file(input)
+		if hover, ok := HoverAt(f, offset); ok {
+			t.Errorf("expected no hover in %q, got %v", input, hover)
+		}
+	}
+}
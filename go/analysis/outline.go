@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/resolve"
+)
+
+// FoldingRange is a range of lines that an editor can fold, lines starting at 1.
+type FoldingRange struct {
+	StartLine, EndLine int
+}
+
+// FoldingRanges returns the ranges of the top-level forms and of the forms with a body (fun, lambda,
+// let, loop, when and struct) that span several lines, sorted by start line.
+// Only the outermost of the forms starting on the same line is folded.
+func FoldingRanges(file *File) []FoldingRange {
+	res := []FoldingRange{}
+	last := 0 // Start line of the last range.
+	fold := func(node ast.Node) {
+		// Forms are folded in source order, so start lines never decrease.
+		if start, end := node.Pos().Line, node.End().Line; start < end && start > last {
+			res = append(res, FoldingRange{start, end})
+			last = start
+		}
+	}
+
+	for _, form := range file.Forms {
+		fold(form)
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			switch expr.(type) {
+			case nil:
+				return false
+			case ast.Fun, ast.Lambda, ast.Let, ast.Loop, ast.When, ast.Struct:
+				fold(expr)
+			}
+			return true
+		})
+	}
+	return res
+}
+
+// OutlineItem is a definition of the outline of a file.
+type OutlineItem struct {
+	// Name is the symbol defining the name, Node the whole definition.
+	Name ast.Symbol
+	Node ast.Expr
+	// Kind is Global for def, Function for fun and Struct for struct.
+	Kind resolve.Kind
+	// Doc is the documentation of the definition, empty when it has none.
+	Doc string
+
+	// Children are the definitions nested in the definition, e.g. in the body of a function.
+	Children []OutlineItem
+}
+
+// Outline returns the definitions of a file (def, fun and struct) in source order, nesting the
+// definitions made inside of other ones. Quoted definitions are not part of the outline.
+func Outline(file *File) []OutlineItem {
+	res := []OutlineItem{}
+	for _, form := range file.Forms {
+		res = append(res, file.outline(form)...)
+	}
+	return res
+}
+
+// outline returns the outermost definitions of a tree.
+func (file *File) outline(expr ast.Expr) []OutlineItem {
+	res := []OutlineItem{}
+	ast.Inspect(expr, func(expr ast.Expr) bool {
+		item := OutlineItem{Node: expr}
+		var children []ast.Expr
+		switch node := expr.(type) {
+		case ast.Def:
+			item.Name, item.Kind, children = node.Name, resolve.Global, []ast.Expr{node.Value}
+		case ast.Fun:
+			item.Name, item.Kind, children = node.Name, resolve.Function, node.Body
+		case ast.Struct:
+			item.Name, item.Kind = node.Name, resolve.Struct
+			for _, field := range node.Fields {
+				children = append(children, field.Value)
+			}
+		case ast.Quote, ast.Quasiquote:
+			return false
+		default:
+			return true
+		}
+
+		item.Doc, _ = file.docs.Get(item.Name)
+		for _, child := range children {
+			item.Children = append(item.Children, file.outline(child)...)
+		}
+		res = append(res, item)
+		return false
+	})
+	return res
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"fmt"
+	"mooss/harp/lex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	input := `(def x 1)
+(fun f [n]
+  (let [m (+ n 1)
+        l (lambda [a]
+            a)]
+    (when [m
+           l]
+      [else n])))
+[1
+ 2]
+(struct P [x 0]
+  [y '(let []
+        1)])
+(let [x 1]`
+	file := NewFile(&lex.Source{Content: input}, nil)
+
+	expected := []FoldingRange{{2, 8}, {3, 8}, {4, 5}, {6, 8}, {9, 10}, {11, 13}, {12, 13}}
+	if got := FoldingRanges(file); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	input := `(def ^{:doc "The answer."} answer 42)
+(fun f [n]
+  (def local (lambda [] (fun inner [] n)))
+  '(def quoted 1))
+(struct Point [x (def origin 0)])
+(g (def nested 1))`
+	file := NewFile(&lex.Source{Content: input}, nil)
+
+	// show renders the outline as indented lines.
+	var show func(items []OutlineItem, indent string) []string
+	show = func(items []OutlineItem, indent string) []string {
+		res := []string{}
+		for _, item := range items {
+			line := fmt.Sprintf("%s%s %s %s-%s", indent, item.Kind, item.Name.Name, item.Node.Pos(), item.Node.End())
+			if item.Doc != "" {
+				line += " " + item.Doc
+			}
+			res = append(res, line)
+			res = append(res, show(item.Children, indent+"  ")...)
+		}
+		return res
+	}
+
+	expected := `global answer 1:0-1:37 The answer.
+function f 2:0-4:18
+  global local 3:2-3:42
+    function inner 3:24-3:40
+struct Point 5:0-5:33
+  global origin 5:17-5:31
+global nested 6:3-6:17`
+	if got := strings.Join(show(Outline(file), ""), "\n"); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,196 @@
+package analysis
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/position"
+	"mooss/harp/resolve"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// SemanticType is the class of a token as known once symbols are resolved, which the lexer cannot
+// tell, like a function rather than a variable.
+type SemanticType uint8
+
+const (
+	// FunctionToken is a name of function: defined by fun, or bound to a lambda, or a predeclared
+	// name being called.
+	FunctionToken SemanticType = iota
+	// VariableToken is a name of global or local variable.
+	VariableToken
+	// ParameterToken is a name of parameter.
+	ParameterToken
+	// StructToken is a name of struct.
+	StructToken
+	// PropertyToken is a name of field of a struct.
+	PropertyToken
+	// KeywordToken is the head of a special form, like def.
+	KeywordToken
+)
+
+// SemanticTypes is the legend of the semantic types: their names in the Language Server Protocol,
+// indexed by type.
+var SemanticTypes = [...]string{
+	FunctionToken:  "function",
+	VariableToken:  "variable",
+	ParameterToken: "parameter",
+	StructToken:    "struct",
+	PropertyToken:  "property",
+	KeywordToken:   "keyword",
+}
+
+func (t SemanticType) String() string {
+	if int(t) < len(SemanticTypes) {
+		return SemanticTypes[t]
+	}
+	return "unknown semantic type"
+}
+
+// SemanticModifiers is a set of modifiers, one bit per modifier.
+type SemanticModifiers uint32
+
+const (
+	// Declaration marks the names being defined.
+	Declaration SemanticModifiers = 1 << iota
+	// DefaultLibrary marks the predeclared names.
+	DefaultLibrary
+)
+
+// SemanticModifierNames is the legend of the modifiers: their names in the Language Server
+// Protocol, indexed by bit.
+var SemanticModifierNames = [...]string{"declaration", "defaultLibrary"}
+
+// SemanticToken is a range of source code with a semantic type.
+type SemanticToken struct {
+	Offset, Length int
+	Type           SemanticType
+	Modifiers      SemanticModifiers
+}
+
+// SemanticTokens classifies the resolved names of a file and the heads of its special forms, in
+// source order. Unbound and quoted symbols are left to lexical highlighting.
+func SemanticTokens(file *File) []SemanticToken {
+	res := []SemanticToken{}
+	// callees are the symbols called as functions, fields the names of the fields of structs.
+	callees, fields := ast.Table[bool]{}, ast.Table[bool]{}
+	for _, form := range file.Forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			switch node := expr.(type) {
+			case nil:
+				return false
+			case ast.Symbol:
+				if tok, ok := file.classify(node, callees, fields); ok {
+					res = append(res, tok)
+				}
+				return true
+			case ast.Call:
+				callees.Set(node.Function, true)
+				return true
+			case ast.Access:
+				start, end := node.Field.Pos().Offset, node.Field.End().Offset
+				res = append(res, SemanticToken{Offset: start, Length: end - start, Type: PropertyToken})
+				return true
+			case ast.Struct:
+				for _, field := range node.Fields {
+					fields.Set(field.Variable, true)
+				}
+			case ast.Assign, ast.Break, ast.Continue, ast.Def, ast.Fun, ast.Lambda, ast.Let, ast.Loop, ast.Tie,
+				ast.When:
+			default:
+				return true
+			}
+
+			if tok, ok := file.head(expr); ok {
+				res = append(res, tok)
+			}
+			return true
+		})
+	}
+
+	slices.SortFunc(res, func(a, b SemanticToken) int { return a.Offset - b.Offset })
+	return res
+}
+
+// classify returns the semantic token of a symbol, and false when it is not resolved.
+func (file *File) classify(symbol ast.Symbol, callees, fields ast.Table[bool]) (SemanticToken, bool) {
+	tok := SemanticToken{Offset: symbol.Pos().Offset, Length: symbol.End().Offset - symbol.Pos().Offset}
+	if field, _ := fields.Get(symbol); field {
+		tok.Type, tok.Modifiers = PropertyToken, Declaration
+		return tok, true
+	}
+	def, ok := file.Info.Uses.Get(symbol)
+	if !ok {
+		if def, ok = file.Info.Defs.Get(symbol); !ok {
+			return SemanticToken{}, false
+		}
+		tok.Modifiers = Declaration
+	}
+
+	_, function := file.parameters.Get(def.Symbol)
+	switch {
+	case def.Kind == resolve.Predeclared:
+		tok.Type, tok.Modifiers = VariableToken, DefaultLibrary
+		if callee, _ := callees.Get(symbol); callee {
+			tok.Type = FunctionToken
+		}
+	case function || def.Kind == resolve.Function:
+		tok.Type = FunctionToken
+	case def.Kind == resolve.Parameter:
+		tok.Type = ParameterToken
+	case def.Kind == resolve.Struct:
+		tok.Type = StructToken
+	default:
+		tok.Type = VariableToken
+	}
+	return tok, true
+}
+
+// head returns the keyword token of the head of a special form, read from the source code.
+func (file *File) head(form ast.Expr) (SemanticToken, bool) {
+	input := file.Source.Content
+	start := form.Pos().Offset + 1 // Right after the parenthesis.
+	for start < len(input) && strings.ContainsRune(" \t\r\n,", rune(input[start])) {
+		start++
+	}
+	end := start
+	for end < len(input) {
+		r, size := utf8.DecodeRuneInString(input[end:])
+		if strings.ContainsRune(delimiters, r) {
+			break
+		}
+		end += size
+	}
+
+	if end == start {
+		return SemanticToken{}, false
+	}
+	return SemanticToken{Offset: start, Length: end - start, Type: KeywordToken}, true
+}
+
+// EncodeSemanticTokens encodes tokens in the format of the Language Server Protocol: five integers
+// per token, its line and start column relative to the previous token, its length, its type and its
+// modifiers. Columns and lengths are in UTF-16 code units and tokens must be in source order.
+func EncodeSemanticTokens(text *position.Text, tokens []SemanticToken) ([]uint32, error) {
+	res := make([]uint32, 0, 5*len(tokens))
+	var previous position.Position
+	for _, tok := range tokens {
+		start, err := text.Position(tok.Offset, position.UTF16)
+		if err != nil {
+			return nil, err
+		}
+		end, err := text.Position(tok.Offset+tok.Length, position.UTF16)
+		if err != nil {
+			return nil, err
+		}
+
+		column := start.Column
+		if start.Line == previous.Line {
+			column -= previous.Column
+		}
+		res = append(res, uint32(start.Line-max(previous.Line, 1)), uint32(column), uint32(end.Column-start.Column),
+			uint32(tok.Type), uint32(tok.Modifiers))
+		previous = start
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"fmt"
+	"mooss/harp/lex"
+	"mooss/harp/position"
+	"reflect"
+	"testing"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	input := `(fun f [x] (print x y))
+(def g (lambda [] (f 1)))
+(struct P [field g])
+(let [n print] (set n.field 2) '(h n))`
+	file := NewFile(&lex.Source{Content: input}, []string{"print"})
+
+	expected := []string{
+		"fun keyword", "f function declaration", "x parameter declaration", "print function defaultLibrary",
+		"x parameter",
+		"def keyword", "g function declaration", "lambda keyword", "f function",
+		"struct keyword", "P struct declaration", "field property declaration", "g function",
+		"let keyword", "n variable declaration", "print variable defaultLibrary", "set keyword", "n variable",
+		"field property",
+	}
+	got := []string{}
+	for _, tok := range SemanticTokens(file) {
+		text := fmt.Sprintf("%s %s", input[tok.Offset:tok.Offset+tok.Length], tok.Type)
+		for i, name := range SemanticModifierNames {
+			if tok.Modifiers&(1<<i) != 0 {
+				text += " " + name
+			}
+		}
+		got = append(got, text)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestEncodeSemanticTokens(t *testing.T) {
+	input := "(def é 1)\n(fun f [] é) é"
+	tokens := SemanticTokens(NewFile(&lex.Source{Content: input}, nil))
+	got, err := EncodeSemanticTokens(position.NewText(input), tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []uint32{
+		0, 1, 3, uint32(KeywordToken), 0,
+		0, 4, 1, uint32(VariableToken), uint32(Declaration),
+		1, 1, 3, uint32(KeywordToken), 0,
+		0, 4, 1, uint32(FunctionToken), uint32(Declaration),
+		0, 5, 1, uint32(VariableToken), 0,
+		0, 3, 1, uint32(VariableToken), 0,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
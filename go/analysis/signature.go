@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/resolve"
+	"strings"
+)
+
+// SignatureHelp describes the function called by the form around some position, while its
+// arguments are being written.
+type SignatureHelp struct {
+	Call       ast.Call
+	Definition *resolve.Definition
+
+	// Parameters are the parameters of the function, the rest parameter being written `& name`.
+	Parameters []string
+	// Active is the index of the parameter receiving the argument at the position, -1 when there
+	// are more arguments than parameters.
+	Active int
+}
+
+// SignatureAt describes the function called by the innermost call containing offset, and returns
+// false when there is no such call or when its function is not defined by the file.
+//
+// The active parameter is the one of the argument containing or ending at offset, or of the next
+// argument when offset is between arguments.
+func SignatureAt(file *File, offset int) (SignatureHelp, bool) {
+	var call *ast.Call
+	for _, form := range file.Forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			if expr == nil || offset < expr.Pos().Offset || offset > expr.End().Offset {
+				return false
+			}
+			if node, ok := expr.(ast.Call); ok && inside(node, offset) {
+				call = &node
+			}
+			return true
+		})
+	}
+	if call == nil {
+		return SignatureHelp{}, false
+	}
+
+	function, ok := call.Function.(ast.Symbol)
+	if !ok {
+		return SignatureHelp{}, false
+	}
+	def, ok := file.Info.Uses.Get(function)
+	if !ok {
+		return SignatureHelp{}, false
+	}
+	params, ok := file.parameters.Get(def.Symbol)
+	if !ok {
+		return SignatureHelp{}, false
+	}
+
+	argument := 0
+	for _, arg := range call.Arguments {
+		if arg.End().Offset < offset {
+			argument++
+		}
+	}
+	return SignatureHelp{Call: *call, Definition: def, Parameters: params, Active: active(params, argument)}, true
+}
+
+// active returns the index of the parameter receiving an argument.
+func active(params []string, argument int) int {
+	switch {
+	case argument < len(params):
+		return argument
+	case len(params) > 0 && strings.HasPrefix(params[len(params)-1], "& "):
+		return len(params) - 1 // The rest parameter receives the extra arguments.
+	}
+	return -1
+}
+
+// String renders the signature with the active parameter between angle brackets, e.g.
+// `(add a <b> & more)`.
+func (h SignatureHelp) String() string {
+	elements := []string{h.Definition.Name}
+	for i, param := range h.Parameters {
+		if i == h.Active {
+			param = "<" + param + ">"
+		}
+		elements = append(elements, param)
+	}
+	return "(" + strings.Join(elements, " ") + ")"
+}
+
+// signature returns the signature of a definition, e.g. `(f a & rest)` for a function, and its name
+// otherwise.
+func (file *File) signature(def *resolve.Definition) string {
+	params, ok := file.parameters.Get(def.Symbol)
+	if !ok {
+		return def.Name
+	}
+	return "(" + strings.Join(append([]string{def.Name}, params...), " ") + ")"
+}
+
+// sign records the parameters of a function.
+func (file *File) sign(name ast.Symbol, params []ast.Symbol, rest *ast.Symbol) {
+	res := []string{}
+	for _, param := range params {
+		res = append(res, param.Name)
+	}
+	if rest != nil {
+		res = append(res, "& "+rest.Name)
+	}
+	file.parameters.Set(name, res)
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import "testing"
+
+func TestSignatureAt(t *testing.T) {
+	const add = "(fun add [a b & more] a)\n"
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"First argument", add + "(add |)", "(add <a> b & more)"},
+		{"In an argument", add + "(add 12|3 4)", "(add <a> b & more)"},
+		{"Between arguments", add + "(add 1 | 2)", "(add a <b> & more)"},
+		{"Rest", add + "(add 1 2 3 |)", "(add a b <& more>)"},
+		{"Unclosed", add + "(add 1 |", "(add a <b> & more)"},
+		{"Innermost call", "(fun f [x] x)\n(fun g [y z] y)\n(f (g 1 |))", "(g y <z>)"},
+		{"Nested argument", "(fun f [x y] x)\n(f [1 2 |] 3)", "(f <x> y)"},
+		{"Too many arguments", "(def f (lambda [x] x))\n(f 1 |)", "(f x)"},
+		{"No parameters", "(fun f [] 1)\n(f|)", "(f)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, offset := file(tt.input)
+			help, ok := SignatureAt(f, offset)
+			if !ok {
+				t.Fatal("expected a signature")
+			}
+			if got := help.String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	for _, input := range []string{"(print 1|)", "(unbound |)", "(fun f [x] x)\n|(f 1)", "((lambda [x] x) |)"} {
+		f, offset := file(input)
+		if help, ok := SignatureAt(f, offset); ok {
+			t.Errorf("expected no signature in %q, got %s", input, help)
+		}
+	}
+}
@@ -1,16 +1,32 @@
 package ast
 
-// expression is something that has a value.
-type expression any
+import "math/big"
+
+// Expr is a node that has a value: a form of the source code.
+// It is sealed, so the nodes of this file are the only expressions and a type switch listing all of
+// them is exhaustive, see Match. A nil Expr is an absent optional form, e.g. the value of (break).
+type Expr interface {
+	Node
+	expr()
+}
+
+// primitive lists the types of the values of primitives, so that the instances of Primitive are the
+// aliases below.
+type primitive interface {
+	int64 | *big.Int | float64 | string | bool | byte | rune
+}
 
 // Primitive represents a primitive value with generic type
-type Primitive[T any] struct {
-	Value T
+type Primitive[T primitive] struct {
+	Value T `json:"value"`
+	Span
 }
 
 // Primitives.
 type (
-	Int64   = Primitive[int64]
+	Int64 = Primitive[int64]
+	// BigInt is an integer literal outside the range of int64, the value is never mutated.
+	BigInt  = Primitive[*big.Int]
 	Float64 = Primitive[float64]
 	String  = Primitive[string]
 	Bool    = Primitive[bool]
@@ -20,84 +36,204 @@ type (
 
 // Symbol is a name with a value in an environment.
 type Symbol struct {
-	Name string
+	Name string `json:"name"`
+	Span
+}
+
+// Keyword is a name that evaluates to itself, written with a leading colon (the colon is not part
+// of the name).
+type Keyword struct {
+	Name string `json:"name"`
+	Span
 }
 
 // Call represents a function/method call.
 type Call struct {
-	Function  any
-	Arguments []expression
+	Function  Expr   `json:"function"`
+	Arguments []Expr `json:"arguments"`
+	Span
 }
 
 // Special forms.
 type (
+	// Assign sets a variable, or a field of a struct when Target is an Access.
 	Assign struct {
-		Target Symbol
-		Value  expression
+		Target Expr `json:"target"` // Symbol or Access.
+		Value  Expr `json:"value"`
+		Span
 	}
 
 	Binding struct {
-		Variable Symbol
-		Value    expression
+		Variable Symbol `json:"variable"`
+		Value    Expr   `json:"value"`
+		Span
 	}
 
 	Break struct {
-		Value expression
+		Value Expr `json:"value"`
+		Span
 	}
 
-	Continue struct{}
+	Continue struct {
+		Span
+	}
 
 	Def struct {
-		Name  Symbol
-		Value expression
+		Name  Symbol `json:"name"`
+		Value Expr   `json:"value"`
+		Meta  *Map   `json:"meta,omitempty"` // Metadata of the definition, nil when there is none.
+		Span
 	}
 
 	Fun struct {
-		Name       Symbol
-		Parameters []Symbol
-		Body       []expression
+		Name       Symbol   `json:"name"`
+		Meta       *Map     `json:"meta,omitempty"`
+		Parameters []Symbol `json:"parameters"`
+		Rest       *Symbol  `json:"rest,omitempty"` // Receives the extra arguments, nil when there is no rest parameter.
+		Body       []Expr   `json:"body"`
+		Span
 	}
 
 	Lambda struct {
-		Parameters []Symbol
-		Body       []expression
+		Parameters []Symbol `json:"parameters"`
+		Rest       *Symbol  `json:"rest,omitempty"`
+		Body       []Expr   `json:"body"`
+		Span
 	}
 
 	Let struct {
-		Bindings []Binding
-		Body     []expression
+		Bindings []Binding `json:"bindings"`
+		Body     []Expr    `json:"body"`
+		Span
 	}
 
 	Loop struct {
-		Bindings  []Binding
-		Condition expression
-		Body      []expression
+		Bindings  []Binding `json:"bindings"`
+		Condition Expr      `json:"condition"`
+		Body      []Expr    `json:"body"`
+		Span
 	}
 
 	Struct struct {
-		Name   Symbol
-		Fields []Binding
+		Name   Symbol    `json:"name"`
+		Meta   *Map      `json:"meta,omitempty"`
+		Fields []Binding `json:"fields"`
+		Span
 	}
 
 	Tie struct {
-		Function any
-		Args     []expression
+		Function Expr   `json:"function"`
+		Args     []Expr `json:"args"`
+		Span
 	}
 
 	When struct {
-		Clauses []WhenClause
-		Else    []expression
+		Clauses []WhenClause `json:"clauses"`
+		Else    []Expr       `json:"else"`
+		Span
 	}
 
 	WhenClause struct {
-		Condition expression
-		Body      []expression
+		Condition Expr   `json:"condition"`
+		Body      []Expr `json:"body"`
+		Span
+	}
+)
+
+// Quotation, the quoted form is kept as is instead of being evaluated.
+// Unquote and UnquoteSplice are only meaningful inside a Quasiquote.
+type (
+	Quote struct {
+		Form Expr `json:"form"`
+		Span
+	}
+
+	Quasiquote struct {
+		Form Expr `json:"form"`
+		Span
+	}
+
+	Unquote struct {
+		Form Expr `json:"form"`
+		Span
+	}
+
+	UnquoteSplice struct {
+		Form Expr `json:"form"`
+		Span
 	}
 )
 
+// Deref is the @form shorthand, reading the value held by a reference.
+type Deref struct {
+	Form Expr `json:"form"`
+	Span
+}
+
+// Access is the target.field shorthand, reading a field of a struct. The dot is written right after
+// the target and right before the name of the field.
+type Access struct {
+	Target Expr   `json:"target"`
+	Field  Symbol `json:"field"`
+	Span
+}
+
+// Meta attaches metadata to a form, written ^{:key value...} form, or ^:key form for {:key true}.
+// Metadata is meant for tools (documentation, linting...) and does not change the value of the form.
+// The metadata of definitions is stored in the definition itself rather than in a Meta node.
+type Meta struct {
+	Data Map  `json:"data"`
+	Form Expr `json:"form"`
+	Span
+}
+
 // Collections.
 type (
-	Array []any
-	Map   map[any]any
-	Set   map[any]struct{}
+	Array struct {
+		Elements []Expr `json:"elements"`
+		Span
+	}
+
+	// Map is a map literal, whose keys are atoms distinct in value. Entries are in source order.
+	Map struct {
+		Entries []Entry `json:"entries"`
+		Span
+	}
+
+	Entry struct {
+		Key   Expr `json:"key"`
+		Value Expr `json:"value"`
+	}
+
+	// Set is a set literal, whose elements are atoms distinct in value, in source order.
+	Set struct {
+		Elements []Expr `json:"elements"`
+		Span
+	}
 )
+
+func (Primitive[T]) expr()  {}
+func (Symbol) expr()        {}
+func (Keyword) expr()       {}
+func (Call) expr()          {}
+func (Assign) expr()        {}
+func (Break) expr()         {}
+func (Continue) expr()      {}
+func (Def) expr()           {}
+func (Fun) expr()           {}
+func (Lambda) expr()        {}
+func (Let) expr()           {}
+func (Loop) expr()          {}
+func (Struct) expr()        {}
+func (Tie) expr()           {}
+func (When) expr()          {}
+func (Quote) expr()         {}
+func (Quasiquote) expr()    {}
+func (Unquote) expr()       {}
+func (UnquoteSplice) expr() {}
+func (Deref) expr()         {}
+func (Access) expr()        {}
+func (Meta) expr()          {}
+func (Array) expr()         {}
+func (Map) expr()           {}
+func (Set) expr()           {}
@@ -0,0 +1,142 @@
+package ast
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// Equal tells whether two trees have the same structure and values, ignoring their positions and
+// identifiers, so that parsed trees can be compared with trees built by code.
+// Nil and empty lists are not equal, like with reflect.DeepEqual.
+func Equal(a, b Expr) bool {
+	return len(Diff(a, b)) == 0
+}
+
+// Difference is a place where two trees diverge.
+type Difference struct {
+	// Path leads from the root to the place, through field names and list indices, e.g.
+	// `.Body[1].Function`. It is empty for the roots themselves.
+	Path string
+	// A and B are the values found in each tree, nil when a list is too short to have a value at
+	// Path.
+	A, B any
+}
+
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "root"
+	}
+	return fmt.Sprintf("%s: %s != %s", path, show(d.A), show(d.B))
+}
+
+// show renders the value of a difference, expressions as source code.
+func show(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case Expr:
+		return Print(value)
+	case *big.Int:
+		return value.String()
+	}
+	return fmt.Sprintf("%#v", value)
+}
+
+// Diff returns the differences between two trees, in the order of their fields, ignoring positions
+// and identifiers like Equal.
+// Only the first place where a path diverges is reported: the children of nodes of different types
+// are not compared, and the elements of a list that are missing from the other one are reported as
+// a whole.
+func Diff(a, b Expr) []Difference {
+	var d differ
+	d.diff("", reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	return d.res
+}
+
+// DiffAll returns the differences between two lists of forms, like Diff, the paths starting with
+// the index of the forms.
+func DiffAll(a, b []Expr) []Difference {
+	var d differ
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.res
+}
+
+type differ struct {
+	res []Difference
+}
+
+func (d *differ) report(path string, a, b reflect.Value) {
+	diff := Difference{Path: path}
+	if a.IsValid() {
+		diff.A = a.Interface()
+	}
+	if b.IsValid() {
+		diff.B = b.Interface()
+	}
+	d.res = append(d.res, diff)
+}
+
+// diff compares two values of the same static type.
+func (d *differ) diff(path string, a, b reflect.Value) {
+	switch a.Kind() {
+	case reflect.Interface:
+		switch {
+		case a.IsNil() || b.IsNil():
+			if a.IsNil() != b.IsNil() {
+				d.report(path, a, b)
+			}
+		case a.Elem().Type() != b.Elem().Type():
+			d.report(path, a, b)
+		default:
+			d.diff(path, a.Elem(), b.Elem())
+		}
+
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.report(path, a, b)
+			}
+			return
+		}
+		if x, ok := a.Interface().(*big.Int); ok { // Its representation is not canonical.
+			if x.Cmp(b.Interface().(*big.Int)) != 0 {
+				d.report(path, a, b)
+			}
+			return
+		}
+		d.diff(path, a.Elem(), b.Elem())
+
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			d.report(path, a, b)
+			return
+		}
+		for i := range max(a.Len(), b.Len()) {
+			index := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= a.Len():
+				d.report(index, reflect.Value{}, b.Index(i))
+			case i >= b.Len():
+				d.report(index, a.Index(i), reflect.Value{})
+			default:
+				d.diff(index, a.Index(i), b.Index(i))
+			}
+		}
+
+	case reflect.Struct:
+		for i := range a.NumField() {
+			field := a.Type().Field(i)
+			if field.Type == reflect.TypeFor[Span]() {
+				continue
+			}
+			d.diff(path+"."+field.Name, a.Field(i), b.Field(i))
+		}
+
+	default:
+		if !a.Equal(b) {
+			d.report(path, a, b)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	at := func(offset int) Span { return Span{Pos{1, offset, offset}, Pos{1, offset + 1, offset + 1}, 1} }
+	tests := []struct {
+		name  string
+		a, b  Expr
+		equal bool
+	}{
+		{"Positions", Symbol{"x", at(0)}, Symbol{"x", at(3)}, true},
+		{"Names", sym("x"), sym("y"), false},
+		{"Types", i64(1), Float64{Value: 1}, false},
+		{"Absent", Break{}, Break{}, true},
+		{"Absent and present", Break{}, Break{Value: i64(1)}, false},
+		{"Nested positions", array(Int64{1, at(1)}), Array{[]Expr{Int64{1, at(4)}}, at(3)}, true},
+		{"Nil and empty lists", Array{}, Array{Elements: []Expr{}}, false},
+		{"Metadata", Def{Name: sym("x")}, Def{Name: sym("x"), Meta: &Map{}}, false},
+		{
+			"Metadata positions",
+			Def{Name: sym("x"), Meta: &Map{Entries: []Entry{}, Span: at(2)}},
+			Def{Name: sym("x"), Meta: &Map{Entries: []Entry{}}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b); got != tt.equal {
+				t.Errorf("expected %t, got %t: %v", tt.equal, got, Diff(tt.a, tt.b))
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	// (let [x 1] (f x 2) y) and (let [z 1] (g x) 3)
+	a := Let{
+		Bindings: []Binding{{Variable: sym("x"), Value: i64(1)}},
+		Body:     []Expr{Call{Function: sym("f"), Arguments: []Expr{sym("x"), i64(2)}}, sym("y")},
+	}
+	b := Let{
+		Bindings: []Binding{{Variable: sym("z"), Value: i64(1)}},
+		Body:     []Expr{Call{Function: sym("g"), Arguments: []Expr{sym("x")}}, i64(3)},
+	}
+
+	expected := []string{
+		`.Bindings[0].Variable.Name: "x" != "z"`,
+		`.Body[0].Function.Name: "f" != "g"`,
+		`.Body[0].Arguments[1]: 2 != nil`,
+		`.Body[1]: y != 3`,
+	}
+	got := []string{}
+	for _, diff := range Diff(a, b) {
+		got = append(got, diff.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, got)
+	}
+
+	if diffs := Diff(nil, sym("x")); len(diffs) != 1 || diffs[0].String() != "root: nil != x" {
+		t.Errorf("expected the roots to differ, got %v", diffs)
+	}
+	if diffs := DiffAll([]Expr{sym("x")}, []Expr{sym("x"), sym("y")}); len(diffs) != 1 || diffs[0].Path != "[1]" {
+		t.Errorf("expected the second form to be missing, got %v", diffs)
+	}
+}
@@ -0,0 +1,307 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Nodes are encoded in JSON as objects whose "type" member tells the kind of node, e.g. "call" or
+// "int64", followed by the fields of the node under their lowercase name and by the "start", "end"
+// and "id" of its span, e.g.
+//
+//	{"type":"symbol","name":"x","start":{"line":1,"column":0,"offset":0},"end":{...},"id":1}
+//
+// The type is required to decode an Expr, since it decides which node is built. The identifier is
+// omitted when the node has none, and so are absent metadata and rest parameters.
+
+////////////
+// Errors //
+////////////
+
+// Failure describes why a JSON document cannot be decoded as a syntax tree.
+// It can be followed by additional information specified after `: `.
+type Failure string
+
+func (f Failure) Error() string {
+	return string(f)
+}
+
+// Is makes errors.Is compare failures by kind, ignoring the additional information.
+func (f Failure) Is(target error) bool {
+	other, ok := target.(Failure)
+	return ok && f.Cause() == other.Cause()
+}
+
+func (f Failure) Cause() string {
+	cause, _, _ := strings.Cut(string(f), ": ")
+	return cause
+}
+
+const (
+	MissingNodeType    Failure = "node has no type"
+	UnknownNodeType    Failure = "met unknown node type"
+	MismatchedNodeType Failure = "met node of another type"
+)
+
+// with adds the faulty value to a failure.
+func (f Failure) with(value string) Failure {
+	return Failure(string(f) + ": " + value)
+}
+
+///////////
+// Types //
+///////////
+
+// typeNames maps the types of the nodes to the name of their type in JSON.
+var typeNames = map[reflect.Type]string{
+	reflect.TypeFor[Int64]():         "int64",
+	reflect.TypeFor[BigInt]():        "bigint",
+	reflect.TypeFor[Float64]():       "float64",
+	reflect.TypeFor[String]():        "string",
+	reflect.TypeFor[Bool]():          "bool",
+	reflect.TypeFor[Byte]():          "byte",
+	reflect.TypeFor[Rune]():          "rune",
+	reflect.TypeFor[Symbol]():        "symbol",
+	reflect.TypeFor[Keyword]():       "keyword",
+	reflect.TypeFor[Call]():          "call",
+	reflect.TypeFor[Assign]():        "assign",
+	reflect.TypeFor[Binding]():       "binding",
+	reflect.TypeFor[Break]():         "break",
+	reflect.TypeFor[Continue]():      "continue",
+	reflect.TypeFor[Def]():           "def",
+	reflect.TypeFor[Fun]():           "fun",
+	reflect.TypeFor[Lambda]():        "lambda",
+	reflect.TypeFor[Let]():           "let",
+	reflect.TypeFor[Loop]():          "loop",
+	reflect.TypeFor[Struct]():        "struct",
+	reflect.TypeFor[Tie]():           "tie",
+	reflect.TypeFor[When]():          "when",
+	reflect.TypeFor[WhenClause]():    "when-clause",
+	reflect.TypeFor[Quote]():         "quote",
+	reflect.TypeFor[Quasiquote]():    "quasiquote",
+	reflect.TypeFor[Unquote]():       "unquote",
+	reflect.TypeFor[UnquoteSplice](): "unquote-splice",
+	reflect.TypeFor[Deref]():         "deref",
+	reflect.TypeFor[Access]():        "access",
+	reflect.TypeFor[Meta]():          "meta",
+	reflect.TypeFor[Array]():         "array",
+	reflect.TypeFor[Map]():           "map",
+	reflect.TypeFor[Set]():           "set",
+}
+
+// typesByName is the reverse of typeNames.
+var typesByName = func() map[string]reflect.Type {
+	res := make(map[string]reflect.Type, len(typeNames))
+	for typ, name := range typeNames {
+		res[name] = typ
+	}
+	return res
+}()
+
+//////////////
+// Encoding //
+//////////////
+
+func (p Primitive[T]) MarshalJSON() ([]byte, error)  { return marshalNode(p) }
+func (s Symbol) MarshalJSON() ([]byte, error)        { return marshalNode(s) }
+func (k Keyword) MarshalJSON() ([]byte, error)       { return marshalNode(k) }
+func (c Call) MarshalJSON() ([]byte, error)          { return marshalNode(c) }
+func (a Assign) MarshalJSON() ([]byte, error)        { return marshalNode(a) }
+func (b Binding) MarshalJSON() ([]byte, error)       { return marshalNode(b) }
+func (b Break) MarshalJSON() ([]byte, error)         { return marshalNode(b) }
+func (c Continue) MarshalJSON() ([]byte, error)      { return marshalNode(c) }
+func (d Def) MarshalJSON() ([]byte, error)           { return marshalNode(d) }
+func (f Fun) MarshalJSON() ([]byte, error)           { return marshalNode(f) }
+func (l Lambda) MarshalJSON() ([]byte, error)        { return marshalNode(l) }
+func (l Let) MarshalJSON() ([]byte, error)           { return marshalNode(l) }
+func (l Loop) MarshalJSON() ([]byte, error)          { return marshalNode(l) }
+func (s Struct) MarshalJSON() ([]byte, error)        { return marshalNode(s) }
+func (t Tie) MarshalJSON() ([]byte, error)           { return marshalNode(t) }
+func (w When) MarshalJSON() ([]byte, error)          { return marshalNode(w) }
+func (w WhenClause) MarshalJSON() ([]byte, error)    { return marshalNode(w) }
+func (q Quote) MarshalJSON() ([]byte, error)         { return marshalNode(q) }
+func (q Quasiquote) MarshalJSON() ([]byte, error)    { return marshalNode(q) }
+func (u Unquote) MarshalJSON() ([]byte, error)       { return marshalNode(u) }
+func (u UnquoteSplice) MarshalJSON() ([]byte, error) { return marshalNode(u) }
+func (d Deref) MarshalJSON() ([]byte, error)         { return marshalNode(d) }
+func (a Access) MarshalJSON() ([]byte, error)        { return marshalNode(a) }
+func (m Meta) MarshalJSON() ([]byte, error)          { return marshalNode(m) }
+func (a Array) MarshalJSON() ([]byte, error)         { return marshalNode(a) }
+func (m Map) MarshalJSON() ([]byte, error)           { return marshalNode(m) }
+func (s Set) MarshalJSON() ([]byte, error)           { return marshalNode(s) }
+
+// marshalNode encodes a node as an object starting with its type, followed by its fields.
+func marshalNode(node any) ([]byte, error) {
+	value := reflect.ValueOf(node)
+	var res bytes.Buffer
+	res.WriteString(`{"type":"` + typeNames[value.Type()] + `"`)
+	if err := marshalFields(&res, value); err != nil {
+		return nil, err
+	}
+	res.WriteByte('}')
+	return res.Bytes(), nil
+}
+
+// marshalFields writes the fields of a struct as members of an object, the fields of embedded
+// structs being members of the object as well.
+func marshalFields(res *bytes.Buffer, value reflect.Value) error {
+	for i := range value.NumField() {
+		field, typ := value.Field(i), value.Type().Field(i)
+		if typ.Anonymous {
+			if err := marshalFields(res, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, options, _ := strings.Cut(typ.Tag.Get("json"), ",")
+		if options == "omitempty" && field.IsZero() {
+			continue
+		}
+		data, err := json.Marshal(field.Interface())
+		if err != nil {
+			return err
+		}
+		res.WriteString(`,"` + name + `":`)
+		res.Write(data)
+	}
+	return nil
+}
+
+//////////////
+// Decoding //
+//////////////
+
+// UnmarshalExpr decodes an expression of any type, or nil from null.
+func UnmarshalExpr(data []byte) (Expr, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	var header struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.Type == nil {
+		return nil, MissingNodeType
+	}
+
+	typ, ok := typesByName[*header.Type]
+	if !ok || !typ.Implements(reflect.TypeFor[Expr]()) {
+		return nil, UnknownNodeType.with(*header.Type)
+	}
+	node := reflect.New(typ)
+	if err := unmarshalNode(data, node.Interface()); err != nil {
+		return nil, err
+	}
+	return node.Elem().Interface().(Expr), nil
+}
+
+// UnmarshalExprs decodes an array of expressions, or nil from null.
+func UnmarshalExprs(data []byte) ([]Expr, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil || elements == nil {
+		return nil, err
+	}
+
+	res := make([]Expr, len(elements))
+	for i, element := range elements {
+		var err error
+		if res[i], err = UnmarshalExpr(element); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (p *Primitive[T]) UnmarshalJSON(data []byte) error  { return unmarshalNode(data, p) }
+func (s *Symbol) UnmarshalJSON(data []byte) error        { return unmarshalNode(data, s) }
+func (k *Keyword) UnmarshalJSON(data []byte) error       { return unmarshalNode(data, k) }
+func (c *Call) UnmarshalJSON(data []byte) error          { return unmarshalNode(data, c) }
+func (a *Assign) UnmarshalJSON(data []byte) error        { return unmarshalNode(data, a) }
+func (b *Binding) UnmarshalJSON(data []byte) error       { return unmarshalNode(data, b) }
+func (b *Break) UnmarshalJSON(data []byte) error         { return unmarshalNode(data, b) }
+func (c *Continue) UnmarshalJSON(data []byte) error      { return unmarshalNode(data, c) }
+func (d *Def) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, d) }
+func (f *Fun) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, f) }
+func (l *Lambda) UnmarshalJSON(data []byte) error        { return unmarshalNode(data, l) }
+func (l *Let) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, l) }
+func (l *Loop) UnmarshalJSON(data []byte) error          { return unmarshalNode(data, l) }
+func (s *Struct) UnmarshalJSON(data []byte) error        { return unmarshalNode(data, s) }
+func (t *Tie) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, t) }
+func (w *When) UnmarshalJSON(data []byte) error          { return unmarshalNode(data, w) }
+func (w *WhenClause) UnmarshalJSON(data []byte) error    { return unmarshalNode(data, w) }
+func (q *Quote) UnmarshalJSON(data []byte) error         { return unmarshalNode(data, q) }
+func (q *Quasiquote) UnmarshalJSON(data []byte) error    { return unmarshalNode(data, q) }
+func (u *Unquote) UnmarshalJSON(data []byte) error       { return unmarshalNode(data, u) }
+func (u *UnquoteSplice) UnmarshalJSON(data []byte) error { return unmarshalNode(data, u) }
+func (d *Deref) UnmarshalJSON(data []byte) error         { return unmarshalNode(data, d) }
+func (a *Access) UnmarshalJSON(data []byte) error        { return unmarshalNode(data, a) }
+func (m *Meta) UnmarshalJSON(data []byte) error          { return unmarshalNode(data, m) }
+func (a *Array) UnmarshalJSON(data []byte) error         { return unmarshalNode(data, a) }
+func (m *Map) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, m) }
+func (s *Set) UnmarshalJSON(data []byte) error           { return unmarshalNode(data, s) }
+
+// UnmarshalJSON decodes an entry, which has no type since it is not a node.
+func (e *Entry) UnmarshalJSON(data []byte) error { return unmarshalNode(data, e) }
+
+// unmarshalNode decodes the object encoding a node into the node pointed to by node, checking its
+// type when it has one. Members missing from the object leave their field as is.
+func unmarshalNode(data []byte, node any) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		return err
+	}
+
+	value := reflect.ValueOf(node).Elem()
+	if kind, ok := typeNames[value.Type()]; ok {
+		raw, ok := members["type"]
+		if !ok {
+			return MissingNodeType
+		}
+		var typ string
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return err
+		}
+		if typ != kind {
+			return MismatchedNodeType.with(typ + " instead of " + kind)
+		}
+	}
+	return unmarshalFields(members, value)
+}
+
+// unmarshalFields decodes the members of an object into the fields of a struct, the fields of
+// embedded structs being members of the object as well.
+func unmarshalFields(members map[string]json.RawMessage, value reflect.Value) error {
+	for i := range value.NumField() {
+		field, typ := value.Field(i), value.Type().Field(i)
+		if typ.Anonymous {
+			if err := unmarshalFields(members, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, _, _ := strings.Cut(typ.Tag.Get("json"), ",")
+		raw, ok := members[name]
+		if !ok {
+			continue
+		}
+		var err error
+		switch field := field.Addr().Interface().(type) {
+		case *Expr: // Interfaces are decoded from the type of their value.
+			*field, err = UnmarshalExpr(raw)
+		case *[]Expr:
+			*field, err = UnmarshalExprs(raw)
+		default:
+			err = json.Unmarshal(raw, field)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
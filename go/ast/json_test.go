@@ -0,0 +1,154 @@
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	// at builds a span on the first line, identified by its start column.
+	at := func(start, stop int) Span {
+		return Span{Pos{1, start, start}, Pos{1, stop, stop}, NodeID(start + 1)}
+	}
+	x := Symbol{"x", at(1, 2)}
+	meta := &Map{Entries: []Entry{{Keyword{"doc", at(3, 7)}, String{"docs", at(8, 14)}}}, Span: at(2, 15)}
+
+	tests := []Expr{
+		Int64{42, at(0, 2)},
+		Float64{-1.5, at(0, 4)},
+		String{"hi\n\"there\"", at(0, 12)},
+		Bool{true, at(0, 4)},
+		Byte{'b', at(0, 4)},
+		Rune{'é', at(0, 3)},
+		x,
+		Keyword{"k", at(0, 2)},
+		Call{Function: x, Arguments: []Expr{Int64{1, at(3, 4)}}, Span: at(0, 5)},
+		Call{Function: x, Arguments: []Expr{}, Span: at(0, 3)},
+		Assign{Target: x, Value: Bool{false, at(3, 8)}, Span: at(0, 9)},
+		Break{Span: at(0, 7)},
+		Break{Value: x, Span: at(0, 9)},
+		Continue{Span: at(0, 10)},
+		Def{Name: x, Value: Int64{1, at(3, 4)}, Span: at(0, 5)},
+		Def{Name: x, Value: Int64{1, at(16, 17)}, Meta: meta, Span: at(0, 18)},
+		Fun{Name: x, Meta: meta, Parameters: []Symbol{}, Rest: &Symbol{"rest", at(20, 24)}, Body: []Expr{x},
+			Span: at(0, 30)},
+		Lambda{Parameters: []Symbol{x}, Body: []Expr{}, Span: at(0, 8)},
+		Let{Bindings: []Binding{{Variable: x, Value: Int64{1, at(3, 4)}, Span: at(1, 4)}}, Body: []Expr{x}, Span: at(0, 9)},
+		Loop{Bindings: []Binding{}, Condition: Bool{true, at(3, 7)}, Body: []Expr{}, Span: at(0, 8)},
+		Struct{Name: x, Fields: []Binding{{Variable: Symbol{"f", at(3, 4)}, Value: x, Span: at(3, 6)}}, Span: at(0, 7)},
+		Tie{Function: x, Args: []Expr{Keyword{"k", at(3, 5)}}, Span: at(0, 6)},
+		When{
+			Clauses: []WhenClause{{Condition: Bool{true, at(3, 7)}, Body: []Expr{x}, Span: at(3, 9)}},
+			Else:    []Expr{},
+			Span:    at(0, 10),
+		},
+		Quote{x, at(0, 2)},
+		Quasiquote{Array{[]Expr{Unquote{x, at(2, 4)}, UnquoteSplice{x, at(5, 8)}}, at(1, 9)}, at(0, 9)},
+		Deref{x, at(0, 2)},
+		Access{Target: x, Field: Symbol{"f", at(2, 3)}, Span: at(1, 3)},
+		Assign{Target: Access{Target: x, Field: Symbol{"f", at(8, 9)}, Span: at(5, 9)}, Value: x, Span: at(0, 12)},
+		Meta{Data: *meta, Form: x, Span: at(0, 17)},
+		Map{Entries: []Entry{{Keyword{"a", at(1, 3)}, Set{[]Expr{x}, at(4, 7)}}}, Span: at(0, 8)},
+		Set{Span: at(0, 3)},
+		sym("no position"),
+	}
+
+	for _, expr := range tests {
+		data, err := json.Marshal(expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", Print(expr), err)
+			continue
+		}
+
+		got, err := UnmarshalExpr(data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", data, err)
+		} else if !reflect.DeepEqual(got, expr) {
+			t.Errorf("%s: expected %#v, got %#v", data, expr, got)
+		}
+
+		// Nodes are decoded in place as well.
+		ptr := reflect.New(reflect.TypeOf(expr))
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			t.Errorf("%s: unexpected error in place: %s", data, err)
+		} else if !reflect.DeepEqual(ptr.Elem().Interface(), expr) {
+			t.Errorf("%s: expected %#v in place, got %#v", data, expr, ptr.Elem().Interface())
+		}
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{
+			Symbol{"x", Span{Pos{1, 0, 0}, Pos{1, 1, 1}, 1}},
+			`{"type":"symbol","name":"x","start":{"line":1,"column":0,"offset":0},` +
+				`"end":{"line":1,"column":1,"offset":1},"id":1}`,
+		},
+		{
+			Lambda{Parameters: []Symbol{}, Rest: &Symbol{Name: "r"}, Body: []Expr{Break{}}},
+			`{"type":"lambda","parameters":[],"rest":{"type":"symbol","name":"r",` +
+				`"start":{"line":0,"column":0,"offset":0},"end":{"line":0,"column":0,"offset":0}},` +
+				`"body":[{"type":"break","value":null,` +
+				`"start":{"line":0,"column":0,"offset":0},"end":{"line":0,"column":0,"offset":0}}],` +
+				`"start":{"line":0,"column":0,"offset":0},"end":{"line":0,"column":0,"offset":0}}`,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := json.Marshal(test.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", Print(test.expr), err)
+		} else if string(got) != test.expected {
+			t.Errorf("%s: expected %s, got %s", Print(test.expr), test.expected, got)
+		}
+	}
+}
+
+func TestUnmarshalExprs(t *testing.T) {
+	got, err := UnmarshalExprs([]byte(`[{"type":"int64","value":1}, null, {"type":"symbol","name":"x"}]`))
+	expected := []Expr{Int64{Value: 1}, nil, sym("x")}
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v (%v)", expected, got, err)
+	}
+
+	if got, err := UnmarshalExprs([]byte(`null`)); got != nil || err != nil {
+		t.Errorf("expected no expressions from null, got %#v (%v)", got, err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{`{"name":"x"}`, MissingNodeType},
+		{`{"type":"variable","name":"x"}`, UnknownNodeType},
+		{`{"type":"binding"}`, UnknownNodeType}, // Not an expression.
+		{`{"type":"call","function":{"name":"f"}}`, MissingNodeType},
+		{`{"type":"call","arguments":[{"type":"macro"}]}`, UnknownNodeType},
+		{`{"type":"def","name":{"type":"keyword","name":"x"}}`, MismatchedNodeType},
+		{`{"type":"let","bindings":[{"type":"binding","value":{}}]}`, MissingNodeType},
+		{`{"type":"map","entries":[{"key":{"type":"int64","value":1},"value":{"value":2}}]}`, MissingNodeType},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalExpr([]byte(test.input))
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %q, got %v", test.input, test.expected, err)
+		}
+	}
+
+	var syntax *json.SyntaxError
+	if _, err := UnmarshalExpr([]byte(`{"type":`)); !errors.As(err, &syntax) {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if _, err := UnmarshalExpr([]byte(`{"type":"int64","value":"one"}`)); !errors.As(err, &typeErr) {
+		t.Errorf("expected a type error, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package ast
+
+// Cases handles every kind of expression, with one method per kind.
+// Since Expr is sealed, an implementation of Cases handles all the expressions: adding a kind of
+// node adds a method to Cases, so that every analysis using Match fails to compile until it handles
+// the new kind, unlike a type switch which silently falls to its default case.
+type Cases[T any] interface {
+	Int64(Int64) T
+	BigInt(BigInt) T
+	Float64(Float64) T
+	String(String) T
+	Bool(Bool) T
+	Byte(Byte) T
+	Rune(Rune) T
+	Symbol(Symbol) T
+	Keyword(Keyword) T
+	Call(Call) T
+
+	Assign(Assign) T
+	Break(Break) T
+	Continue(Continue) T
+	Def(Def) T
+	Fun(Fun) T
+	Lambda(Lambda) T
+	Let(Let) T
+	Loop(Loop) T
+	Struct(Struct) T
+	Tie(Tie) T
+	When(When) T
+
+	Quote(Quote) T
+	Quasiquote(Quasiquote) T
+	Unquote(Unquote) T
+	UnquoteSplice(UnquoteSplice) T
+	Deref(Deref) T
+	Access(Access) T
+	Meta(Meta) T
+
+	Array(Array) T
+	Map(Map) T
+	Set(Set) T
+
+	// Absent handles a nil expression, the absent optional form.
+	Absent() T
+}
+
+// Match calls the method of cases handling the kind of expr.
+func Match[T any](expr Expr, cases Cases[T]) T {
+	switch expr := expr.(type) {
+	case Int64:
+		return cases.Int64(expr)
+	case BigInt:
+		return cases.BigInt(expr)
+	case Float64:
+		return cases.Float64(expr)
+	case String:
+		return cases.String(expr)
+	case Bool:
+		return cases.Bool(expr)
+	case Byte:
+		return cases.Byte(expr)
+	case Rune:
+		return cases.Rune(expr)
+	case Symbol:
+		return cases.Symbol(expr)
+	case Keyword:
+		return cases.Keyword(expr)
+	case Call:
+		return cases.Call(expr)
+	case Assign:
+		return cases.Assign(expr)
+	case Break:
+		return cases.Break(expr)
+	case Continue:
+		return cases.Continue(expr)
+	case Def:
+		return cases.Def(expr)
+	case Fun:
+		return cases.Fun(expr)
+	case Lambda:
+		return cases.Lambda(expr)
+	case Let:
+		return cases.Let(expr)
+	case Loop:
+		return cases.Loop(expr)
+	case Struct:
+		return cases.Struct(expr)
+	case Tie:
+		return cases.Tie(expr)
+	case When:
+		return cases.When(expr)
+	case Quote:
+		return cases.Quote(expr)
+	case Quasiquote:
+		return cases.Quasiquote(expr)
+	case Unquote:
+		return cases.Unquote(expr)
+	case UnquoteSplice:
+		return cases.UnquoteSplice(expr)
+	case Deref:
+		return cases.Deref(expr)
+	case Access:
+		return cases.Access(expr)
+	case Meta:
+		return cases.Meta(expr)
+	case Array:
+		return cases.Array(expr)
+	case Map:
+		return cases.Map(expr)
+	case Set:
+		return cases.Set(expr)
+	}
+
+	// Expr being sealed and primitives limited to the aliases above, only nil is left.
+	return cases.Absent()
+}
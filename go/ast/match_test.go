@@ -0,0 +1,65 @@
+package ast
+
+import "testing"
+
+// kinds names the kind of expressions.
+type kinds struct{}
+
+func (kinds) Int64(Int64) string                 { return "int64" }
+func (kinds) BigInt(BigInt) string               { return "bigint" }
+func (kinds) Float64(Float64) string             { return "float64" }
+func (kinds) String(String) string               { return "string" }
+func (kinds) Bool(Bool) string                   { return "bool" }
+func (kinds) Byte(Byte) string                   { return "byte" }
+func (kinds) Rune(Rune) string                   { return "rune" }
+func (kinds) Symbol(Symbol) string               { return "symbol" }
+func (kinds) Keyword(Keyword) string             { return "keyword" }
+func (kinds) Call(Call) string                   { return "call" }
+func (kinds) Assign(Assign) string               { return "set" }
+func (kinds) Break(Break) string                 { return "break" }
+func (kinds) Continue(Continue) string           { return "continue" }
+func (kinds) Def(Def) string                     { return "def" }
+func (kinds) Fun(Fun) string                     { return "fun" }
+func (kinds) Lambda(Lambda) string               { return "lambda" }
+func (kinds) Let(Let) string                     { return "let" }
+func (kinds) Loop(Loop) string                   { return "loop" }
+func (kinds) Struct(Struct) string               { return "struct" }
+func (kinds) Tie(Tie) string                     { return "tie" }
+func (kinds) When(When) string                   { return "when" }
+func (kinds) Quote(Quote) string                 { return "quote" }
+func (kinds) Quasiquote(Quasiquote) string       { return "quasiquote" }
+func (kinds) Unquote(Unquote) string             { return "unquote" }
+func (kinds) UnquoteSplice(UnquoteSplice) string { return "unquote-splice" }
+func (kinds) Deref(Deref) string                 { return "deref" }
+func (kinds) Access(Access) string               { return "access" }
+func (kinds) Meta(Meta) string                   { return "meta" }
+func (kinds) Array(Array) string                 { return "array" }
+func (kinds) Map(Map) string                     { return "map" }
+func (kinds) Set(Set) string                     { return "set literal" }
+func (kinds) Absent() string                     { return "absent" }
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{i64(1), "int64"},
+		{Byte{Value: 1}, "byte"},
+		{Rune{Value: 'a'}, "rune"},
+		{sym("x"), "symbol"},
+		{Call{Function: sym("f")}, "call"},
+		{Assign{Target: sym("x"), Value: i64(1)}, "set"},
+		{Continue{}, "continue"},
+		{UnquoteSplice{Form: sym("x")}, "unquote-splice"},
+		{Meta{Form: sym("x")}, "meta"},
+		{Access{Target: sym("p"), Field: sym("x")}, "access"},
+		{Set{}, "set literal"},
+		{nil, "absent"},
+	}
+
+	for _, tt := range tests {
+		if got := Match[string](tt.expr, kinds{}); got != tt.expected {
+			t.Errorf("expected %#v to be a %s, got %s", tt.expr, tt.expected, got)
+		}
+	}
+}
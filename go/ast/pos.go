@@ -0,0 +1,55 @@
+package ast
+
+import "fmt"
+
+// Pos is a location in the source code, counted like the positions of tokens: lines start at 1,
+// columns at 0 and offsets are in bytes.
+// The zero Pos is not valid, it is the position of nodes built by code rather than parsed.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// IsValid tells whether the position is in the source code.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the extent of a node in the source code, from its first character to right after its last
+// one, along with the identifier of the node. Nodes embed it to implement Node.
+type Span struct {
+	Start Pos    `json:"start"`
+	Stop  Pos    `json:"end"`
+	ID    NodeID `json:"id,omitempty"`
+}
+
+// Pos returns the position of the first character of the node.
+func (s Span) Pos() Pos {
+	return s.Start
+}
+
+// End returns the position right after the last character of the node.
+func (s Span) End() Pos {
+	return s.Stop
+}
+
+// Identity returns the identifier of the node.
+func (s Span) Identity() NodeID {
+	return s.ID
+}
+
+// Node is implemented by every node of the syntax tree, so that tools can point back to the source
+// code of a node and attach information to it.
+type Node interface {
+	Pos() Pos
+	End() Pos
+	Identity() NodeID
+}
@@ -0,0 +1,303 @@
+package ast
+
+import (
+	"fmt"
+	"mooss/harp/lex"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Printer renders nodes back into canonical Harp source.
+//
+// A form is written on a single line when it fits in Width, otherwise its elements are broken
+// across lines like the indent package expects them: the body of special forms is indented by
+// Indent columns relative to the opening parenthesis, the arguments of a call are aligned with the
+// first one and the elements of collections are aligned after the opening delimiter.
+//
+// Maps and sets are printed in the order of their entries, which is the order of the source code for
+// parsed nodes.
+// Negative numbers and non-finite floats have no literal syntax and are printed as is.
+type Printer struct {
+	// Indent is the number of columns by which the body of special forms is indented.
+	Indent int
+
+	// Width is the number of columns that forms should fit in, 0 meaning that they are never broken.
+	Width int
+}
+
+// DefaultPrinter is the printer used by Print and PrintAll.
+var DefaultPrinter = Printer{Indent: 2, Width: 80}
+
+// Print renders a node with the default printer.
+func Print(node Expr) string {
+	return DefaultPrinter.Print(node)
+}
+
+// PrintAll renders top-level forms with the default printer.
+func PrintAll(forms []Expr) string {
+	return DefaultPrinter.PrintAll(forms)
+}
+
+// Print renders a node, without trailing newline.
+func (pr Printer) Print(node Expr) string {
+	w := &writer{}
+	pr.write(w, toSexp(node))
+	return w.String()
+}
+
+// PrintAll renders top-level forms, one after the other and each followed by a newline.
+func (pr Printer) PrintAll(forms []Expr) string {
+	var res strings.Builder
+	for _, form := range forms {
+		res.WriteString(pr.Print(form))
+		res.WriteByte('\n')
+	}
+	return res.String()
+}
+
+// sexp is the printed structure of a node, either an atom or delimited elements.
+type sexp struct {
+	atom string
+
+	open, close string
+	elements    []sexp
+
+	// inline is the number of elements kept on the line of the opening delimiter when the sexp is
+	// broken across lines.
+	inline int
+
+	// body is true when the elements of a broken sexp are indented by Printer.Indent, and false
+	// when they are aligned with the second one (or after the opening delimiter when inline is 1).
+	body bool
+}
+
+// flat renders the sexp on a single line.
+func (s sexp) flat() string {
+	if s.elements == nil {
+		return s.atom
+	}
+
+	elements := make([]string, len(s.elements))
+	for i, element := range s.elements {
+		elements[i] = element.flat()
+	}
+	return s.open + strings.Join(elements, " ") + s.close
+}
+
+// writer builds the output while keeping track of the current column.
+type writer struct {
+	strings.Builder
+	column int
+}
+
+func (w *writer) write(text string) {
+	w.WriteString(text)
+	w.column += utf8.RuneCountInString(text)
+}
+
+func (w *writer) newline(indent int) {
+	w.WriteByte('\n')
+	w.WriteString(strings.Repeat(" ", indent))
+	w.column = indent
+}
+
+func (pr Printer) write(w *writer, s sexp) {
+	flat := s.flat()
+	if s.elements == nil || pr.Width <= 0 || w.column+utf8.RuneCountInString(flat) <= pr.Width {
+		w.write(flat)
+		return
+	}
+
+	start := w.column
+	w.write(s.open)
+	indent := w.column
+	if s.body {
+		indent = start + pr.Indent
+	}
+
+	for i, element := range s.elements {
+		switch {
+		case i == 0:
+		case i < s.inline:
+			w.write(" ")
+			if i == 1 && !s.body {
+				indent = w.column
+			}
+		default:
+			w.newline(indent)
+		}
+		pr.write(w, element)
+	}
+	w.write(s.close)
+}
+
+////////////////
+// Conversion //
+
+func atom(text string) sexp {
+	return sexp{atom: text}
+}
+
+// list builds a parenthesized sexp starting with a symbol.
+func list(head string, inline int, body bool, elements ...sexp) sexp {
+	return sexp{open: "(", close: ")", elements: append([]sexp{atom(head)}, elements...), inline: inline, body: body}
+}
+
+// prefixed builds a sexp made of a prefix immediately followed by a form, like a quote.
+func prefixed(prefix string, form Expr) sexp {
+	return sexp{open: prefix, elements: []sexp{toSexp(form)}, inline: 1}
+}
+
+func vector(elements []sexp) sexp {
+	return sexp{open: "[", close: "]", elements: elements, inline: 1}
+}
+
+// pair builds an undelimited key and value, as found in maps and bindings.
+func pair(key, value sexp) sexp {
+	return sexp{elements: []sexp{key, value}, inline: 2}
+}
+
+func forms(nodes []Expr) []sexp {
+	res := make([]sexp, len(nodes))
+	for i, node := range nodes {
+		res[i] = toSexp(node)
+	}
+	return res
+}
+
+func parameters(params []Symbol, rest *Symbol) sexp {
+	elements := []sexp{}
+	for _, param := range params {
+		elements = append(elements, atom(param.Name))
+	}
+	if rest != nil {
+		elements = append(elements, atom("&"), atom(rest.Name))
+	}
+	return vector(elements)
+}
+
+func bindings(nodes []Binding) sexp {
+	elements := make([]sexp, len(nodes))
+	for i, binding := range nodes {
+		elements[i] = pair(atom(binding.Variable.Name), toSexp(binding.Value))
+	}
+	return vector(elements)
+}
+
+// metadata builds the ^ prefix of a form, using the ^:key shorthand when possible.
+func metadata(data Map) sexp {
+	if len(data.Entries) == 1 {
+		key, isKeyword := data.Entries[0].Key.(Keyword)
+		value, isBool := data.Entries[0].Value.(Bool)
+		if isKeyword && isBool && value.Value {
+			return atom("^:" + key.Name)
+		}
+	}
+	return prefixed("^", data)
+}
+
+// name builds the name of a definition preceded by its metadata, if any.
+func name(meta *Map, symbol Symbol) sexp {
+	if meta == nil {
+		return atom(symbol.Name)
+	}
+	return pair(metadata(*meta), atom(symbol.Name))
+}
+
+func toSexp(node Expr) sexp {
+	switch node := node.(type) {
+	case Int64:
+		return atom(strconv.FormatInt(node.Value, 10))
+	case BigInt:
+		return atom(node.Value.String())
+	case Float64:
+		res := strconv.FormatFloat(node.Value, 'g', -1, 64)
+		if !strings.ContainsAny(res, ".eIN") { // Keep floats distinguishable from ints.
+			res += ".0"
+		}
+		return atom(res)
+	case String:
+		return atom(strconv.Quote(node.Value))
+	case Bool:
+		return atom(strconv.FormatBool(node.Value))
+	case Byte:
+		return atom(strconv.FormatUint(uint64(node.Value), 10))
+	case Rune:
+		return atom(lex.EncodeChar(node.Value))
+	case Symbol:
+		return atom(node.Name)
+	case Keyword:
+		return atom(":" + node.Name)
+	case Call:
+		return sexp{open: "(", close: ")", elements: forms(append([]Expr{node.Function}, node.Arguments...)), inline: 2}
+	case Array:
+		return vector(forms(node.Elements))
+	case Map:
+		pairs := []sexp{}
+		for _, entry := range node.Entries {
+			pairs = append(pairs, pair(toSexp(entry.Key), toSexp(entry.Value)))
+		}
+		return sexp{open: "{", close: "}", elements: pairs, inline: 1}
+	case Set:
+		return sexp{open: "#{", close: "}", elements: forms(node.Elements), inline: 1}
+	case Quote:
+		return prefixed("'", node.Form)
+	case Quasiquote:
+		return prefixed("`", node.Form)
+	case Unquote:
+		if _, ok := node.Form.(Deref); ok {
+			return prefixed(", ", node.Form) // ,@ would be read as a splice.
+		}
+		return prefixed(",", node.Form)
+	case UnquoteSplice:
+		return prefixed(",@", node.Form)
+	case Deref:
+		return prefixed("@", node.Form)
+	case Access:
+		return sexp{elements: []sexp{toSexp(node.Target)}, close: "." + node.Field.Name, inline: 1}
+	case Meta:
+		return pair(metadata(node.Data), toSexp(node.Form))
+	case Def:
+		return list("def", 2, true, name(node.Meta, node.Name), toSexp(node.Value))
+	case Assign:
+		return list("set", 2, true, toSexp(node.Target), toSexp(node.Value))
+	case Fun:
+		head := []sexp{name(node.Meta, node.Name), parameters(node.Parameters, node.Rest)}
+		return list("fun", 3, true, append(head, forms(node.Body)...)...)
+	case Lambda:
+		head := []sexp{parameters(node.Parameters, node.Rest)}
+		return list("lambda", 2, true, append(head, forms(node.Body)...)...)
+	case Let:
+		return list("let", 2, true, append([]sexp{bindings(node.Bindings)}, forms(node.Body)...)...)
+	case Loop:
+		head := []sexp{bindings(node.Bindings), toSexp(node.Condition)}
+		return list("loop", 3, true, append(head, forms(node.Body)...)...)
+	case Struct:
+		fields := make([]sexp, len(node.Fields))
+		for i, field := range node.Fields {
+			fields[i] = vector([]sexp{atom(field.Variable.Name), toSexp(field.Value)})
+		}
+		return list("struct", 2, true, append([]sexp{name(node.Meta, node.Name)}, fields...)...)
+	case Tie:
+		return list("tie", 2, false, append([]sexp{toSexp(node.Function)}, forms(node.Args)...)...)
+	case When:
+		clauses := []sexp{}
+		for _, clause := range node.Clauses {
+			clauses = append(clauses, vector(forms(append([]Expr{clause.Condition}, clause.Body...))))
+		}
+		if node.Else != nil {
+			clauses = append(clauses, vector(append([]sexp{atom("else")}, forms(node.Else)...)))
+		}
+		return list("when", 1, true, clauses...)
+	case Break:
+		if node.Value == nil {
+			return list("break", 1, true)
+		}
+		return list("break", 2, true, toSexp(node.Value))
+	case Continue:
+		return list("continue", 1, true)
+	}
+
+	return atom(fmt.Sprint(node))
+}
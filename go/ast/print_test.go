@@ -0,0 +1,118 @@
+package ast
+
+import (
+	"testing"
+)
+
+func sym(name string) Symbol       { return Symbol{Name: name} }
+func i64(value int64) Int64        { return Int64{Value: value} }
+func array(elements ...Expr) Array { return Array{Elements: elements} }
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		printer  Printer
+		node     Expr
+		expected string
+	}{
+		{
+			name: "Atoms",
+			node: array(
+				i64(42), Float64{Value: 2}, String{Value: "a\n\"b\""}, Rune{Value: ' '}, Bool{Value: true},
+				Keyword{Name: "k"}, sym("x"),
+			),
+			expected: `[42 2.0 "a\n\"b\"" \space true :k x]`,
+		},
+		{
+			name:     "Call",
+			node:     Call{Function: sym("f"), Arguments: []Expr{i64(1), Call{Function: sym("g")}}},
+			expected: "(f 1 (g))",
+		},
+		{
+			name: "Collections",
+			node: array(
+				Map{Entries: []Entry{{Keyword{Name: "b"}, i64(2)}, {Keyword{Name: "a"}, i64(1)}}},
+				Set{Elements: []Expr{sym("z"), sym("y")}},
+			),
+			expected: "[{:b 2 :a 1} #{z y}]",
+		},
+		{
+			name: "Quotation",
+			node: Quasiquote{Form: Call{Function: sym("f"), Arguments: []Expr{
+				Unquote{Form: sym("a")}, UnquoteSplice{Form: sym("b")}, Quote{Form: sym("c")}, Deref{Form: sym("d")},
+				Unquote{Form: Deref{Form: sym("e")}},
+			}}},
+			expected: "`(f ,a ,@b 'c @d , @e)",
+		},
+		{
+			name: "Field access",
+			node: Assign{
+				Target: Access{Target: Access{Target: sym("line"), Field: sym("start")}, Field: sym("x")},
+				Value:  Access{Target: Call{Function: sym("origin")}, Field: sym("x")},
+			},
+			expected: "(set line.start.x (origin).x)",
+		},
+		{
+			name: "Special forms",
+			node: array(
+				Fun{Name: sym("f"), Parameters: []Symbol{sym("a")}, Rest: &Symbol{Name: "r"}, Body: []Expr{sym("a")}},
+				Let{Bindings: []Binding{{Variable: sym("x"), Value: i64(1)}}, Body: []Expr{sym("x")}},
+				When{Clauses: []WhenClause{{Condition: sym("c")}}, Else: []Expr{}},
+				Break{}, Continue{},
+			),
+			expected: "[(fun f [a & r] a) (let [x 1] x) (when [c] [else]) (break) (continue)]",
+		},
+		{
+			name: "Metadata",
+			node: array(
+				Meta{Data: Map{Entries: []Entry{{Keyword{Name: "private"}, Bool{Value: true}}}}, Form: sym("x")},
+				Def{Name: sym("y"), Value: i64(1), Meta: &Map{Entries: []Entry{{Keyword{Name: "doc"}, String{Value: "Y."}}}}},
+			),
+			expected: `[^:private x (def ^{:doc "Y."} y 1)]`,
+		},
+		{
+			name:    "Broken special form",
+			printer: Printer{Indent: 4, Width: 20},
+			node: Fun{Name: sym("add"), Parameters: []Symbol{sym("a"), sym("b")}, Body: []Expr{
+				Call{Function: sym("print"), Arguments: []Expr{sym("a"), sym("b")}},
+				Call{Function: sym("+"), Arguments: []Expr{sym("a"), sym("b")}},
+			}},
+			expected: "(fun add [a b]\n    (print a b)\n    (+ a b))",
+		},
+		{
+			name:    "Broken call and bindings",
+			printer: Printer{Indent: 2, Width: 16},
+			node: Let{
+				Bindings: []Binding{{Variable: sym("first"), Value: i64(1)}, {Variable: sym("second"), Value: i64(2)}},
+				Body:     []Expr{Call{Function: sym("combine"), Arguments: []Expr{sym("first"), sym("second")}}},
+			},
+			expected: "(let [first 1\n      second 2]\n  (combine first\n           second))",
+		},
+		{
+			name:     "Unlimited width",
+			printer:  Printer{Indent: 2},
+			node:     Call{Function: sym("f"), Arguments: []Expr{String{Value: "a long string that would not fit in a narrow printer"}}},
+			expected: `(f "a long string that would not fit in a narrow printer")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := tt.printer
+			if printer == (Printer{}) {
+				printer = DefaultPrinter
+			}
+
+			if got := printer.Print(tt.node); got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrintAll(t *testing.T) {
+	got := PrintAll([]Expr{Def{Name: sym("x"), Value: i64(1)}, sym("x")})
+	if expected := "(def x 1)\nx\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,208 @@
+package ast
+
+import "reflect"
+
+// Rewrite transforms a tree from the bottom up: the children of each expression are rewritten
+// first, then fn receives the expression with its rewritten children and returns its replacement,
+// which is the expression itself to keep it.
+//
+// The result shares the parts of the tree that are not changed: an expression whose children are
+// all kept is not copied, so rewriting is cheap when few expressions change and the original tree
+// is never modified.
+// Like Walk, Rewrite follows the structure of the tree and goes into quoted forms, but the names of
+// definitions, the parameters of functions, the variables of bindings and metadata are kept as is.
+func Rewrite(expr Expr, fn func(Expr) Expr) Expr {
+	res, _ := rewriter{fn}.rewrite(expr)
+	return res
+}
+
+// rewriter rewrites a tree with fn, its methods telling whether their result differs from their
+// argument.
+type rewriter struct {
+	fn func(Expr) Expr
+}
+
+func (r rewriter) rewrite(expr Expr) (Expr, bool) {
+	if expr == nil {
+		return nil, false
+	}
+
+	changed := false
+	one := func(expr Expr) Expr {
+		res, ok := r.rewrite(expr)
+		changed = changed || ok
+		return res
+	}
+	list := func(exprs []Expr) []Expr {
+		res, ok := r.list(exprs)
+		changed = changed || ok
+		return res
+	}
+
+	original := expr
+	switch node := expr.(type) {
+	case Call:
+		node.Function = one(node.Function)
+		node.Arguments = list(node.Arguments)
+		expr = node
+	case Assign:
+		if _, ok := node.Target.(Access); ok { // The object of a field is not a name.
+			node.Target = one(node.Target)
+		}
+		node.Value = one(node.Value)
+		expr = node
+	case Break:
+		node.Value = one(node.Value)
+		expr = node
+	case Def:
+		node.Value = one(node.Value)
+		expr = node
+	case Fun:
+		node.Body = list(node.Body)
+		expr = node
+	case Lambda:
+		node.Body = list(node.Body)
+		expr = node
+	case Let:
+		node.Bindings = rewriteEach(node.Bindings, &changed, func(binding Binding) Binding {
+			binding.Value = one(binding.Value)
+			return binding
+		})
+		node.Body = list(node.Body)
+		expr = node
+	case Loop:
+		node.Bindings = rewriteEach(node.Bindings, &changed, func(binding Binding) Binding {
+			binding.Value = one(binding.Value)
+			return binding
+		})
+		node.Condition = one(node.Condition)
+		node.Body = list(node.Body)
+		expr = node
+	case Struct:
+		node.Fields = rewriteEach(node.Fields, &changed, func(field Binding) Binding {
+			field.Value = one(field.Value)
+			return field
+		})
+		expr = node
+	case Tie:
+		node.Function = one(node.Function)
+		node.Args = list(node.Args)
+		expr = node
+	case When:
+		node.Clauses = rewriteEach(node.Clauses, &changed, func(clause WhenClause) WhenClause {
+			clause.Condition = one(clause.Condition)
+			clause.Body = list(clause.Body)
+			return clause
+		})
+		node.Else = list(node.Else)
+		expr = node
+	case Quote:
+		node.Form = one(node.Form)
+		expr = node
+	case Quasiquote:
+		node.Form = one(node.Form)
+		expr = node
+	case Unquote:
+		node.Form = one(node.Form)
+		expr = node
+	case UnquoteSplice:
+		node.Form = one(node.Form)
+		expr = node
+	case Deref:
+		node.Form = one(node.Form)
+		expr = node
+	case Access:
+		node.Target = one(node.Target)
+		expr = node
+	case Meta:
+		node.Form = one(node.Form)
+		expr = node
+	case Array:
+		node.Elements = list(node.Elements)
+		expr = node
+	case Map:
+		node.Entries = rewriteEach(node.Entries, &changed, func(entry Entry) Entry {
+			entry.Key = one(entry.Key)
+			entry.Value = one(entry.Value)
+			return entry
+		})
+		expr = node
+	case Set:
+		node.Elements = list(node.Elements)
+		expr = node
+	}
+	if !changed {
+		expr = original
+	}
+
+	res := r.fn(expr)
+	return res, changed || !identical(res, expr)
+}
+
+func (r rewriter) list(exprs []Expr) ([]Expr, bool) {
+	changed := false
+	res := rewriteEach(exprs, &changed, func(expr Expr) Expr {
+		res, ok := r.rewrite(expr)
+		changed = changed || ok
+		return res
+	})
+	return res, changed
+}
+
+// rewriteEach applies rewrite to the elements of a list, which must set *changed when an element
+// changes. The list is copied on the first change and returned as is when nothing changes.
+func rewriteEach[T any](list []T, changed *bool, rewrite func(T) T) []T {
+	var res []T
+	for i, element := range list {
+		before := *changed
+		*changed = false
+		element = rewrite(element)
+		if *changed && res == nil {
+			res = append([]T{}, list...)
+		}
+		if *changed {
+			res[i] = element
+		}
+		*changed = *changed || before
+	}
+
+	if res == nil {
+		return list
+	}
+	return res
+}
+
+// identical tells whether two expressions have the same fields, lists being the same when they
+// share their elements. Nodes hold lists, so they cannot be compared with ==.
+func identical(a, b Expr) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return shallowEqual(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func shallowEqual(a, b reflect.Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len()
+	case reflect.Pointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		return shallowEqual(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !shallowEqual(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,52 @@
+package ast
+
+import "testing"
+
+func TestRewrite(t *testing.T) {
+	// (let [x (+ 1 2)] [x {:k (+ 3 4)}] (f x))
+	sum := func(a, b int64) Call { return Call{Function: sym("+"), Arguments: []Expr{i64(a), i64(b)}} }
+	tree := Let{
+		Bindings: []Binding{{Variable: sym("x"), Value: sum(1, 2)}},
+		Body: []Expr{
+			array(sym("x"), Map{Entries: []Entry{{Keyword{Name: "k"}, sum(3, 4)}}}),
+			Call{Function: sym("f"), Arguments: []Expr{sym("x")}},
+		},
+	}
+	before := Print(tree)
+
+	// fold replaces the sums of integers by their value.
+	fold := func(expr Expr) Expr {
+		call, ok := expr.(Call)
+		if function, isSymbol := call.Function.(Symbol); !ok || !isSymbol || function.Name != "+" {
+			return expr
+		}
+		res := int64(0)
+		for _, arg := range call.Arguments {
+			n, ok := arg.(Int64)
+			if !ok {
+				return expr
+			}
+			res += n.Value
+		}
+		return i64(res)
+	}
+
+	got := Rewrite(tree, fold).(Let)
+	if expected := "(let [x 3] [x {:k 7}] (f x))"; Print(got) != expected {
+		t.Errorf("expected %s, got %s", expected, Print(got))
+	}
+	if Print(tree) != before {
+		t.Errorf("expected the original tree to be kept, got %s", Print(tree))
+	}
+	if &got.Body[1].(Call).Arguments[0] != &tree.Body[1].(Call).Arguments[0] {
+		t.Error("expected the unchanged call to be shared")
+	}
+	if &got.Body[0] == &tree.Body[0] {
+		t.Error("expected the changed body to be copied")
+	}
+
+	identity := func(expr Expr) Expr { return expr }
+	if same := Rewrite(tree, identity).(Let); &same.Bindings[0] != &tree.Bindings[0] || &same.Body[0] != &tree.Body[0] {
+		t.Error("expected a rewrite keeping everything to share the whole tree")
+	}
+}
@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"iter"
+	"maps"
+	"slices"
+)
+
+// NodeID identifies a node among the nodes read by a parser, so that analyses can attach information
+// to nodes in side tables instead of changing or wrapping them.
+// Identifiers are assigned in the order in which the parser completes the nodes, children before
+// their parent, so they are the same every time the same source code is parsed.
+// The zero NodeID is not valid, it is the identifier of nodes built by code rather than parsed.
+type NodeID uint32
+
+// IsValid tells whether the identifier was assigned by a parser.
+func (id NodeID) IsValid() bool {
+	return id != 0
+}
+
+// Table associates a value with nodes, e.g. their type, their constant value or the definition a
+// symbol resolves to.
+// The nodes of a table must come from the same parser, otherwise their identifiers may collide.
+type Table[T any] map[NodeID]T
+
+// Set associates value with node, and reports false without doing so when node has no identifier.
+func (t Table[T]) Set(node Node, value T) bool {
+	if !node.Identity().IsValid() {
+		return false
+	}
+	t[node.Identity()] = value
+	return true
+}
+
+// Get returns the value associated with node, if any.
+func (t Table[T]) Get(node Node) (T, bool) {
+	value, ok := t[node.Identity()]
+	return value, ok
+}
+
+// All iterates over the identifiers and their values in the order of the identifiers.
+func (t Table[T]) All() iter.Seq2[NodeID, T] {
+	return func(yield func(NodeID, T) bool) {
+		for _, id := range slices.Sorted(maps.Keys(t)) {
+			if !yield(id, t[id]) {
+				return
+			}
+		}
+	}
+}
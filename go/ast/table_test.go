@@ -0,0 +1,35 @@
+package ast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	x := Symbol{Name: "x", Span: Span{ID: 2}}
+	one := Int64{Value: 1, Span: Span{ID: 1}}
+	unparsed := Symbol{Name: "y"}
+
+	types := Table[string]{}
+	if !types.Set(x, "int") || !types.Set(one, "int") {
+		t.Fatal("expected parsed nodes to be set")
+	}
+	if types.Set(unparsed, "int") {
+		t.Error("expected a node without identifier not to be set")
+	}
+
+	if got, ok := types.Get(x); !ok || got != "int" {
+		t.Errorf("expected x to be an int, got %q (%v)", got, ok)
+	}
+	if _, ok := types.Get(unparsed); ok {
+		t.Error("expected no value for a node without identifier")
+	}
+
+	ids := []NodeID{}
+	for id := range types.All() {
+		ids = append(ids, id)
+	}
+	if !slices.Equal(ids, []NodeID{1, 2}) {
+		t.Errorf("expected the identifiers in order, got %v", ids)
+	}
+}
@@ -0,0 +1,137 @@
+package ast
+
+// Visitor is called by Walk for each expression of a tree, like the visitors of go/ast.
+// If the result w of Visit(expr) is not nil, Walk visits each of the children of expr with w, then
+// calls w.Visit(nil).
+type Visitor interface {
+	Visit(expr Expr) (w Visitor)
+}
+
+// Walk traverses a tree in depth-first order, starting with v.Visit(expr), with the children of
+// each expression in source order.
+// Every expression of the tree is visited, including the names of definitions, the parameters of
+// functions, the variables of bindings and metadata, so that a traversal sees all the symbols.
+// Absent optional expressions, like the value of (break), are not visited.
+func Walk(v Visitor, expr Expr) {
+	if expr == nil {
+		return
+	}
+	if v = v.Visit(expr); v == nil {
+		return
+	}
+
+	switch expr := expr.(type) {
+	case Call:
+		Walk(v, expr.Function)
+		walkList(v, expr.Arguments)
+	case Assign:
+		Walk(v, expr.Target)
+		Walk(v, expr.Value)
+	case Break:
+		Walk(v, expr.Value)
+	case Def:
+		walkMeta(v, expr.Meta)
+		Walk(v, expr.Name)
+		Walk(v, expr.Value)
+	case Fun:
+		walkMeta(v, expr.Meta)
+		Walk(v, expr.Name)
+		walkParameters(v, expr.Parameters, expr.Rest)
+		walkList(v, expr.Body)
+	case Lambda:
+		walkParameters(v, expr.Parameters, expr.Rest)
+		walkList(v, expr.Body)
+	case Let:
+		walkBindings(v, expr.Bindings)
+		walkList(v, expr.Body)
+	case Loop:
+		walkBindings(v, expr.Bindings)
+		Walk(v, expr.Condition)
+		walkList(v, expr.Body)
+	case Struct:
+		walkMeta(v, expr.Meta)
+		Walk(v, expr.Name)
+		walkBindings(v, expr.Fields)
+	case Tie:
+		Walk(v, expr.Function)
+		walkList(v, expr.Args)
+	case When:
+		for _, clause := range expr.Clauses {
+			Walk(v, clause.Condition)
+			walkList(v, clause.Body)
+		}
+		walkList(v, expr.Else)
+	case Quote:
+		Walk(v, expr.Form)
+	case Quasiquote:
+		Walk(v, expr.Form)
+	case Unquote:
+		Walk(v, expr.Form)
+	case UnquoteSplice:
+		Walk(v, expr.Form)
+	case Deref:
+		Walk(v, expr.Form)
+	case Access:
+		Walk(v, expr.Target)
+		Walk(v, expr.Field)
+	case Meta:
+		Walk(v, expr.Data)
+		Walk(v, expr.Form)
+	case Array:
+		walkList(v, expr.Elements)
+	case Map:
+		for _, entry := range expr.Entries {
+			Walk(v, entry.Key)
+			Walk(v, entry.Value)
+		}
+	case Set:
+		walkList(v, expr.Elements)
+	}
+	// Primitives, symbols, keywords and continue have no children.
+
+	v.Visit(nil)
+}
+
+func walkList(v Visitor, list []Expr) {
+	for _, expr := range list {
+		Walk(v, expr)
+	}
+}
+
+func walkMeta(v Visitor, meta *Map) {
+	if meta != nil {
+		Walk(v, *meta)
+	}
+}
+
+func walkParameters(v Visitor, params []Symbol, rest *Symbol) {
+	for _, param := range params {
+		Walk(v, param)
+	}
+	if rest != nil {
+		Walk(v, *rest)
+	}
+}
+
+func walkBindings(v Visitor, bindings []Binding) {
+	for _, binding := range bindings {
+		Walk(v, binding.Variable)
+		Walk(v, binding.Value)
+	}
+}
+
+// inspector is the Visitor of Inspect.
+type inspector func(Expr) bool
+
+func (f inspector) Visit(expr Expr) Visitor {
+	if f(expr) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a tree like Walk, calling f(expr) for each expression and then f(nil) once its
+// children are traversed. The children of expr are skipped when f(expr) returns false.
+func Inspect(expr Expr, f func(Expr) bool) {
+	Walk(inspector(f), expr)
+}
@@ -0,0 +1,71 @@
+package ast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// (def ^:private f (fun [x & r] (let [y x] (when [y 'z] [else (break)]))))
+	tree := Def{
+		Meta: &Map{Entries: []Entry{{Keyword{Name: "private"}, Bool{Value: true}}}},
+		Name: sym("f"),
+		Value: Lambda{Parameters: []Symbol{sym("x")}, Rest: &Symbol{Name: "r"}, Body: []Expr{
+			Let{Bindings: []Binding{{Variable: sym("y"), Value: sym("x")}}, Body: []Expr{
+				When{
+					Clauses: []WhenClause{{Condition: sym("y"), Body: []Expr{Quote{Form: sym("z")}}}},
+					Else:    []Expr{Break{}},
+				},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		skip     func(Expr) bool
+		expected []string
+	}{
+		{
+			name: "Everything",
+			skip: func(Expr) bool { return false },
+			expected: []string{
+				"def", "map", "keyword", "bool", "symbol f", "lambda", "symbol x", "symbol r", "let", "symbol y",
+				"symbol x", "when", "symbol y", "quote", "symbol z", "break",
+			},
+		},
+		{
+			name:     "Skipped children",
+			skip:     func(expr Expr) bool { _, ok := expr.(Let); return ok },
+			expected: []string{"def", "map", "keyword", "bool", "symbol f", "lambda", "symbol x", "symbol r", "let"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, depth := []string{}, 0
+			Inspect(tree, func(expr Expr) bool {
+				if expr == nil {
+					depth--
+					return false
+				}
+				kind := Match[string](expr, kinds{})
+				if symbol, ok := expr.(Symbol); ok {
+					kind += " " + symbol.Name
+				}
+				got = append(got, kind)
+				if tt.skip(expr) {
+					return false
+				}
+				depth++
+				return true
+			})
+
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected:\n%v\ngot:\n%v", tt.expected, got)
+			}
+			if depth != 0 {
+				t.Errorf("expected every visit to be ended by nil, %d were not", depth)
+			}
+		})
+	}
+}
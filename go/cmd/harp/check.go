@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"mooss/harp/analysis"
+	"mooss/harp/diag"
+	"mooss/harp/eval"
+	"mooss/harp/lex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// workspace holds the analysis of the Harp files of a project between two checks, so that only the
+// files that changed are analyzed again.
+// Files are independent: Harp has no imports, so a change cannot affect the diagnostics of another
+// file.
+type workspace struct {
+	// roots are the files and directories given on the command line, directories being searched
+	// recursively for .harp files.
+	roots []string
+
+	// files are the files found by the last refresh, indexed by path.
+	files map[string]*checked
+}
+
+// checked is the state of a file as of its last analysis.
+type checked struct {
+	content     string
+	diagnostics []string
+}
+
+// update is a change of the diagnostics of a file. No diagnostics means that the file is fine, or
+// has been removed.
+type update struct {
+	path        string
+	diagnostics []string
+}
+
+func newWorkspace(roots []string) *workspace {
+	return &workspace{roots: roots, files: map[string]*checked{}}
+}
+
+// refresh analyzes the files that are new or changed since the last refresh and returns the
+// updates of diagnostics, sorted by path, along with the errors met while reading files.
+// A file that is fine is reported only when it was not on the previous refresh.
+func (ws *workspace) refresh() ([]update, []error) {
+	paths, errs := harpFiles(ws.roots)
+	res := []update{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		previous, known := ws.files[path]
+		if known && previous.content == string(content) {
+			continue
+		}
+		current := &checked{content: string(content), diagnostics: check(path, string(content))}
+		ws.files[path] = current
+		if !known || !slices.Equal(previous.diagnostics, current.diagnostics) {
+			res = append(res, update{path, current.diagnostics})
+		}
+	}
+
+	for path, file := range ws.files {
+		if !slices.Contains(paths, path) {
+			delete(ws.files, path)
+			if len(file.diagnostics) > 0 {
+				res = append(res, update{path: path})
+			}
+		}
+	}
+	slices.SortFunc(res, func(a, b update) int { return strings.Compare(a.path, b.path) })
+	return res, errs
+}
+
+// harpFiles returns the paths of the files given as roots and of the .harp files found in the
+// directories given as roots, sorted and without duplicates. Hidden directories are skipped.
+func harpFiles(roots []string) ([]string, []error) {
+	paths, errs := []string{}, []error{}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case path == root && !entry.IsDir():
+				paths = append(paths, path) // Given explicitly, whatever its extension.
+			case entry.IsDir() && path != root && strings.HasPrefix(entry.Name(), "."):
+				return filepath.SkipDir
+			case !entry.IsDir() && filepath.Ext(path) == ".harp":
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	slices.Sort(paths)
+	return slices.Compact(paths), errs
+}
+
+// check returns the diagnostics of a file: its syntax error, then its unbound symbols.
+func check(path, input string) []string {
+	file := analysis.NewFile(&lex.Source{Name: path, Content: input}, eval.Predeclared())
+	res := []string{}
+	if file.Err != nil {
+		res = append(res, diag.Render(file.Err, input, false))
+	}
+	for _, symbol := range file.Info.Unbound {
+		pos := symbol.Pos()
+		res = append(res, fmt.Sprintf(
+			"%s at line %d column %d: unbound symbol %s", path, pos.Line, pos.Column, symbol.Name,
+		))
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.harp"), filepath.Join(dir, "sub", "b.harp")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a, "(def x 1)\n(println x y)")
+	write(b, "(fun f [n] (+ n 1))")
+	write(filepath.Join(dir, ".hidden", "c.harp"), "z")
+	write(filepath.Join(dir, "notes.txt"), "z")
+
+	// summary returns the path of each update followed by its number of diagnostics.
+	ws := newWorkspace([]string{dir})
+	summary := func() []string {
+		t.Helper()
+		updates, errs := ws.refresh()
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		res := []string{}
+		for _, up := range updates {
+			res = append(res, strings.TrimPrefix(up.path, dir), strings.Repeat("!", len(up.diagnostics)))
+		}
+		return res
+	}
+	steps := []struct {
+		name     string
+		change   func()
+		expected []string
+	}{
+		{"First check", func() {}, []string{"/a.harp", "!", "/sub/b.harp", ""}},
+		{"Nothing changed", func() {}, []string{}},
+		{"Same diagnostics", func() { write(b, "(fun f [n] (+ n 2))") }, []string{}},
+		{"Syntax error", func() { write(b, "(fun f [n] (+ n 2)") }, []string{"/sub/b.harp", "!"}},
+		{"Fixed", func() { write(a, "(def y 1)\n(println y)") }, []string{"/a.harp", ""}},
+		{"Removed", func() { os.Remove(b) }, []string{"/sub/b.harp", ""}},
+		{"Removed without diagnostics", func() { os.Remove(a) }, []string{}},
+	}
+
+	for _, step := range steps {
+		step.change()
+		if got := summary(); !reflect.DeepEqual(got, step.expected) {
+			t.Errorf("%s: expected %q, got %q", step.name, step.expected, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	expected := []string{"a.harp at line 1 column 16: unbound symbol y"}
+	if got := check("a.harp", "(fun f [x] (+ x y))"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	got := check("a.harp", "(def x")
+	if len(got) != 1 || !strings.HasPrefix(got[0], "parse error in a.harp") {
+		t.Errorf("expected a parse error, got %q", got)
+	}
+}
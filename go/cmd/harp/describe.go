@@ -0,0 +1,18 @@
+package main
+
+import (
+	"mooss/harp/analysis"
+	"mooss/harp/eval"
+	"mooss/harp/lex"
+)
+
+// describe returns the description of a name as defined by the code entered during a REPL session,
+// like an editor would show it when hovering the name.
+func describe(session, name string) string {
+	src := &lex.Source{Name: "session", Content: session + name}
+	hover, ok := analysis.HoverAt(analysis.NewFile(src, eval.Predeclared()), len(src.Content))
+	if !ok {
+		return name + " is not defined"
+	}
+	return hover.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	session := "(fun ^{:doc \"Squares n.\"} square [n] (* n n))\n(def x 1)\n"
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"square", "(square n)\nfunction defined at line 1 column 26\n\nSquares n."},
+		{"x", "x\nglobal defined at line 2 column 5"},
+		{"str", "str\npredeclared"},
+		{"missing", "missing is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describe(session, tt.name); got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changes of a diff.
+const diffContext = 3
+
+// edit is a line of a diff: kept (' '), removed ('-') or added ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff returns the differences between two versions of a file in the unified format of
+// diff -u, like gofmt -d, or an empty string when they are equal. The lines are matched along their
+// longest common subsequence, which is quadratic but more than enough for source files.
+func unifiedDiff(path, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	edits := lineEdits(splitLines(before), splitLines(after))
+	var res strings.Builder
+	fmt.Fprintf(&res, "--- %s.orig\n+++ %s\n", path, path)
+
+	// line is the number of the line of edits[start] in both texts.
+	line := [2]int{1, 1}
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			line[0]++
+			line[1]++
+			start++
+			continue
+		}
+
+		// A hunk spans the changes separated by less than twice the context.
+		from := max(0, start-diffContext)
+		end, kept := start, 0
+		for ; end < len(edits) && kept <= 2*diffContext; end++ {
+			if edits[end].op == ' ' {
+				kept++
+			} else {
+				kept = 0
+			}
+		}
+		end -= max(0, kept-diffContext)
+
+		count := [2]int{}
+		for _, e := range edits[from:end] {
+			if e.op != '+' {
+				count[0]++
+			}
+			if e.op != '-' {
+				count[1]++
+			}
+		}
+		back := start - from
+		fmt.Fprintf(&res, "@@ -%s +%s @@\n", hunkRange(line[0]-back, count[0]), hunkRange(line[1]-back, count[1]))
+		for _, e := range edits[from:end] {
+			fmt.Fprintf(&res, "%c%s\n", e.op, e.line)
+		}
+
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				line[0]++
+			}
+			if e.op != '-' {
+				line[1]++
+			}
+		}
+		start = end
+	}
+	return res.String()
+}
+
+// hunkRange formats the start and length of a hunk, the start being the line before the hunk when
+// it is empty.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineEdits returns the edits turning a into b.
+func lineEdits(a, b []string) []edit {
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	res := []edit{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			res = append(res, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && common[i+1][j] >= common[i][j+1]:
+			res = append(res, edit{'-', a[i]})
+			i++
+		default:
+			res = append(res, edit{'+', b[j]})
+			j++
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		var res strings.Builder
+		for i := from; i <= to; i++ {
+			res.WriteString(string(rune('a'+i-1)) + "\n")
+		}
+		return res.String()
+	}
+
+	tests := []struct {
+		name          string
+		before, after string
+		expected      string
+	}{
+		{"Equal", "a\nb\n", "a\nb\n", ""},
+		{
+			name:     "Changed line",
+			before:   "a\nb\nc\n",
+			after:    "a\nB\nc\n",
+			expected: "--- f.harp.orig\n+++ f.harp\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "Added lines at the start",
+			before:   "b\n",
+			after:    "a\nb\n",
+			expected: "--- f.harp.orig\n+++ f.harp\n@@ -1,1 +1,2 @@\n+a\n b\n",
+		},
+		{
+			name:     "From empty",
+			before:   "",
+			after:    "a\n",
+			expected: "--- f.harp.orig\n+++ f.harp\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name:   "Separate hunks",
+			before: lines(1, 20),
+			after:  "A\n" + lines(2, 19) + "T\n",
+			expected: "--- f.harp.orig\n+++ f.harp\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n" +
+				"@@ -17,4 +17,4 @@\n q\n r\n s\n-t\n+T\n",
+		},
+		{
+			name:   "Close changes share a hunk",
+			before: lines(1, 8),
+			after:  "A\n" + lines(2, 7) + "H\n",
+			expected: "--- f.harp.orig\n+++ f.harp\n" +
+				"@@ -1,8 +1,8 @@\n-a\n+A\n b\n c\n d\n e\n f\n g\n-h\n+H\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("f.harp", tt.before, tt.after); got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
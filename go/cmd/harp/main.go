@@ -0,0 +1,477 @@
+// Command harp runs Harp programs and exposes the stages of the interpreter for inspection.
+//
+//	harp [--no-rc]                  start the REPL
+//	harp [run] file.harp            evaluate a script
+//	harp lex [--json|--ndjson] file.harp
+//	                                dump the tokens of a file
+//	harp tokens file.harp           dump the tokens of a file as JSON, like lex --json
+//	harp parse [--json] file.harp   dump the syntax tree of a file
+//	harp indent --line N file.harp  print the suggested indentation of a line
+//	harp fmt [-w] [-d] file.harp... format files, to stdout, in place or as a diff
+//	harp diff a.harp b.harp         compare the forms of two files, ignoring formatting and comments
+//	harp stats [--json] [--top N] path...
+//	                                report the lines, forms and longest functions of files
+//	harp check [--watch] path...    report the syntax errors and unbound symbols of files and
+//	                                directories, once or whenever they change
+//
+// The exit code is 0 on success, 1 when the input is erroneous and 2 when the command line is.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"mooss/harp/ast"
+	"mooss/harp/diag"
+	"mooss/harp/eval"
+	"mooss/harp/format"
+	"mooss/harp/indent"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// commands are the subcommands of harp, taking their arguments and returning their exit code.
+var commands = map[string]func(args []string) int{
+	"repl":   repl,
+	"run":    run,
+	"lex":    lexFile,
+	"tokens": tokens,
+	"parse":  parseFile,
+	"indent": indentLine,
+	"fmt":    formatFiles,
+	"check":  checkFiles,
+	"diff":   diffFiles,
+	"stats":  statsFiles,
+}
+
+const usage = `usage:
+  harp [repl] [--no-rc]
+  harp [run] file.harp
+  harp lex [--json|--ndjson] file.harp
+  harp tokens file.harp
+  harp parse [--json] file.harp
+  harp indent --line N file.harp
+  harp fmt [-w] [-d] file.harp...
+  harp check [--watch] path...
+  harp diff a.harp b.harp
+  harp stats [--json] [--top N] path...`
+
+func main() {
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+// dispatch runs the subcommand named by the first argument and returns its exit code.
+func dispatch(args []string) int {
+	if len(args) > 0 {
+		if command, ok := commands[args[0]]; ok {
+			return command(args[1:])
+		}
+		if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			fmt.Println(usage)
+			return exitOK
+		}
+	}
+
+	// Without subcommand, a file is run (e.g. from a shebang line) and flags go to the REPL.
+	if len(args) == 1 && args[0] != "" && args[0][0] != '-' {
+		return run(args)
+	}
+	return repl(args)
+}
+
+// fileArgument parses the flags of a subcommand expecting a single file and returns the path and
+// content of the file. ok is false when the subcommand must exit with code.
+func fileArgument(flags *flag.FlagSet, args []string) (path, input string, code int, ok bool) {
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		flags.Usage()
+		return "", "", exitUsage, false
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return "", "", exitError, false
+	}
+	return flags.Arg(0), string(content), exitOK, true
+}
+
+// report prints an error to stderr and returns the corresponding exit code.
+func report(err error) int {
+	fmt.Fprintln(os.Stderr, diag.Render(err, "", colored(os.Stderr)))
+	return exitError
+}
+
+// lexFile implements `harp lex [--json|--ndjson] file.harp`, dumping the token stream of a file.
+// Lexical errors are reported on stderr without stopping the dump, or as a part of the tokens in
+// JSON. Either way, they make the command fail.
+func lexFile(args []string) int {
+	flags := flag.NewFlagSet("lex", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "emit the tokens as a JSON array")
+	asNDJSON := flags.Bool("ndjson", false, "emit the tokens as newline-delimited JSON, one per line")
+	path, input, code, ok := fileArgument(flags, args)
+	if !ok {
+		return code
+	}
+
+	if *asJSON || *asNDJSON {
+		marshal := lex.MarshalTokens
+		if *asNDJSON {
+			marshal = lex.MarshalTokenLines
+		}
+
+		out, err := marshal(input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+
+		os.Stdout.Write(out)
+		if *asJSON {
+			fmt.Println()
+		}
+		if _, errs := lex.NewLexer(input).Tokenize(); len(errs) > 0 {
+			return exitError
+		}
+		return exitOK
+	}
+
+	lexer := lex.NewSourceLexer(&lex.Source{Name: path, Content: input})
+	for tok, err := range lexer.Tokens() {
+		if err != nil {
+			code = report(err)
+		}
+		fmt.Printf("%d:%d %s %q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+	return code
+}
+
+// tokens implements `harp tokens file.harp`, the former name of `harp lex --json file.harp`, kept
+// for the scripts using it.
+func tokens(args []string) int {
+	return lexFile(append([]string{"--json"}, args...))
+}
+
+// parseFile implements `harp parse [--json] file.harp`, dumping the syntax tree of every top-level
+// form of a file, as Go values or as a JSON array of nodes.
+func parseFile(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "emit the forms as a JSON array")
+	path, input, code, ok := fileArgument(flags, args)
+	if !ok {
+		return code
+	}
+
+	forms, err := parse.NewParser(lex.NewSourceLexer(&lex.Source{Name: path, Content: input})).Parse()
+	if err != nil {
+		return report(err)
+	}
+
+	if *asJSON {
+		out, err := json.Marshal(forms)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		os.Stdout.Write(out)
+		fmt.Println()
+		return exitOK
+	}
+	for _, form := range forms {
+		fmt.Printf("%#v\n", form)
+	}
+	return exitOK
+}
+
+// indentLine implements `harp indent --line N file.harp`, printing the suggested indentation of a
+// line.
+func indentLine(args []string) int {
+	flags := flag.NewFlagSet("indent", flag.ContinueOnError)
+	line := flags.Int("line", 0, "line to indent (starting at 1)")
+	_, input, code, ok := fileArgument(flags, args)
+	if !ok {
+		return code
+	}
+	if *line < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitUsage
+	}
+
+	fmt.Println(indent.At(input, *line))
+	return exitOK
+}
+
+// formatFiles implements `harp fmt [-w] [-d] file.harp...`, printing the formatted source of files.
+// With -w, the files are rewritten in place instead, and with -d, the differences between the files
+// and their formatted source are printed. A file that does not parse is reported and left untouched,
+// without stopping the processing of the others.
+func formatFiles(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	write := flags.Bool("w", false, "rewrite the files instead of printing them")
+	diff := flags.Bool("d", false, "print the changes as a unified diff instead of the files")
+	if flags.Parse(args) != nil || flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, path := range flags.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitError
+			continue
+		}
+
+		input := string(content)
+		if _, err := parse.NewParser(lex.NewSourceLexer(&lex.Source{Name: path, Content: input})).Parse(); err != nil {
+			code = report(err)
+			continue
+		}
+		formatted, _ := format.Source(input) // Parsing succeeded, so formatting does too.
+
+		if *diff {
+			fmt.Print(unifiedDiff(path, input, formatted))
+		}
+		if *write && formatted != input {
+			if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = exitError
+			}
+		}
+		if !*diff && !*write {
+			fmt.Print(formatted)
+		}
+	}
+	return code
+}
+
+// diffFiles implements `harp diff a.harp b.harp`, printing the changes between the top-level forms
+// of two files: the forms removed, added, moved, and the definitions changed or renamed. Like diff,
+// the exit code is 1 when the files differ.
+func diffFiles(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	if flags.Parse(args) != nil || flags.NArg() != 2 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	versions := [2][]ast.Expr{}
+	for i, path := range flags.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		src := &lex.Source{Name: path, Content: string(content)}
+		if versions[i], err = parse.NewParser(lex.NewSourceLexer(src)).Parse(); err != nil {
+			return report(err)
+		}
+	}
+
+	changes := formChanges(versions[0], versions[1])
+	fmt.Print(describeChanges(flags.Arg(0), flags.Arg(1), changes))
+	if len(changes) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// statsFiles implements `harp stats [--json] [--top N] path...`, printing the metrics of the .harp
+// files found in paths: their lines of code and comments, their forms and functions, and their N
+// longest functions (5 by default). A file that does not parse is reported and left out.
+func statsFiles(args []string) int {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	asJSON := flags.Bool("json", false, "emit the metrics as a JSON object")
+	top := flags.Int("top", 5, "number of longest functions to list")
+	if flags.Parse(args) != nil || flags.NArg() == 0 || *top < 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	code := exitOK
+	paths, errs := harpFiles(flags.Args())
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+		code = exitError
+	}
+	res := &stats{Longest: []functionStats{}}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			err = res.add(path, string(content))
+		}
+		if err != nil {
+			code = report(err)
+		}
+	}
+	res.keepLongest(*top)
+
+	if *asJSON {
+		out, err := json.Marshal(res)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		os.Stdout.Write(out)
+		fmt.Println()
+		return code
+	}
+	fmt.Print(res)
+	return code
+}
+
+// checkInterval is the delay between two checks of `harp check --watch`.
+const checkInterval = 500 * time.Millisecond
+
+// checkFiles implements `harp check [--watch] path...`, printing the diagnostics of the .harp files
+// found in paths. With --watch, the files are checked again whenever they change until interrupted,
+// and only the files whose diagnostics changed are reported, `path: ok` meaning that a file has no
+// diagnostics anymore.
+func checkFiles(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	watch := flags.Bool("watch", false, "check the files again whenever they change")
+	if flags.Parse(args) != nil || flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	ws := newWorkspace(flags.Args())
+	// failed are the errors of the previous check, to avoid repeating them while watching.
+	failed := map[string]bool{}
+	for first := true; ; first = false {
+		updates, errs := ws.refresh()
+		code, failing := exitOK, map[string]bool{}
+		for _, err := range errs {
+			if !failed[err.Error()] {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			failing[err.Error()] = true
+			code = exitError
+		}
+		failed = failing
+		for _, up := range updates {
+			for _, diagnostic := range up.diagnostics {
+				fmt.Println(diagnostic)
+				code = exitError
+			}
+			if len(up.diagnostics) == 0 && !first {
+				fmt.Printf("%s: ok\n", up.path)
+			}
+		}
+
+		if !*watch {
+			return code
+		}
+		time.Sleep(checkInterval)
+	}
+}
+
+// run implements `harp run file.harp`, evaluating a whole file in a fresh global environment.
+// The file can start with a shebang line.
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if err := loadFile(flags.Arg(0), eval.NewGlobalEnvironment()); err != nil {
+		return report(err)
+	}
+	return exitOK
+}
+
+// repl implements `harp repl [--no-rc]`, reading forms from stdin, evaluating them and printing their
+// values.
+// Lines are accumulated until all delimiters and strings are closed, so that forms can span several
+// lines. Meanwhile, the signature of the function being called is shown when it is known.
+// Unless --no-rc is given, ~/.harprc and then ./.harprc are evaluated first when they exist.
+func repl(args []string) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	noRC := flags.Bool("no-rc", false, "do not evaluate the .harprc startup files")
+	if flags.Parse(args) != nil || flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fmt.Println("Harp REPL - v0.0.0")
+	fmt.Println("Enter code, or :describe name to describe a name (Ctrl+C to exit)")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	env := eval.NewGlobalEnvironment()
+	if !*noRC {
+		loadRC(env)
+	}
+	input := ""
+	// session is the code entered so far, so that names can be described.
+	session := ""
+
+	for {
+		if input == "" {
+			fmt.Print(">> ")
+		} else {
+			fmt.Print(".. ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+
+		if name, ok := strings.CutPrefix(scanner.Text(), ":describe "); ok && input == "" {
+			fmt.Println(describe(session, strings.TrimSpace(name)))
+			continue
+		}
+		input += scanner.Text() + "\n"
+		if lex.Unbalanced(input) {
+			if help, ok := signature(session, input); ok {
+				fmt.Println(help)
+			}
+			continue
+		}
+
+		forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+		if err != nil {
+			fmt.Println(diag.Render(err, input, colored(os.Stdout)))
+			input = ""
+			continue
+		}
+		session += input
+		input = ""
+
+		res, err := eval.EvalAll(forms, env)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(eval.Repr(res))
+	}
+
+	return exitOK
+}
+
+// colored tells whether diagnostics written to f should be highlighted, that is to say when f is a
+// terminal and the NO_COLOR convention is not in effect.
+func colored(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"mooss/harp/eval"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture runs the command line args with stdout and stderr redirected, and returns what they
+// received with the exit code.
+func capture(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	outputs := [2]*os.File{}
+	for i := range outputs {
+		f, err := os.CreateTemp(t.TempDir(), "output")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		outputs[i] = f
+	}
+
+	oldStdout, oldStderr, oldOutput := os.Stdout, os.Stderr, eval.Output
+	os.Stdout, os.Stderr, eval.Output = outputs[0], outputs[1], outputs[0]
+	code = dispatch(args)
+	os.Stdout, os.Stderr, eval.Output = oldStdout, oldStderr, oldOutput
+
+	read := func(f *os.File) string {
+		content, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	return read(outputs[0]), read(outputs[1]), code
+}
+
+// writeFile writes content to a file named name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDispatch(t *testing.T) {
+	script := writeFile(t, "script.harp", "#!/usr/bin/env harp\n(println (+ 1 2))")
+	failing := writeFile(t, "failing.harp", "(println 1)\n(+ 1 :a)")
+	call := writeFile(t, "call.harp", "(f 1)")
+	unclosed := writeFile(t, "unclosed.harp", "(f 1")
+	tokensJSON := `[{"type":"LPAREN","literal":"(","start":{"line":1,"column":0,"offset":0},` +
+		`"end":{"line":1,"column":1,"offset":1}},`
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string // Prefix of the output.
+		stderr string // Substring of the errors.
+	}{
+		{"Run", []string{"run", script}, exitOK, "3\n", ""},
+		{"Run without subcommand", []string{script}, exitOK, "3\n", ""},
+		{"Runtime error", []string{"run", failing}, exitError, "1\n", "runtime error at line 2"},
+		{"Run without file", []string{"run"}, exitUsage, "", "usage:"},
+		{"Missing file", []string{"run", filepath.Join(t.TempDir(), "missing.harp")}, exitError, "",
+			"no such file"},
+		{"Lex", []string{"lex", call}, exitOK, "1:0 LPAREN \"(\"\n1:1 SYMBOL \"f\"\n", ""},
+		{"Lex as JSON", []string{"lex", "--json", call}, exitOK, tokensJSON, ""},
+		{"Tokens", []string{"tokens", call}, exitOK, tokensJSON, ""},
+		{"Tokens with the former flag", []string{"tokens", "--json", call}, exitOK, tokensJSON, ""},
+		{"Parse", []string{"parse", call}, exitOK, "ast.Call{", ""},
+		{"Parse as JSON", []string{"parse", "--json", call}, exitOK, `[{"type":"call","function":`, ""},
+		{"Parse error", []string{"parse", unclosed}, exitError, "", "parse error in " + unclosed},
+		{"Unknown flag", []string{"lex", "--xml", call}, exitUsage, "", "usage:"},
+		{"Help", []string{"--help"}, exitOK, "usage:", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := capture(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("expected exit code %d, got %d (stderr: %q)", tt.code, code, stderr)
+			}
+			if !strings.HasPrefix(stdout, tt.stdout) {
+				t.Errorf("expected output starting with:\n> %s\ngot:\n> %s", tt.stdout, stdout)
+			}
+			if !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("expected errors containing:\n> %s\ngot:\n> %s", tt.stderr, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mooss/harp/diag"
+	"mooss/harp/eval"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"os"
+	"path/filepath"
+)
+
+// rcName is the name of the startup files evaluated by the REPL.
+const rcName = ".harprc"
+
+// rcFiles returns the startup files of the REPL, in evaluation order: the user file in the home
+// directory, then the project file in the working directory, so that projects can override user
+// definitions.
+func rcFiles() []string {
+	res := []string{}
+	if home, err := os.UserHomeDir(); err == nil {
+		res = append(res, filepath.Join(home, rcName))
+	}
+
+	project, err := filepath.Abs(rcName)
+	if err == nil && (len(res) == 0 || project != res[0]) {
+		res = append(res, project)
+	}
+	return res
+}
+
+// loadRC evaluates the existing startup files in env.
+// Errors are reported without stopping, a broken startup file must not prevent using the REPL.
+func loadRC(env *eval.Environment) {
+	for _, path := range rcFiles() {
+		err := loadFile(path, env)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, diag.Render(err, "", colored(os.Stderr)))
+		}
+	}
+}
+
+// loadFile parses and evaluates a whole file in env.
+// Errors mention the path of the file.
+func loadFile(path string, env *eval.Environment) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	src := &lex.Source{Name: path, Content: string(input)}
+	forms, err := parse.NewParser(lex.NewSourceLexer(src)).Parse()
+	if err != nil {
+		return err
+	}
+
+	if _, err = eval.EvalAll(forms, env); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"mooss/harp/eval"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// inDirectories sets the home directory and moves to the working directory for the duration of a
+// test, they are created when empty and returned.
+func inDirectories(t *testing.T, home, work string) (string, string) {
+	t.Helper()
+	if home == "" {
+		home = t.TempDir()
+	}
+	if work == "" {
+		work = t.TempDir()
+	}
+	t.Setenv("HOME", home)
+
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	// The working directory is read back, since it can differ from work through symbolic links.
+	if work, err = os.Getwd(); err != nil {
+		t.Fatal(err)
+	}
+	return home, work
+}
+
+func TestRCFiles(t *testing.T) {
+	home, work := inDirectories(t, "", "")
+	expected := []string{filepath.Join(home, rcName), filepath.Join(work, rcName)}
+	if got := rcFiles(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the user file then the project file %q, got %q", expected, got)
+	}
+
+	// In the home directory, the user file is also the project file.
+	home, _ = inDirectories(t, work, work)
+	expected = []string{filepath.Join(home, rcName)}
+	if got := rcFiles(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected a single file %q, got %q", expected, got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string // Prefix of the error, after the path of the file.
+		check   func(err error) bool
+	}{
+		{"Runtime error", "(def x 1)\n(+ x :a)", ": runtime error at line 2", func(err error) bool {
+			var rerr *eval.RuntimeError
+			return errors.As(err, &rerr)
+		}},
+		{"Parse error", "(def x", "", func(err error) bool {
+			return strings.HasPrefix(err.Error(), "parse error in ")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, rcName, tt.content)
+			err := loadFile(path, eval.NewGlobalEnvironment())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if !strings.Contains(err.Error(), path+tt.message) || !tt.check(err) {
+				t.Errorf("expected an error mentioning %s, got %v", path, err)
+			}
+		})
+	}
+
+	missing := filepath.Join(t.TempDir(), rcName)
+	if err := loadFile(missing, eval.NewGlobalEnvironment()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file to be reported as such, got %v", err)
+	}
+}
+
+func TestREPLStartupFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string // Content of the user file, none when empty.
+		project string // Content of the project file, none when empty.
+		args    []string
+		stdout  string // Substring of the output.
+		stderr  string // Substring of the errors.
+	}{
+		{"Project overrides user", "(def x 1)\n(def y 3)", "(def x 2)", nil, ">> [2 3]", ""},
+		{"User only", "(def x 1)\n(def y 3)", "", nil, ">> [1 3]", ""},
+		{"Broken user file", "(def x", "(def x 2)\n(def y 3)", nil, ">> [2 3]",
+			rcName + " at line 1 column 0: met EOF"},
+		{"Failing project file", "(def x 1)\n(def y 3)", "(def y 4)\n(+ 1 :a)", nil, ">> [1 4]",
+			rcName + ": runtime error at line 2"},
+		{"Without startup files", "(def x 1)\n(def y 3)", "(def x 2)", []string{"--no-rc"},
+			">> runtime error at line 1 column 1: met unbound symbol: x", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, work := inDirectories(t, "", "")
+			for dir, content := range map[string]string{home: tt.user, work: tt.project} {
+				if content == "" {
+					continue
+				}
+				if err := os.WriteFile(filepath.Join(dir, rcName), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stdin := writeFile(t, "stdin", "[x y]\n")
+			input, err := os.Open(stdin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer input.Close()
+			oldStdin := os.Stdin
+			os.Stdin = input
+			defer func() { os.Stdin = oldStdin }()
+
+			stdout, stderr, code := capture(t, append([]string{"repl"}, tt.args...)...)
+			if code != exitOK {
+				t.Errorf("expected the REPL to succeed, got exit code %d", code)
+			}
+			if !strings.Contains(stdout, tt.stdout) {
+				t.Errorf("expected output containing:\n> %s\ngot:\n> %s", tt.stdout, stdout)
+			}
+			if !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("expected errors containing:\n> %s\ngot:\n> %s", tt.stderr, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"mooss/harp/analysis"
+	"mooss/harp/eval"
+	"mooss/harp/lex"
+)
+
+// signature returns the signature of the function called by the unclosed call at the end of the
+// input of a REPL session, with the parameter of the argument being written highlighted, and false
+// when there is no such function.
+func signature(session, input string) (string, bool) {
+	src := &lex.Source{Name: "session", Content: session + input}
+	help, ok := analysis.SignatureAt(analysis.NewFile(src, eval.Predeclared()), len(src.Content))
+	if !ok {
+		return "", false
+	}
+	return help.String(), true
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSignature(t *testing.T) {
+	session := "(fun clamp [n low high] n)\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(clamp\n", "(clamp <n> low high)"},
+		{"(clamp 5\n", "(clamp n <low> high)"},
+		{"(clamp 5 0\n  ", "(clamp n low <high>)"},
+		{"(clamp 5 (list\n", ""},
+		{"(print\n", ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := signature(session, tt.input)
+		if ok != (tt.expected != "") || got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"slices"
+	"strings"
+)
+
+// stats are the metrics of a set of source files.
+type stats struct {
+	Files int `json:"files"`
+
+	// Lines are all the lines, split between the lines holding code, the lines holding only
+	// comments and the blank lines.
+	Lines   int `json:"lines"`
+	Code    int `json:"code"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+
+	// Forms are the top-level forms, Functions the fun and lambda forms at any depth.
+	Forms     int `json:"forms"`
+	Functions int `json:"functions"`
+
+	// Longest are the longest functions, by number of lines.
+	Longest []functionStats `json:"longest"`
+}
+
+// functionStats locates a function and gives its length.
+type functionStats struct {
+	// Name is the name of the function, or of the definition of a lambda, empty for an anonymous
+	// lambda.
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Line  int    `json:"line"`
+	Lines int    `json:"lines"`
+}
+
+// CommentRatio returns the share of the non-blank lines that hold only comments.
+func (s *stats) CommentRatio() float64 {
+	if s.Code+s.Comment == 0 {
+		return 0
+	}
+	return float64(s.Comment) / float64(s.Code+s.Comment)
+}
+
+// add adds the metrics of a file to the stats. The file must parse.
+func (s *stats) add(path, input string) error {
+	src := &lex.Source{Name: path, Content: input}
+	forms, err := parse.NewParser(lex.NewSourceLexer(src)).Parse()
+	if err != nil {
+		return err
+	}
+	s.Files++
+	s.Forms += len(forms)
+
+	// A token spanning several lines, like a heredoc, counts for all of them.
+	code, comment := map[int]bool{}, map[int]bool{}
+	for tok := range lex.NewSourceLexer(src).Tokens() {
+		if tok.Type == lex.TOKEN_EOF {
+			break
+		}
+		lines := code
+		if tok.Type == lex.TOKEN_COMMENT {
+			lines = comment
+		}
+		for line := tok.Line; line <= tok.Line+strings.Count(tok.Literal, "\n"); line++ {
+			lines[line] = true
+		}
+	}
+	for line := range comment {
+		if code[line] {
+			delete(comment, line)
+		}
+	}
+	lines := strings.Count(input, "\n")
+	if input != "" && !strings.HasSuffix(input, "\n") {
+		lines++
+	}
+	s.Lines += lines
+	s.Code += len(code)
+	s.Comment += len(comment)
+	s.Blank += lines - len(code) - len(comment)
+
+	// named are the lambdas recorded with the name of their definition.
+	named := ast.Table[bool]{}
+	for _, form := range forms {
+		ast.Inspect(form, func(expr ast.Expr) bool {
+			switch node := expr.(type) {
+			case ast.Def:
+				if lambda, ok := node.Value.(ast.Lambda); ok {
+					s.function(node.Name.Name, path, lambda)
+					named.Set(lambda, true)
+				}
+			case ast.Fun:
+				s.function(node.Name.Name, path, node)
+			case ast.Lambda:
+				if done, _ := named.Get(node); !done {
+					s.function("", path, node)
+				}
+			}
+			return expr != nil
+		})
+	}
+	return nil
+}
+
+// function records a function.
+func (s *stats) function(name, path string, node ast.Expr) {
+	s.Functions++
+	s.Longest = append(s.Longest, functionStats{
+		Name: name, Path: path, Line: node.Pos().Line, Lines: node.End().Line - node.Pos().Line + 1,
+	})
+}
+
+// keepLongest keeps the n longest functions, the longest first and then in source order.
+func (s *stats) keepLongest(n int) {
+	slices.SortStableFunc(s.Longest, func(a, b functionStats) int {
+		return cmp.Or(cmp.Compare(b.Lines, a.Lines), cmp.Compare(a.Path, b.Path), cmp.Compare(a.Line, b.Line))
+	})
+	s.Longest = s.Longest[:min(n, len(s.Longest))]
+}
+
+func (s *stats) String() string {
+	var res strings.Builder
+	fmt.Fprintf(&res, "files      %d\n", s.Files)
+	fmt.Fprintf(&res, "lines      %d (code %d, comment %d, blank %d)\n", s.Lines, s.Code, s.Comment, s.Blank)
+	fmt.Fprintf(&res, "forms      %d\n", s.Forms)
+	fmt.Fprintf(&res, "functions  %d\n", s.Functions)
+	fmt.Fprintf(&res, "comments   %.1f%% of the non-blank lines\n", 100*s.CommentRatio())
+	if len(s.Longest) > 0 {
+		res.WriteString("longest functions:\n")
+	}
+	for _, function := range s.Longest {
+		name := function.Name
+		if name == "" {
+			name = "<lambda>"
+		}
+		fmt.Fprintf(&res, "  %4d  %s:%d  %s\n", function.Lines, function.Path, function.Line, name)
+	}
+	return res.String()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	files := []struct{ path, input string }{
+		{"a.harp", `; Helpers.
+
+(fun square [n] ; Squared.
+  (* n n))
+(def twice (lambda [f x]
+  (f (f x))))
+`},
+		{"b.harp", "(def x <<END\nheredoc\nEND)\n#| Block\ncomment |#\n(map (lambda [y] y) [x])"},
+	}
+	res := &stats{}
+	for _, file := range files {
+		if err := res.add(file.path, file.input); err != nil {
+			t.Fatalf("unexpected error in %s: %s", file.path, err)
+		}
+	}
+	res.keepLongest(2)
+
+	expected := &stats{
+		Files: 2, Lines: 12, Code: 8, Comment: 3, Blank: 1, Forms: 4, Functions: 3,
+		Longest: []functionStats{
+			{Name: "square", Path: "a.harp", Line: 3, Lines: 2},
+			{Name: "twice", Path: "a.harp", Line: 5, Lines: 2},
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, res)
+	}
+	if ratio := res.CommentRatio(); ratio != 3.0/11 {
+		t.Errorf("expected a comment ratio of 3/11, got %f", ratio)
+	}
+}
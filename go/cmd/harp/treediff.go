@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"mooss/harp/ast"
+	"reflect"
+	"strings"
+)
+
+// change is a difference between the top-level forms of two versions of a file.
+type change struct {
+	// kind is either "removed", "added", "changed", "moved" or "renamed".
+	kind string
+	// before and after are the form in each version, nil when it is missing from a version.
+	before, after ast.Expr
+}
+
+// formChanges returns the changes turning the forms before into the forms after, in the order of
+// the forms. Forms are compared by structure, so that formatting and comments are ignored.
+// The forms kept in place are found along their longest common subsequence, then among the others:
+//   - a form found in both versions is moved,
+//   - a definition whose name is found in both versions is changed,
+//   - a definition that only differs by its name, including its recursive uses, is renamed.
+func formChanges(before, after []ast.Expr) []change {
+	edits := lineEdits(printAll(before), printAll(after))
+
+	// removed and added are the indices of the forms that are not kept in place.
+	removed, added := []int{}, []int{}
+	for i, j, k := 0, 0, 0; k < len(edits); k++ {
+		switch edits[k].op {
+		case ' ':
+			i++
+			j++
+		case '-':
+			removed = append(removed, i)
+			i++
+		case '+':
+			added = append(added, j)
+			j++
+		}
+	}
+
+	// paired maps the indices of the forms after to the changes pairing them with a form before,
+	// matched tells which forms before are paired.
+	paired, matched := map[int]change{}, map[int]bool{}
+	pair := func(kind string, match func(a, b ast.Expr) bool) {
+		for _, j := range added {
+			for _, i := range removed {
+				if _, ok := paired[j]; !ok && !matched[i] && match(before[i], after[j]) {
+					paired[j], matched[i] = change{kind, before[i], after[j]}, true
+				}
+			}
+		}
+	}
+	pair("moved", ast.Equal)
+	pair("changed", func(a, b ast.Expr) bool {
+		nameA, okA := definedName(a)
+		nameB, okB := definedName(b)
+		return okA && okB && sameKind(a, b) && nameA == nameB
+	})
+	pair("renamed", renamed)
+
+	res := []change{}
+	for i, j, k := 0, 0, 0; k < len(edits); k++ {
+		switch edits[k].op {
+		case ' ':
+			i++
+			j++
+		case '-':
+			if !matched[i] {
+				res = append(res, change{kind: "removed", before: before[i]})
+			}
+			i++
+		case '+':
+			if c, ok := paired[j]; ok {
+				res = append(res, c)
+			} else {
+				res = append(res, change{kind: "added", after: after[j]})
+			}
+			j++
+		}
+	}
+	return res
+}
+
+func printAll(forms []ast.Expr) []string {
+	res := make([]string, len(forms))
+	for i, form := range forms {
+		res[i] = ast.Print(form)
+	}
+	return res
+}
+
+// definedName returns the name defined by a def, fun or struct.
+func definedName(form ast.Expr) (string, bool) {
+	switch form := form.(type) {
+	case ast.Def:
+		return form.Name.Name, true
+	case ast.Fun:
+		return form.Name.Name, true
+	case ast.Struct:
+		return form.Name.Name, true
+	}
+	return "", false
+}
+
+// sameKind tells whether two forms are the same kind of node.
+func sameKind(a, b ast.Expr) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+// renamed tells whether two definitions are equal once the name defined by a is replaced by the
+// name defined by b.
+func renamed(a, b ast.Expr) bool {
+	old, okA := definedName(a)
+	name, okB := definedName(b)
+	if !okA || !okB || !sameKind(a, b) || old == name {
+		return false
+	}
+
+	a = ast.Rewrite(a, func(expr ast.Expr) ast.Expr {
+		if symbol, ok := expr.(ast.Symbol); ok && symbol.Name == old {
+			symbol.Name = name
+			return symbol
+		}
+		return expr
+	})
+	switch node := a.(type) {
+	case ast.Def:
+		node.Name.Name = name
+		a = node
+	case ast.Fun:
+		node.Name.Name = name
+		a = node
+	case ast.Struct:
+		node.Name.Name = name
+		a = node
+	}
+	return ast.Equal(a, b)
+}
+
+// describeChanges renders changes, one per line followed by details indented by two spaces: the
+// removed and added forms, and the differences of the changed definitions.
+func describeChanges(pathBefore, pathAfter string, changes []change) string {
+	var res strings.Builder
+	location := func(path string, form ast.Expr) string {
+		return fmt.Sprintf("%s:%d", path, form.Pos().Line)
+	}
+	indent := func(text string) {
+		for _, line := range strings.Split(text, "\n") {
+			res.WriteString("  " + line + "\n")
+		}
+	}
+
+	for _, c := range changes {
+		switch c.kind {
+		case "removed":
+			fmt.Fprintf(&res, "removed %s\n", location(pathBefore, c.before))
+			indent(ast.Print(c.before))
+		case "added":
+			fmt.Fprintf(&res, "added %s\n", location(pathAfter, c.after))
+			indent(ast.Print(c.after))
+		default:
+			fmt.Fprintf(&res, "%s %s -> %s: ", c.kind, location(pathBefore, c.before), location(pathAfter, c.after))
+			name, isDefinition := definedName(c.before)
+			after, _ := definedName(c.after)
+			switch {
+			case c.kind == "renamed":
+				res.WriteString(name + " -> " + after + "\n")
+			case isDefinition:
+				res.WriteString(name + "\n")
+			default:
+				res.WriteString(strings.ReplaceAll(ast.Print(c.before), "\n", " ") + "\n")
+			}
+
+			if c.kind == "changed" {
+				for _, difference := range ast.Diff(c.before, c.after) {
+					indent(difference.String())
+				}
+			}
+		}
+	}
+	return res.String()
+}
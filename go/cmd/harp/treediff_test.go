@@ -0,0 +1,72 @@
+package main
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"testing"
+)
+
+func TestFormChanges(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		expected      string
+	}{
+		{"Formatting and comments", "(def x\n  1) ; One.\n(f x)", "(def x 1)\n\n(f   x)", ""},
+		{
+			name:     "Removed and added",
+			before:   "(def x 1)\n(f x)",
+			after:    "(def x 1)\n(g x)\n[1]",
+			expected: "removed a.harp:2\n  (f x)\nadded b.harp:2\n  (g x)\nadded b.harp:3\n  [1]\n",
+		},
+		{
+			name:     "Moved",
+			before:   "(def x 1)\n(def y 2)\n(f x y)",
+			after:    "(def y 2)\n(f x y)\n(def x 1)",
+			expected: "moved a.harp:1 -> b.harp:3: x\n",
+		},
+		{
+			name:     "Moved expression",
+			before:   "(f 1)\n(g 2)",
+			after:    "(g 2)\n(f 1)",
+			expected: "moved a.harp:1 -> b.harp:2: (f 1)\n",
+		},
+		{
+			name:     "Changed",
+			before:   "(fun f [n] (+ n 1))",
+			after:    "(fun f [n]\n  (+ n 2))",
+			expected: "changed a.harp:1 -> b.harp:1: f\n  .Body[0].Arguments[1].Value: 1 != 2\n",
+		},
+		{
+			name:     "Renamed",
+			before:   "(def a 0)\n(fun f [n] (when [(< n 1) 0] [else (f (- n 1))]))",
+			after:    "(def a 0)\n(fun loop-down [n] (when [(< n 1) 0] [else (loop-down (- n 1))]))",
+			expected: "renamed a.harp:2 -> b.harp:2: f -> loop-down\n",
+		},
+		{
+			name:     "Different kinds are not renamed",
+			before:   "(def x 1)",
+			after:    "(struct x [a 1])",
+			expected: "removed a.harp:1\n  (def x 1)\nadded b.harp:1\n  (struct x [a 1])\n",
+		},
+	}
+
+	parseAll := func(t *testing.T, input string) []ast.Expr {
+		t.Helper()
+		forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+		if err != nil {
+			t.Fatalf("unexpected parse error: %s", err)
+		}
+		return forms
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := formChanges(parseAll(t, tt.before), parseAll(t, tt.after))
+			if got := describeChanges("a.harp", "b.harp", changes); got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package diag renders errors located in Harp source code as human-friendly diagnostics, showing
+// the offending line with a marker under the faulty token.
+package diag
+
+import (
+	"errors"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"mooss/harp/position"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used when rendering with colors.
+const (
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	reset = "\x1b[0m"
+)
+
+// Locate returns the token an error is about, when it is a lexical or a parse error.
+func Locate(err error) (lex.Token, bool) {
+	var lerr *lex.LexicalError
+	if errors.As(err, &lerr) {
+		return lerr.Token, true
+	}
+
+	var perr *parse.ParseError
+	if errors.As(err, &perr) {
+		return perr.Token, true
+	}
+
+	return lex.Token{}, false
+}
+
+// Render formats err as a multi-line diagnostic, e.g.
+//
+//	lexical error at line 1 column 3: met non-digit while reading number
+//	 1 | (f 1a)
+//	   |    ^
+//
+// The fixes suggested for the error are listed after the excerpt, one per line starting with
+// "help: ".
+// The excerpt is taken from the source of the token when it has one and from input otherwise.
+// Errors that are not located, or whose location falls outside of the input, are rendered as is.
+// When color is true, the message and the marker are highlighted with ANSI escape sequences.
+func Render(err error, input string, color bool) string {
+	tok, ok := Locate(err)
+	if !ok {
+		return err.Error()
+	}
+
+	var index *position.LineIndex
+	if tok.Source != nil {
+		input, index = tok.Source.Content, tok.Source.Index()
+	} else {
+		index = position.NewLineIndex(input)
+	}
+	pos, perr := index.Position(tok.Offset)
+	if perr != nil || tok.Offset < len(input) && !utf8.RuneStart(input[tok.Offset]) {
+		return err.Error()
+	}
+
+	start, end, _ := index.Bounds(pos.Line)
+	line := strings.TrimSuffix(input[start:end], "\r")
+
+	// The marker spans the token up to the end of the line, and at least one column for EOF.
+	width := utf8.RuneCountInString(input[tok.Offset:min(tok.End(), end)])
+	marker := "^" + strings.Repeat("~", max(width-1, 0))
+
+	number := strconv.Itoa(tok.Line)
+	gutter := strings.Repeat(" ", len(number)+2) + "| "
+
+	var res strings.Builder
+	if color {
+		res.WriteString(bold + err.Error() + reset)
+	} else {
+		res.WriteString(err.Error())
+	}
+	res.WriteString("\n " + number + " | " + line + "\n" + gutter)
+	res.WriteString(padding(input[start:tok.Offset]))
+	if color {
+		res.WriteString(red + marker + reset)
+	} else {
+		res.WriteString(marker)
+	}
+	res.WriteString(renderFixes(Suggest(err, input)))
+	return res.String()
+}
+
+// padding returns the whitespace needed to align a marker with the end of prefix.
+// Tabs are kept so that the marker stays aligned whatever the tab width of the terminal.
+func padding(prefix string) string {
+	var res strings.Builder
+	for _, r := range prefix {
+		if r == '\t' {
+			res.WriteRune('\t')
+		} else {
+			res.WriteRune(' ')
+		}
+	}
+	return res.String()
+}
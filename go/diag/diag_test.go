@@ -0,0 +1,134 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "Lexical error",
+			input: "(f\n  1.2.3)",
+			expected: "lexical error at line 2 column 2: met a second dot while reading float\n" +
+				" 2 |   1.2.3)\n" +
+				"   |   ^~~",
+		},
+		{
+			name:  "Parse error",
+			input: "(def 12 x)",
+			expected: "parse error at line 1 column 5: expected a symbol: \"12\"\n" +
+				" 1 | (def 12 x)\n" +
+				"   |      ^~",
+		},
+		{
+			name:  "Tabs are kept for alignment",
+			input: "\t(f 1]",
+			expected: "parse error at line 1 column 5: " +
+				"met closing delimiter that does not match the opening delimiter: \"]\"\n" +
+				" 1 | \t(f 1]\n" +
+				"   | \t    ^\n" +
+				"help: replace \"]\" with \")\"",
+		},
+		{
+			name:  "Error at EOF",
+			input: "(f",
+			expected: "parse error at line 1 column 0: met EOF before the end of the form\n" +
+				" 1 | (f\n" +
+				"   | ^\n" +
+				"help: insert \")\"",
+		},
+		{
+			name:  "Multi-line token",
+			input: "(def #\"ab\nc\"# 1)",
+			expected: "parse error at line 1 column 5: expected a symbol: \"#\\\"ab\\nc\\\"#\"\n" +
+				" 1 | (def #\"ab\n" +
+				"   |      ^~~~",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse.NewParser(lex.NewLexer(tt.input)).Parse()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if got := Render(err, tt.input, false); got != tt.expected {
+				t.Errorf("expected:\n> %q\ngot:\n> %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderSource(t *testing.T) {
+	src := &lex.Source{Name: "main.harp", Content: "(f $)"}
+	_, err := parse.NewParser(lex.NewSourceLexer(src)).Parse()
+
+	// The input is ignored in favor of the source of the token, and wrapping is transparent.
+	got := Render(fmt.Errorf("loading: %w", err), "", true)
+	expected := bold + "loading: lexical error in main.harp at line 1 column 3: " +
+		"met character that is not a valid token start: string($) hex(24)" + reset + "\n" +
+		" 1 | (f $)\n" +
+		"   |    " + red + "^" + reset
+	if got != expected {
+		t.Errorf("expected:\n> %q\ngot:\n> %q", expected, got)
+	}
+}
+
+func TestRenderUnlocated(t *testing.T) {
+	err := errors.New("boom")
+	if got := Render(err, "(f)", true); got != "boom" {
+		t.Errorf("expected the bare message, got %q", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		message  string
+		expected string // Input once the fix is applied.
+	}{
+		{"Unclosed forms", "(f [1 {a", `insert "}])"`, "(f [1 {a}])"},
+		{"Unclosed set", "(f #{a", `insert "})"`, "(f #{a})"},
+		{"Unclosed string", `(f "a`, `insert "\""`, `(f "a"`},
+		{"Unclosed raw string", "(f #\"a\nb", `insert "\"#"`, "(f #\"a\nb\"#"},
+		{"Unclosed heredoc", "(f <<~SQL\n  x", `insert "\nSQL"`, "(f <<~SQL\n  x\nSQL"},
+		{"Unclosed heredoc after a newline", "(f <<END \nx\n", `insert "END"`, "(f <<END \nx\nEND"},
+		{"Mismatched closer", "(f [1) 2]", `replace ")" with "]"`, "(f [1] 2]"},
+		{"Unexpected closer", "(f 1))", `remove ")"`, "(f 1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse.NewParser(lex.NewLexer(tt.input)).Parse()
+			fixes := Suggest(err, tt.input)
+			if len(fixes) != 1 {
+				t.Fatalf("expected one fix for %q, got %v", err, fixes)
+			}
+
+			if fixes[0].Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, fixes[0].Message)
+			}
+			if got := Apply(tt.input, fixes[0].Edits); got != tt.expected {
+				t.Errorf("expected %q once fixed, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	unknown := &lex.LexicalError{Token: lex.Token{Type: lex.TOKEN_SYMBOL, Literal: "a"}, Reason: lex.EofInString}
+	if fixes := Suggest(unknown, "a"); fixes != nil {
+		t.Errorf("expected no fix for an unterminated token of unknown kind, got %v", fixes)
+	}
+	if fixes := Suggest(errors.New("boom"), ""); fixes != nil {
+		t.Errorf("expected no fix for an unlocated error, got %v", fixes)
+	}
+}
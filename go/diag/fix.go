@@ -0,0 +1,129 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"slices"
+	"strings"
+)
+
+// Edit replaces the bytes input[Offset:Offset+Length] with Text.
+type Edit struct {
+	Offset int
+	Length int
+	Text   string
+}
+
+// Fix is a correction of an error that can be applied mechanically, e.g. by an editor.
+type Fix struct {
+	// Message describes the fix to a human, e.g. `insert ")"`.
+	Message string
+	Edits   []Edit
+}
+
+// closers maps the opening delimiters to their closing delimiter.
+var closers = map[lex.TokenType]string{
+	lex.TOKEN_LPAREN: ")", lex.TOKEN_LBRACKET: "]", lex.TOKEN_LBRACE: "}", lex.TOKEN_SET: "}",
+}
+
+// Suggest returns the fixes of an error in input, if any.
+// As in Render, the source of the token of the error takes precedence over input.
+func Suggest(err error, input string) []Fix {
+	tok, ok := Locate(err)
+	if tok.Source != nil {
+		input = tok.Source.Content
+	}
+	if !ok || tok.Offset < 0 || tok.End() > len(input) {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, lex.EofInString):
+		if closer := stringCloser(tok); closer != "" {
+			return []Fix{insert(closer, len(input))}
+		}
+	case errors.Is(err, parse.EofInForm):
+		missing := ""
+		for _, open := range slices.Backward(unclosed(input, len(input))) {
+			missing += closers[open.Type]
+		}
+		if missing != "" {
+			return []Fix{insert(missing, len(input))}
+		}
+	case errors.Is(err, parse.MismatchedCloser):
+		if open := unclosed(input, tok.Offset); len(open) > 0 {
+			closer := closers[open[len(open)-1].Type]
+			return []Fix{{
+				Message: fmt.Sprintf("replace %q with %q", tok.Literal, closer),
+				Edits:   []Edit{{tok.Offset, tok.Length, closer}},
+			}}
+		}
+	case errors.Is(err, parse.UnexpectedCloser):
+		return []Fix{{Message: fmt.Sprintf("remove %q", tok.Literal), Edits: []Edit{{tok.Offset, tok.Length, ""}}}}
+	}
+	return nil
+}
+
+// insert builds a fix inserting text at offset.
+func insert(text string, offset int) Fix {
+	return Fix{Message: fmt.Sprintf("insert %q", text), Edits: []Edit{{offset, 0, text}}}
+}
+
+// stringCloser returns the text closing the unterminated string read as tok, or "" when unknown.
+func stringCloser(tok lex.Token) string {
+	switch tok.Type {
+	case lex.TOKEN_DQSTRING:
+		return `"`
+	case lex.TOKEN_RAWSTRING:
+		return `"#`
+	case lex.TOKEN_HEREDOC:
+		// The tag runs up to the end of the header line, which the lexer checked.
+		header, _, _ := strings.Cut(strings.TrimPrefix(tok.Literal, "<<"), "\n")
+		tag := strings.TrimRight(strings.TrimPrefix(header, "~"), " \t\r")
+		if tag == "" {
+			return ""
+		}
+		if strings.HasSuffix(tok.Literal, "\n") {
+			return tag
+		}
+		return "\n" + tag
+	}
+	return ""
+}
+
+// unclosed returns the opening delimiters of input[:end] that are not closed, outermost first.
+// Closers that do not match the innermost opening delimiter are ignored.
+func unclosed(input string, end int) []lex.Token {
+	stack := []lex.Token{}
+	for tok := range lex.NewLexer(input[:end]).Tokens() {
+		if _, ok := closers[tok.Type]; ok {
+			stack = append(stack, tok)
+		} else if len(stack) > 0 && tok.Literal == closers[stack[len(stack)-1].Type] {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return stack
+}
+
+// Apply returns input with the edits applied.
+// The edits must not overlap, they are applied from the last one so that offsets stay valid.
+func Apply(input string, edits []Edit) string {
+	edits = slices.Clone(edits)
+	slices.SortFunc(edits, func(a, b Edit) int { return b.Offset - a.Offset })
+
+	for _, edit := range edits {
+		input = input[:edit.Offset] + edit.Text + input[edit.Offset+edit.Length:]
+	}
+	return input
+}
+
+// renderFixes formats fixes as help lines to append to a diagnostic.
+func renderFixes(fixes []Fix) string {
+	var res strings.Builder
+	for _, fix := range fixes {
+		res.WriteString("\nhelp: " + fix.Message)
+	}
+	return res.String()
+}
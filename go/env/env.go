@@ -0,0 +1,131 @@
+// Package env implements the environments where the evaluator stores the values of names.
+//
+// Environments are chained: each one is a scope holding the names it defines, and the names it does
+// not define are looked up in its parent. The evaluator creates a child environment for each
+// function call and each let or loop, so that scoping is lexical:
+//   - Define binds a name in a scope, shadowing the bindings of the same name in the enclosing
+//     scopes until the scope ends, and replacing any binding of the same name in the scope itself,
+//   - Set changes the closest binding of a name, which is visible to every scope sharing it,
+//   - Get reads the closest binding of a name.
+//
+// Closures capture the environment where they are created rather than a copy of it: they see the
+// definitions and assignments made in that environment after their creation, which is how functions
+// can be recursive or refer to the functions defined after them. Each call of a function has an
+// environment of its own, so closures created by different calls do not share their local state.
+//
+// Environments are not synchronized, they must not be modified while being read from other
+// goroutines.
+package env
+
+import "sort"
+
+// Predeclared returns the value of a name that is visible from every scope without being defined,
+// like a builtin, and false when there is no such name.
+type Predeclared func(name string) (any, bool)
+
+// Environment maps names to values.
+type Environment struct {
+	// parent is the enclosing environment, nil for a root environment.
+	parent *Environment
+
+	// values holds the values defined in this very environment.
+	values map[string]any
+
+	// predeclared is looked up when a name is not in values, nil except for root environments.
+	// Predeclared values are never copied to values, so that creating a root environment does not
+	// depend on their number and so that looking up a name never writes to the environment.
+	predeclared Predeclared
+
+	// depth is the number of function calls in progress when the environment is used, 0 for a root
+	// environment, see NewCall.
+	depth int
+}
+
+// New creates a root environment, where the names that are not defined are looked up with
+// predeclared, which may be nil when there are no predeclared names.
+func New(predeclared Predeclared) *Environment {
+	return &Environment{values: map[string]any{}, predeclared: predeclared}
+}
+
+// NewChild creates an environment enclosed by this one, e.g. for a let, at the same depth.
+func (env *Environment) NewChild() *Environment {
+	return &Environment{parent: env, values: map[string]any{}, depth: env.depth}
+}
+
+// NewCall creates an environment enclosed by this one for a function call, depth being the number
+// of calls in progress including this one. It differs from the depth of this environment, which is
+// where the function was defined rather than where it is called.
+func (env *Environment) NewCall(depth int) *Environment {
+	return &Environment{parent: env, values: map[string]any{}, depth: depth}
+}
+
+// Depth returns the number of function calls in progress when the environment is used.
+func (env *Environment) Depth() int {
+	return env.depth
+}
+
+// Parent returns the enclosing environment, nil for a root environment.
+func (env *Environment) Parent() *Environment {
+	return env.parent
+}
+
+// Define binds name to value in this environment, shadowing any binding from the parents and any
+// predeclared value.
+func (env *Environment) Define(name string, value any) {
+	env.values[name] = value
+}
+
+// Set changes the value of the closest existing binding of name.
+// Setting a predeclared name defines it in the root environment, shadowing the predeclared value
+// from then on. Set returns false when name is not bound.
+func (env *Environment) Set(name string, value any) bool {
+	for cur := env; cur != nil; cur = cur.parent {
+		if _, ok := cur.values[name]; ok {
+			cur.values[name] = value
+			return true
+		}
+		if _, ok := cur.lookupPredeclared(name); ok {
+			cur.values[name] = value
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the value of the closest binding of name.
+func (env *Environment) Get(name string) (any, bool) {
+	for cur := env; cur != nil; cur = cur.parent {
+		if value, ok := cur.values[name]; ok {
+			return value, true
+		}
+		if value, ok := cur.lookupPredeclared(name); ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Defines tells whether name is defined in this very environment, predeclared names excluded.
+func (env *Environment) Defines(name string) bool {
+	_, ok := env.values[name]
+	return ok
+}
+
+// Names returns the names defined in this very environment, sorted, predeclared names excluded.
+func (env *Environment) Names() []string {
+	res := make([]string, 0, len(env.values))
+	for name := range env.values {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (env *Environment) lookupPredeclared(name string) (any, bool) {
+	if env.predeclared == nil {
+		return nil, false
+	}
+	return env.predeclared(name)
+}
@@ -0,0 +1,119 @@
+package env
+
+import (
+	"reflect"
+	"testing"
+)
+
+// predeclared defines print, as a builtin would.
+func predeclared(name string) (any, bool) {
+	if name == "print" {
+		return "<builtin print>", true
+	}
+	return nil, false
+}
+
+func TestShadowing(t *testing.T) {
+	root := New(predeclared)
+	root.Define("x", 1)
+	child := root.NewChild()
+	child.Define("x", 2)
+	child.Define("print", 3)
+	grandchild := child.NewChild()
+
+	tests := []struct {
+		name     string
+		env      *Environment
+		variable string
+		expected any
+	}{
+		{"Defined", root, "x", 1},
+		{"Predeclared", root, "print", "<builtin print>"},
+		{"Shadowing a definition", child, "x", 2},
+		{"Shadowing a predeclared name", child, "print", 3},
+		{"Closest definition", grandchild, "x", 2},
+		{"Predeclared from a child", New(predeclared).NewChild(), "print", "<builtin print>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.env.Get(tt.variable)
+			if !ok || got != tt.expected {
+				t.Errorf("expected %v, got %v (%t)", tt.expected, got, ok)
+			}
+		})
+	}
+
+	if _, ok := child.Get("missing"); ok {
+		t.Error("expected missing to be unbound")
+	}
+	if _, ok := New(nil).Get("print"); ok {
+		t.Error("expected print to be unbound without predeclared names")
+	}
+	if !child.Defines("x") || grandchild.Defines("x") || root.Defines("print") {
+		t.Error("expected Defines to only report the definitions of the environment itself")
+	}
+	if names := child.Names(); !reflect.DeepEqual(names, []string{"print", "x"}) {
+		t.Errorf("expected the names of the child, got %v", names)
+	}
+}
+
+func TestMutation(t *testing.T) {
+	root := New(predeclared)
+	root.Define("x", 1)
+	child := root.NewChild()
+	child.Define("y", 1)
+	sibling := root.NewChild()
+
+	// Setting a name changes its closest binding, which is shared by the scopes seeing it.
+	if !child.NewChild().Set("x", 2) || !child.Set("y", 2) {
+		t.Fatal("expected x and y to be set")
+	}
+	if x, _ := sibling.Get("x"); x != 2 {
+		t.Errorf("expected the sibling to see the new x, got %v", x)
+	}
+	if child.Defines("x") {
+		t.Error("expected x to stay defined in the root only")
+	}
+	if _, ok := sibling.Get("y"); ok {
+		t.Error("expected y to stay local to the child")
+	}
+
+	// Setting a shadowing name leaves the shadowed binding as is.
+	child.Define("x", 3)
+	child.Set("x", 4)
+	if x, _ := root.Get("x"); x != 2 {
+		t.Errorf("expected the shadowed x to be kept, got %v", x)
+	}
+
+	// Setting a predeclared name shadows it in the root, but not in other roots.
+	if !child.Set("print", 5) || !root.Defines("print") {
+		t.Error("expected print to be defined in the root")
+	}
+	if print, _ := sibling.Get("print"); print != 5 {
+		t.Errorf("expected the new print, got %v", print)
+	}
+	if print, _ := New(predeclared).Get("print"); print != "<builtin print>" {
+		t.Errorf("expected the predeclared print in another root, got %v", print)
+	}
+
+	if child.Set("missing", 1) || child.Defines("missing") || root.Defines("missing") {
+		t.Error("expected setting an unbound name to fail without defining it")
+	}
+}
+
+func TestParent(t *testing.T) {
+	root := New(nil)
+	if child := root.NewChild(); child.Parent() != root || root.Parent() != nil {
+		t.Error("expected the child to be enclosed by the root only")
+	}
+}
+
+func TestDepth(t *testing.T) {
+	root := New(nil)
+	call := root.NewChild().NewCall(3)
+	if root.Depth() != 0 || call.Depth() != 3 || call.NewChild().Depth() != 3 {
+		t.Errorf("expected depths 0, 3 and 3, got %d, %d and %d",
+			root.Depth(), call.Depth(), call.NewChild().Depth())
+	}
+}
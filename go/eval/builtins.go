@@ -0,0 +1,109 @@
+package eval
+
+import (
+	"fmt"
+	"time"
+)
+
+// builtins are the functions implemented in Go that are visible from every global environment,
+// indexed by name. They are registered by the init functions of the files implementing them.
+var builtins = map[string]*Builtin{}
+
+// Register adds functions to the builtins visible from global environments, replacing the builtins
+// with the same names. Embedders can use it to provide their own functions; it must be called
+// before evaluating code since builtins are looked up without synchronization.
+func Register(functions ...*Builtin) {
+	for _, function := range functions {
+		builtins[function.Name] = function
+	}
+}
+
+const (
+	WrongType    RuntimeFailure = "met argument of the wrong type"
+	InvalidValue RuntimeFailure = "met argument with an invalid value"
+)
+
+// arity checks that a builtin received between min and max arguments (max < 0 means no limit).
+func arity(name string, args []any, min, max int) error {
+	if len(args) >= min && (max < 0 || len(args) <= max) {
+		return nil
+	}
+
+	expected := fmt.Sprint(min)
+	switch {
+	case max < 0:
+		expected = fmt.Sprintf("at least %d", min)
+	case max != min:
+		expected = fmt.Sprintf("between %d and %d", min, max)
+	}
+
+	return &RuntimeError{Reason: WrongArity.With("%s expects %s, got %d", name, expected, len(args))}
+}
+
+// argument returns the i-th argument of a builtin, checking that it has the expected type.
+// what describes the expected type in the error message.
+func argument[T any](name string, args []any, i int, what string) (T, error) {
+	res, ok := args[i].(T)
+	if !ok {
+		return res, &RuntimeError{Reason: WrongType.With(
+			"argument %d of %s must be %s, got %s", i+1, name, what, Repr(args[i]),
+		)}
+	}
+
+	return res, nil
+}
+
+/////////////
+// Options //
+
+// option returns the value of an option from a map given to a builtin, the key of the option being
+// either a keyword (:max) or a string ("max").
+func option(opts map[any]any, key string) (any, bool) {
+	if value, ok := opts[Keyword(key)]; ok {
+		return value, true
+	}
+	value, ok := opts[key]
+	return value, ok
+}
+
+// intOption returns a non-negative int option, or def if the option is missing.
+func intOption(name string, opts map[any]any, key string, def int64) (int64, error) {
+	value, ok := option(opts, key)
+	if !ok {
+		return def, nil
+	}
+
+	res, ok := value.(int64)
+	if !ok || res < 0 {
+		return 0, &RuntimeError{Reason: InvalidValue.With(
+			"%s %s must be a non-negative int, got %s", name, key, Repr(value),
+		)}
+	}
+	return res, nil
+}
+
+// duration converts a string like "5m" or "1.5s" to a non-negative duration.
+func duration(value any) (time.Duration, bool) {
+	spec, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	res, err := time.ParseDuration(spec)
+	return res, err == nil && res >= 0
+}
+
+// durationOption returns a duration option, or def if the option is missing.
+func durationOption(name string, opts map[any]any, key string, def time.Duration) (time.Duration, error) {
+	value, ok := option(opts, key)
+	if !ok {
+		return def, nil
+	}
+
+	res, ok := duration(value)
+	if !ok {
+		return 0, &RuntimeError{Reason: InvalidValue.With(
+			"%s %s must be a duration like \"5m\", got %s", name, key, Repr(value),
+		)}
+	}
+	return res, nil
+}
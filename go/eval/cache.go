@@ -0,0 +1,273 @@
+package eval
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache is a key/value store with bounded memory usage, meant for long-running programs.
+// When it is full, putting a new key evicts the least recently used entry.
+// Entries older than the time to live are treated as absent, they are evicted when read and when
+// new entries are put, so that keys that are never read again do not stay in the cache.
+type Cache struct {
+	// max is the maximum number of entries, 0 meaning no limit.
+	max int
+
+	// ttl is the time to live of the entries, 0 meaning that they never expire.
+	ttl time.Duration
+
+	// entries indexes the elements of order by key.
+	entries map[any]*list.Element
+
+	// order holds the *cacheEntry values, from the most to the least recently used.
+	order *list.List
+
+	// expiring holds the elements of order from the earliest to the latest expiry, which is the
+	// order in which they were put since they all live for ttl. It is empty when ttl is 0.
+	expiring *list.List
+
+	hits, misses, evictions int64
+
+	// now returns the current time, it can be replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+}
+
+type cacheEntry struct {
+	key, value any
+	expiry     time.Time
+	// expiring is the element of the entry in Cache.expiring, nil when entries do not expire.
+	expiring *list.Element
+}
+
+func NewCache(max int, ttl time.Duration) *Cache {
+	return &Cache{
+		max:      max,
+		ttl:      ttl,
+		entries:  map[any]*list.Element{},
+		order:    list.New(),
+		expiring: list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *Cache) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("<cache %d/%d>", c.order.Len(), c.max)
+}
+
+// Get returns the value of key and whether it was present (and not expired).
+func (c *Cache) Get(key any) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elt, ok := c.entries[key]
+	if ok && c.expired(elt) {
+		c.remove(elt)
+		c.evictions++
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(elt)
+	return elt.Value.(*cacheEntry).value, true
+}
+
+// Put associates value with key, evicting the least recently used entry if the cache is full.
+func (c *Cache) Put(key, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.purge()
+	expiry := time.Time{}
+	if c.ttl > 0 {
+		expiry = c.now().Add(c.ttl)
+	}
+
+	if elt, ok := c.entries[key]; ok {
+		entry := elt.Value.(*cacheEntry)
+		entry.value, entry.expiry = value, expiry
+		c.order.MoveToFront(elt)
+		if entry.expiring != nil {
+			c.expiring.MoveToBack(entry.expiring)
+		}
+		return
+	}
+
+	entry := &cacheEntry{key: key, value: value, expiry: expiry}
+	elt := c.order.PushFront(entry)
+	c.entries[key] = elt
+	if c.ttl > 0 {
+		entry.expiring = c.expiring.PushBack(elt)
+	}
+	if c.max > 0 && c.order.Len() > c.max {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// Evict removes key from the cache and returns whether it was present.
+func (c *Cache) Evict(key any) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elt, ok := c.entries[key]
+	if ok {
+		c.remove(elt)
+	}
+	return ok
+}
+
+// Stats returns the size of the cache and its hit, miss and eviction counters.
+func (c *Cache) Stats() map[any]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return map[any]any{
+		Keyword("size"):      int64(c.order.Len()),
+		Keyword("hits"):      c.hits,
+		Keyword("misses"):    c.misses,
+		Keyword("evictions"): c.evictions,
+	}
+}
+
+// purge evicts the expired entries.
+func (c *Cache) purge() {
+	for front := c.expiring.Front(); front != nil; front = c.expiring.Front() {
+		elt := front.Value.(*list.Element)
+		if !c.expired(elt) {
+			return
+		}
+		c.remove(elt)
+		c.evictions++
+	}
+}
+
+func (c *Cache) expired(elt *list.Element) bool {
+	expiry := elt.Value.(*cacheEntry).expiry
+	return !expiry.IsZero() && !c.now().Before(expiry)
+}
+
+func (c *Cache) remove(elt *list.Element) {
+	entry := elt.Value.(*cacheEntry)
+	c.order.Remove(elt)
+	if entry.expiring != nil {
+		c.expiring.Remove(entry.expiring)
+	}
+	delete(c.entries, entry.key)
+}
+
+//////////////
+// Builtins //
+
+func init() {
+	Register(
+		&Builtin{Name: "cache", Fun: builtinCache},
+		&Builtin{Name: "cache-get", Fun: builtinCacheGet},
+		&Builtin{Name: "cache-put", Fun: builtinCachePut},
+		&Builtin{Name: "cache-evict", Fun: builtinCacheEvict},
+		&Builtin{Name: "cache-stats", Fun: builtinCacheStats},
+	)
+}
+
+// (cache) or (cache {:max 1000 :ttl "5m"})
+func builtinCache(args []any) (any, error) {
+	if err := arity("cache", args, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return NewCache(0, 0), nil
+	}
+
+	opts, err := argument[map[any]any]("cache", args, 0, "a map of options")
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := intOption("cache", opts, "max", 0)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := durationOption("cache", opts, "ttl", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCache(int(max), ttl), nil
+}
+
+// (cache-get c key) or (cache-get c key default)
+func builtinCacheGet(args []any) (any, error) {
+	if err := arity("cache-get", args, 2, 3); err != nil {
+		return nil, err
+	}
+	c, err := argument[*Cache]("cache-get", args, 0, "a cache")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHashable(args[1]); err != nil {
+		return nil, err
+	}
+
+	if value, ok := c.Get(args[1]); ok {
+		return value, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, nil
+}
+
+// (cache-put c key value), returns value.
+func builtinCachePut(args []any) (any, error) {
+	if err := arity("cache-put", args, 3, 3); err != nil {
+		return nil, err
+	}
+	c, err := argument[*Cache]("cache-put", args, 0, "a cache")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHashable(args[1]); err != nil {
+		return nil, err
+	}
+
+	c.Put(args[1], args[2])
+	return args[2], nil
+}
+
+// (cache-evict c key), returns whether key was present.
+func builtinCacheEvict(args []any) (any, error) {
+	if err := arity("cache-evict", args, 2, 2); err != nil {
+		return nil, err
+	}
+	c, err := argument[*Cache]("cache-evict", args, 0, "a cache")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHashable(args[1]); err != nil {
+		return nil, err
+	}
+
+	return c.Evict(args[1]), nil
+}
+
+// (cache-stats c), returns a map with the :size, :hits, :misses and :evictions of the cache.
+func builtinCacheStats(args []any) (any, error) {
+	if err := arity("cache-stats", args, 1, 1); err != nil {
+		return nil, err
+	}
+	c, err := argument[*Cache]("cache-stats", args, 0, "a cache")
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Stats(), nil
+}
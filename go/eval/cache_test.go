@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2, 0)
+	c.Put("a", int64(1))
+	c.Put("b", int64(2))
+	c.Get("a") // b becomes the least recently used.
+	c.Put("c", int64(3))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be present", key)
+		}
+	}
+
+	expected := "{:evictions 1 :hits 3 :misses 1 :size 2}"
+	if got := Repr(c.Stats()); got != expected {
+		t.Errorf("expected stats:\n> %s\ngot:\n> %s", expected, got)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewCache(0, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Put("a", int64(1))
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("expected a to be present before its expiry")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to be expired")
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewCache(0, time.Minute)
+	c.now = func() time.Time { return now }
+
+	// Keys put once and never read again are evicted once expired.
+	for i := range 100 {
+		c.Put(int64(i), int64(i))
+		now = now.Add(time.Second)
+	}
+	c.Put("a", int64(1)) // Refreshes the expiry of a.
+	now = now.Add(30 * time.Second)
+	c.Put("a", int64(2))
+
+	if size := c.Stats()[Keyword("size")]; size != int64(30) {
+		t.Errorf("expected the 29 keys put less than a minute ago and a, got %v entries", size)
+	}
+	if _, ok := c.Get(int64(70)); ok {
+		t.Errorf("expected 70 to be evicted")
+	}
+	if value, _ := c.Get("a"); value != int64(2) {
+		t.Errorf("expected a to be 2, got %v", value)
+	}
+}
+
+func TestCacheBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Put and get", `(def c (cache)) (cache-put c "k" 1) (cache-get c "k")`, "1"},
+		{"Missing key", `(cache-get (cache) "k")`, "nil"},
+		{"Default value", `(cache-get (cache) "k" 0)`, "0"},
+		{"Evict", `(def c (cache)) (cache-put c 1 1) [(cache-evict c 1) (cache-evict c 1)]`, "[true false]"},
+		{"Options", `(def c (cache {"max" 1 "ttl" "5m"})) (cache-put c 1 1) (cache-put c 2 2) c`, "<cache 1/1>"},
+		{"Keyword options", `(def c (cache {:max 1})) (cache-put c 1 1) (cache-put c 2 2) c`, "<cache 1/1>"},
+		{"Stats", `(def c (cache)) (cache-get c 1) (get (cache-stats c) :misses)`, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestCacheBuiltinsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Not a cache", `(cache-get {} 1)`, WrongType},
+		{"Invalid max", `(cache {"max" "a"})`, InvalidValue},
+		{"Invalid ttl", `(cache {"ttl" "soon"})`, InvalidValue},
+		{"Unhashable key", `(cache-put (cache) [1] 1)`, UnhashableKey},
+		{"Wrong arity", `(cache-put (cache) 1)`, WrongArity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package eval
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Deques and priority queues are mutable collections: their builtins modify them in place.
+// A deque used with deque-push-back and deque-pop-front is a queue.
+
+const EmptyCollection RuntimeFailure = "met an empty collection"
+
+///////////
+// Deque //
+
+// Deque is a double-ended queue stored in a ring buffer, pushing and popping at both ends take
+// amortized constant time.
+type Deque struct {
+	elements []any
+	// head is the index of the front element.
+	head int
+	size int
+}
+
+func (d *Deque) String() string {
+	return fmt.Sprintf("<deque %d>", d.size)
+}
+
+// Len returns the number of elements of the deque.
+func (d *Deque) Len() int {
+	return d.size
+}
+
+// At returns the i-th element from the front, i must be in [0, Len()).
+func (d *Deque) At(i int) any {
+	return d.elements[(d.head+i)%len(d.elements)]
+}
+
+// PushBack adds an element after the back of the deque.
+func (d *Deque) PushBack(element any) {
+	d.grow()
+	d.elements[(d.head+d.size)%len(d.elements)] = element
+	d.size++
+}
+
+// PushFront adds an element before the front of the deque.
+func (d *Deque) PushFront(element any) {
+	d.grow()
+	d.head = (d.head - 1 + len(d.elements)) % len(d.elements)
+	d.elements[d.head] = element
+	d.size++
+}
+
+// PopBack removes and returns the back element, the deque must not be empty.
+func (d *Deque) PopBack() any {
+	i := (d.head + d.size - 1) % len(d.elements)
+	res := d.elements[i]
+	d.elements[i] = nil // Let the element be garbage collected.
+	d.size--
+	return res
+}
+
+// PopFront removes and returns the front element, the deque must not be empty.
+func (d *Deque) PopFront() any {
+	res := d.elements[d.head]
+	d.elements[d.head] = nil
+	d.head = (d.head + 1) % len(d.elements)
+	d.size--
+	return res
+}
+
+// grow doubles the capacity of the ring buffer when it is full, moving the front to index 0.
+func (d *Deque) grow() {
+	if d.size < len(d.elements) {
+		return
+	}
+
+	elements := make([]any, max(4, 2*len(d.elements)))
+	for i := 0; i < d.size; i++ {
+		elements[i] = d.At(i)
+	}
+	d.elements, d.head = elements, 0
+}
+
+////////////////////
+// Priority queue //
+
+// PriorityQueue is a binary heap popping its smallest element first, according to a comparator.
+// Elements that compare equal are popped in insertion order.
+type PriorityQueue struct {
+	elements []prioritized
+	// order holds the comparator given to priority-queue, if any, see comparator.
+	order []any
+	// compare is the comparison function of the current operation.
+	compare func(a, b any) (int, error)
+	// pushed counts insertions, to order equal elements.
+	pushed int
+	// failure is the first comparison error of the current operation.
+	failure error
+}
+
+type prioritized struct {
+	element any
+	order   int
+}
+
+func (pq *PriorityQueue) String() string {
+	return fmt.Sprintf("<priority-queue %d>", len(pq.elements))
+}
+
+// Push adds an element to the queue, calling the comparator with call.
+func (pq *PriorityQueue) Push(call Caller, element any) error {
+	pq.failure, pq.compare = nil, comparator(call, pq.order, 0)
+	heap.Push((*pqHeap)(pq), prioritized{element, pq.pushed})
+	pq.pushed++
+	return pq.failure
+}
+
+// Pop removes and returns the smallest element, calling the comparator with call. The queue must
+// not be empty.
+func (pq *PriorityQueue) Pop(call Caller) (any, error) {
+	pq.failure, pq.compare = nil, comparator(call, pq.order, 0)
+	res := heap.Pop((*pqHeap)(pq)).(prioritized).element
+	return res, pq.failure
+}
+
+// Peek returns the smallest element without removing it, the queue must not be empty.
+func (pq *PriorityQueue) Peek() any {
+	return pq.elements[0].element
+}
+
+// pqHeap implements heap.Interface without exposing its methods on PriorityQueue.
+type pqHeap PriorityQueue
+
+func (h *pqHeap) Len() int { return len(h.elements) }
+
+func (h *pqHeap) Less(i, j int) bool {
+	a, b := h.elements[i], h.elements[j]
+	res, err := h.compare(a.element, b.element)
+	if err != nil && h.failure == nil {
+		h.failure = err
+	}
+	return res < 0 || (res == 0 && a.order < b.order)
+}
+
+func (h *pqHeap) Swap(i, j int) { h.elements[i], h.elements[j] = h.elements[j], h.elements[i] }
+func (h *pqHeap) Push(x any)    { h.elements = append(h.elements, x.(prioritized)) }
+func (h *pqHeap) Pop() any {
+	last := h.elements[len(h.elements)-1]
+	h.elements = h.elements[:len(h.elements)-1]
+	return last
+}
+
+//////////////
+// Builtins //
+
+func init() {
+	Register(
+		&Builtin{Name: "deque", Fun: builtinDeque},
+		&Builtin{Name: "deque-push-back", Fun: builtinDequePushBack},
+		&Builtin{Name: "deque-push-front", Fun: builtinDequePushFront},
+		&Builtin{Name: "deque-pop-back", Fun: builtinDequePopBack},
+		&Builtin{Name: "deque-pop-front", Fun: builtinDequePopFront},
+		&Builtin{Name: "deque-peek-back", Fun: builtinDequePeekBack},
+		&Builtin{Name: "deque-peek-front", Fun: builtinDequePeekFront},
+		&Builtin{Name: "deque-size", Fun: builtinDequeSize},
+		&Builtin{Name: "priority-queue", Fun: builtinPriorityQueue},
+		&Builtin{Name: "pq-push", Calls: builtinPqPush},
+		&Builtin{Name: "pq-pop", Calls: builtinPqPop},
+		&Builtin{Name: "pq-peek", Fun: builtinPqPeek},
+		&Builtin{Name: "pq-size", Fun: builtinPqSize},
+	)
+}
+
+// (deque elements...), a deque holding the elements from front to back.
+func builtinDeque(args []any) (any, error) {
+	res := &Deque{}
+	for _, arg := range args {
+		res.PushBack(arg)
+	}
+	return res, nil
+}
+
+// dequeArgument extracts the deque of a deque builtin taking n arguments.
+func dequeArgument(name string, args []any, n int) (*Deque, error) {
+	if err := arity(name, args, n, n); err != nil {
+		return nil, err
+	}
+	return argument[*Deque](name, args, 0, "a deque")
+}
+
+// nonEmptyDeque extracts the deque of a builtin reading one of its ends.
+func nonEmptyDeque(name string, args []any) (*Deque, error) {
+	d, err := dequeArgument(name, args, 1)
+	if err == nil && d.Len() == 0 {
+		return nil, &RuntimeError{Reason: EmptyCollection.With("%s", name)}
+	}
+	return d, err
+}
+
+// (deque-push-back d x), adds x after the back of d and returns d.
+func builtinDequePushBack(args []any) (any, error) {
+	d, err := dequeArgument("deque-push-back", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.PushBack(args[1])
+	return d, nil
+}
+
+// (deque-push-front d x), adds x before the front of d and returns d.
+func builtinDequePushFront(args []any) (any, error) {
+	d, err := dequeArgument("deque-push-front", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	d.PushFront(args[1])
+	return d, nil
+}
+
+// (deque-pop-back d), removes and returns the back element of d.
+func builtinDequePopBack(args []any) (any, error) {
+	d, err := nonEmptyDeque("deque-pop-back", args)
+	if err != nil {
+		return nil, err
+	}
+	return d.PopBack(), nil
+}
+
+// (deque-pop-front d), removes and returns the front element of d.
+func builtinDequePopFront(args []any) (any, error) {
+	d, err := nonEmptyDeque("deque-pop-front", args)
+	if err != nil {
+		return nil, err
+	}
+	return d.PopFront(), nil
+}
+
+// (deque-peek-back d), returns the back element of d.
+func builtinDequePeekBack(args []any) (any, error) {
+	d, err := nonEmptyDeque("deque-peek-back", args)
+	if err != nil {
+		return nil, err
+	}
+	return d.At(d.Len() - 1), nil
+}
+
+// (deque-peek-front d), returns the front element of d.
+func builtinDequePeekFront(args []any) (any, error) {
+	d, err := nonEmptyDeque("deque-peek-front", args)
+	if err != nil {
+		return nil, err
+	}
+	return d.At(0), nil
+}
+
+// (deque-size d)
+func builtinDequeSize(args []any) (any, error) {
+	d, err := dequeArgument("deque-size", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return int64(d.Len()), nil
+}
+
+// (priority-queue) or (priority-queue cmp), an empty priority queue ordering its elements
+// naturally or with a comparator, like sort.
+func builtinPriorityQueue(args []any) (any, error) {
+	if err := arity("priority-queue", args, 0, 1); err != nil {
+		return nil, err
+	}
+	return &PriorityQueue{order: args}, nil
+}
+
+// pqArgument extracts the queue of a priority queue builtin taking n arguments.
+func pqArgument(name string, args []any, n int) (*PriorityQueue, error) {
+	if err := arity(name, args, n, n); err != nil {
+		return nil, err
+	}
+	return argument[*PriorityQueue](name, args, 0, "a priority queue")
+}
+
+// nonEmptyPq extracts the queue of a builtin reading its smallest element.
+func nonEmptyPq(name string, args []any) (*PriorityQueue, error) {
+	pq, err := pqArgument(name, args, 1)
+	if err == nil && len(pq.elements) == 0 {
+		return nil, &RuntimeError{Reason: EmptyCollection.With("%s", name)}
+	}
+	return pq, err
+}
+
+// (pq-push pq x), adds x to pq and returns pq.
+func builtinPqPush(call Caller, args []any) (any, error) {
+	pq, err := pqArgument("pq-push", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return pq, pq.Push(call, args[1])
+}
+
+// (pq-pop pq), removes and returns the smallest element of pq.
+func builtinPqPop(call Caller, args []any) (any, error) {
+	pq, err := nonEmptyPq("pq-pop", args)
+	if err != nil {
+		return nil, err
+	}
+	return pq.Pop(call)
+}
+
+// (pq-peek pq), returns the smallest element of pq.
+func builtinPqPeek(args []any) (any, error) {
+	pq, err := nonEmptyPq("pq-peek", args)
+	if err != nil {
+		return nil, err
+	}
+	return pq.Peek(), nil
+}
+
+// (pq-size pq)
+func builtinPqSize(args []any) (any, error) {
+	pq, err := pqArgument("pq-size", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(pq.elements)), nil
+}
@@ -0,0 +1,102 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestDeque(t *testing.T) {
+	d := &Deque{}
+	// Alternate ends to wrap around the ring buffer while it grows.
+	for i := int64(0); i < 10; i++ {
+		if i%2 == 0 {
+			d.PushBack(i)
+		} else {
+			d.PushFront(i)
+		}
+	}
+
+	expected := []any{int64(9), int64(7), int64(5), int64(3), int64(1),
+		int64(0), int64(2), int64(4), int64(6), int64(8)}
+	if d.Len() != len(expected) {
+		t.Fatalf("expected %d elements, got %d", len(expected), d.Len())
+	}
+	for i, exp := range expected {
+		if got := d.At(i); got != exp {
+			t.Errorf("expected %v at %d, got %v", exp, i, got)
+		}
+	}
+
+	if front, back := d.PopFront(), d.PopBack(); front != int64(9) || back != int64(8) {
+		t.Errorf("expected to pop 9 and 8, got %v and %v", front, back)
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Queue", `
+			(def q (deque))
+			(deque-push-back q 1) (deque-push-back q 2)
+			[(deque-pop-front q) (deque-pop-front q) (deque-size q)]`, "[1 2 0]"},
+		{"Both ends", `
+			(def d (deque 2))
+			(deque-push-front d 1) (deque-push-back d 3)
+			[(deque-peek-front d) (deque-peek-back d) (deque-pop-back d) d]`, "[1 3 3 <deque 2>]"},
+		{"Deque iteration", "(into [] (deque-push-front (deque 2 3) 1))", "[1 2 3]"},
+		{"Priority queue", `
+			(def pq (priority-queue))
+			(pq-push pq 3) (pq-push pq 1) (pq-push pq 2)
+			[(pq-pop pq) (pq-peek pq) (pq-size pq)]`, "[1 2 2]"},
+		{"Comparator", `
+			(def pq (priority-queue (lambda [a b] (lt b a))))
+			(pq-push pq 1) (pq-push pq 3) (pq-push pq 2)
+			[(pq-pop pq) (pq-pop pq) (pq-pop pq)]`, "[3 2 1]"},
+		{"Equal elements in insertion order", `
+			(def pq (priority-queue (lambda [a b] (sub (first a) (first b)))))
+			(pq-push pq [1 "a"]) (pq-push pq [0 "b"]) (pq-push pq [1 "c"]) (pq-push pq [1 "d"])
+			[(pq-pop pq) (pq-pop pq) (pq-pop pq) (pq-pop pq)]`, `[[0 "b"] [1 "a"] [1 "c"] [1 "d"]]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestCollectionBuiltinsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Pop from an empty deque", "(deque-pop-front (deque))", EmptyCollection},
+		{"Peek into an empty priority queue", "(pq-peek (priority-queue))", EmptyCollection},
+		{"Not a deque", "(deque-size [])", WrongType},
+		{"Incomparable elements", `(def pq (priority-queue)) (pq-push pq 1) (pq-push pq "a")`, NotComparable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
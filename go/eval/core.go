@@ -0,0 +1,282 @@
+package eval
+
+import (
+	"io"
+	"maps"
+	"mooss/harp/value"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Core builtins: arithmetic, comparisons, printing and access to collections.
+//
+// Arithmetic follows the numeric tower described in numbers.go.
+// Collections are not modified: put returns an updated copy.
+
+const DivisionByZero RuntimeFailure = "met division by zero"
+
+// Output is where print and println write.
+var Output io.Writer = os.Stdout
+
+func init() {
+	Register(
+		&Builtin{Name: "+", Fun: builtinAdd},
+		&Builtin{Name: "-", Fun: builtinSub},
+		&Builtin{Name: "*", Fun: builtinMul},
+		&Builtin{Name: "/", Fun: builtinDiv},
+		&Builtin{Name: "=", Fun: builtinEqual},
+		&Builtin{Name: "<", Fun: builtinLess},
+		&Builtin{Name: ">", Fun: builtinGreater},
+		&Builtin{Name: "print", Fun: builtinPrint},
+		&Builtin{Name: "println", Fun: builtinPrintln},
+		&Builtin{Name: "len", Fun: builtinLen},
+		&Builtin{Name: "get", Fun: builtinGet},
+		&Builtin{Name: "put", Fun: builtinPut},
+	)
+}
+
+////////////////
+// Arithmetic //
+
+// fold combines numbers from left to right with an operator, starting with init which must be a
+// number.
+func fold(name string, init any, args []any, op operator) (any, error) {
+	if err := numbers(name, args); err != nil {
+		return nil, err
+	}
+
+	res := init
+	for _, arg := range args {
+		res = op.apply(res, arg)
+	}
+	return res, nil
+}
+
+// (+ numbers...), 0 without numbers.
+func builtinAdd(args []any) (any, error) {
+	return fold("+", int64(0), args, addition)
+}
+
+// (* numbers...), 1 without numbers.
+func builtinMul(args []any) (any, error) {
+	return fold("*", int64(1), args, multiplication)
+}
+
+// (- x) negates x, (- x numbers...) subtracts the numbers from x.
+func builtinSub(args []any) (any, error) {
+	if err := arity("-", args, 1, -1); err != nil {
+		return nil, err
+	}
+	if err := numbers("-", args); err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		args = []any{int64(0), args[0]}
+	}
+
+	return fold("-", args[0], args[1:], subtraction)
+}
+
+// (/ x) is the inverse of x, (/ x numbers...) divides x by the numbers in order.
+func builtinDiv(args []any) (any, error) {
+	if err := arity("/", args, 1, -1); err != nil {
+		return nil, err
+	}
+	if err := numbers("/", args); err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		args = []any{int64(1), args[0]}
+	}
+
+	res := args[0]
+	for _, arg := range args[1:] {
+		var err error
+		if res, err = divide(res, arg); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+/////////////////
+// Comparisons //
+
+// (= values...), true when all the values are equal, see value.Equal.
+func builtinEqual(args []any) (any, error) {
+	if err := arity("=", args, 1, -1); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(args); i++ {
+		if !value.Equal(args[i-1], args[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ordered tells whether each value is ordered with the next one according to ok, values being
+// compared naturally like by sort.
+func ordered(name string, args []any, ok func(cmp int) bool) (any, error) {
+	if err := arity(name, args, 1, -1); err != nil {
+		return nil, err
+	}
+	res := true
+	for i := 1; i < len(args); i++ {
+		cmp, err := Compare(args[i-1], args[i])
+		if err != nil {
+			return nil, err
+		}
+		res = res && ok(cmp)
+	}
+	return res, nil
+}
+
+// (< values...), true when the values are strictly increasing.
+func builtinLess(args []any) (any, error) {
+	return ordered("<", args, func(cmp int) bool { return cmp < 0 })
+}
+
+// (> values...), true when the values are strictly decreasing.
+func builtinGreater(args []any) (any, error) {
+	return ordered(">", args, func(cmp int) bool { return cmp > 0 })
+}
+
+//////////////
+// Printing //
+
+// write writes the textual form of values separated by spaces to Output, like str.
+func write(values []any, end string) (any, error) {
+	var builder strings.Builder
+	for i, value := range values {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		display(&builder, value)
+	}
+	builder.WriteString(end)
+
+	_, err := io.WriteString(Output, builder.String())
+	return nil, err
+}
+
+// (print values...), writes the values separated by spaces.
+func builtinPrint(args []any) (any, error) {
+	return write(args, "")
+}
+
+// (println values...), writes the values separated by spaces and a newline.
+func builtinPrintln(args []any) (any, error) {
+	return write(args, "\n")
+}
+
+/////////////////
+// Collections //
+
+// (len coll), the number of elements of an array, map or set, or of runes of a string, 0 for nil.
+func builtinLen(args []any) (any, error) {
+	if err := arity("len", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch coll := args[0].(type) {
+	case nil:
+		return int64(0), nil
+	case string:
+		return int64(utf8.RuneCountInString(coll)), nil
+	case []any:
+		return int64(len(coll)), nil
+	case map[any]any:
+		return int64(len(coll)), nil
+	case map[any]struct{}:
+		return int64(len(coll)), nil
+	}
+	return nil, &RuntimeError{Reason: WrongType.With("len expects a collection, got %s", Repr(args[0]))}
+}
+
+// (get coll key) or (get coll key default), the element of an array at an index, the value of a
+// map at a key, the element of a set equal to key or the field of a struct named by a keyword.
+// The default, nil if not given, is returned when there is no such element.
+func builtinGet(args []any) (any, error) {
+	if err := arity("get", args, 2, 3); err != nil {
+		return nil, err
+	}
+	var def any
+	if len(args) == 3 {
+		def = args[2]
+	}
+
+	switch coll := args[0].(type) {
+	case nil:
+		return def, nil
+	case []any:
+		i, err := argument[int64]("get", args, 1, "an int")
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(coll)) {
+			return def, nil
+		}
+		return coll[i], nil
+	case map[any]any:
+		if !value.Hashable(args[1]) {
+			return def, nil
+		}
+		if res, ok := coll[args[1]]; ok {
+			return res, nil
+		}
+		return def, nil
+	case map[any]struct{}:
+		if !value.Hashable(args[1]) {
+			return def, nil
+		}
+		if _, ok := coll[args[1]]; ok {
+			return args[1], nil
+		}
+		return def, nil
+	case *value.Struct:
+		field, err := argument[Keyword]("get", args, 1, "a keyword")
+		if err != nil {
+			return nil, err
+		}
+		if res, ok := coll.Field(string(field)); ok {
+			return res, nil
+		}
+		return def, nil
+	}
+	return nil, &RuntimeError{Reason: WrongType.With("get expects a collection, got %s", Repr(args[0]))}
+}
+
+// (put coll key value), a copy of an array with the element at an index replaced, or of a map with
+// a key set to value.
+func builtinPut(args []any) (any, error) {
+	if err := arity("put", args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	switch coll := args[0].(type) {
+	case []any:
+		i, err := argument[int64]("put", args, 1, "an int")
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(coll)) {
+			return nil, &RuntimeError{Reason: OutOfBounds.With(
+				"put at %d in an array of length %d", i, len(coll),
+			)}
+		}
+		res := slices.Clone(coll)
+		res[i] = args[2]
+		return res, nil
+	case map[any]any:
+		if err := checkHashable(args[1]); err != nil {
+			return nil, err
+		}
+		res := maps.Clone(coll)
+		res[args[1]] = args[2]
+		return res, nil
+	}
+	return nil, &RuntimeError{Reason: WrongType.With("put expects an array or a map, got %s", Repr(args[0]))}
+}
@@ -0,0 +1,150 @@
+package eval
+
+import (
+	"io"
+	"mooss/harp/ast"
+	"strings"
+	"testing"
+)
+
+func TestCore(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Add", "(+ 1 2 3)", "6"},
+		{"Add nothing", "(+)", "0"},
+		{"Add floats", "(+ 1 2.5)", "3.5"},
+		{"Multiply", "[(* 2 3 4) (*) (* 2 0.5)]", "[24 1 1.0]"},
+		{"Subtract", "[(- 10 1 2) (- 1.5 1)]", "[7 0.5]"},
+		{"Negate", "[(- 3) (- 2.5)]", "[-3 -2.5]"},
+		{"Exact division", "(/ 12 2 3)", "2"},
+		{"Inexact division", "[(/ 1 2) (/ 2) (/ 1.0 4)]", "[1/2 1/2 0.25]"},
+		{"Equal", `[(= 1 1 1) (= 1 2) (= [1 {"a" 2}] [1 {"a" 2}]) (= 1 1.0)]`, "[true false true false]"},
+		{"Less", `[(< 1 2 3) (< 1 3 2) (< "a" "b") (< 1)]`, "[true false true true]"},
+		{"Greater", "[(> 3 2 1) (> 1 1)]", "[true false]"},
+		{"Len", `[(len "λx") (len [1 2]) (len {:a 1}) (len #{}) (len nil)]`, "[2 2 1 0 0]"},
+		{"Get from array", "[(get [1 2] 1) (get [1 2] 2) (get [1 2] (- 1) :none)]", "[2 nil :none]"},
+		{"Get from map", `[(get {:a 1} :a) (get {:a 1} :b 0) (get {} [1])]`, "[1 0 nil]"},
+		{"Get from set", "[(get #{1} 1) (get #{1} 2 :no)]", "[1 :no]"},
+		{"Get from nil", "(get nil :a 1)", "1"},
+		{"Put in array", "(let [a [1 2]] [(put a 0 :x) a])", "[[:x 2] [1 2]]"},
+		{"Put in map", "(let [m {:a 1}] [(put m :b 2) m])", "[{:a 1 :b 2} {:a 1}]"},
+		{"Big literal", "99999999999999999999", "99999999999999999999"},
+		{"Add overflows", "(+ 9223372036854775807 1)", "9223372036854775808"},
+		{"Subtract overflows", "(- (- 9223372036854775807) 2)", "-9223372036854775809"},
+		{"Negate the smallest int", "(- (- (- 9223372036854775807) 1))", "9223372036854775808"},
+		{"Multiply overflows", "(* 4294967296 4294967296 (- 1))", "-18446744073709551616"},
+		{"Big ints are demoted", "(= (- 9223372036854775808 1) 9223372036854775807)", "true"},
+		{"Big and float", "(+ 9223372036854775808 0.5)", "9.223372036854776e+18"},
+		{"Exact big division", "(/ 18446744073709551616 4294967296 2)", "2147483648"},
+		{
+			"Inexact big division", "(/ 18446744073709551617 18446744073709551616)",
+			"18446744073709551617/18446744073709551616",
+		},
+		{"Divide the smallest int", "(/ (- (- 9223372036854775807) 1) (- 1))", "9223372036854775808"},
+		{"Equal big ints", "[(= 18446744073709551616 (* 4294967296 4294967296)) (= 9223372036854775808 1)]",
+			"[true false]"},
+		{"Compare big ints", "[(< 1 9223372036854775808 18446744073709551616) (> 1.5 (- 9223372036854775808))]",
+			"[true true]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestCoreErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Add a string", `(+ 1 "2")`, WrongType},
+		{"Negate nothing", "(-)", WrongArity},
+		{"Subtract a string", `(- "a")`, WrongType},
+		{"Divide by zero", "(/ 1 0)", DivisionByZero},
+		{"Compare nothing", "(<)", WrongArity},
+		{"Compare different types", `(< 1 "a")`, NotComparable},
+		{"Len of an int", "(len 1)", WrongType},
+		{"Get with a string index", `(get [1] "0")`, WrongType},
+		{"Get too few arguments", "(get [1])", WrongArity},
+		{"Put out of bounds", "(put [1] 1 2)", OutOfBounds},
+		{"Put with an unhashable key", "(put {} [1] 2)", UnhashableKey},
+		{"Put in a set", "(put #{} 1 2)", WrongType},
+		{"Divide a big int by zero", "(/ 9223372036854775808 0)", DivisionByZero},
+		{"Big int as a map key", "(put {} 9223372036854775808 1)", UnhashableKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var out strings.Builder
+	defer func(previous io.Writer) { Output = previous }(Output)
+	Output = &out
+
+	got, err := run(t, `(print "a" 1 [:b "c"]) (println) (println "d" nil)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != nil {
+		t.Errorf("expected println to return nil, got %s", Repr(got))
+	}
+	if expected := "a 1 [:b \"c\"]\nd nil\n"; out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	_, err := run(t, "(fun f [x]\n  (+ x 1))\n(f :a)")
+	rerr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a runtime error, got: %v", err)
+	}
+
+	// The error is positioned at the failing call of the builtin, inside the function.
+	if expected := (ast.Pos{Line: 2, Column: 2, Offset: 13}); rerr.Pos != expected {
+		t.Errorf("expected position %+v, got %+v", expected, rerr.Pos)
+	}
+	if !strings.HasPrefix(rerr.Error(), "runtime error at line 2 column 2: ") {
+		t.Errorf("unexpected message: %s", rerr.Error())
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(&Builtin{Name: "test/twice", Fun: func(args []any) (any, error) {
+		return []any{args[0], args[0]}, nil
+	}})
+	defer delete(builtins, "test/twice")
+
+	got, err := run(t, "(test/twice 1)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if Repr(got) != "[1 1]" {
+		t.Errorf("expected [1 1], got %s", Repr(got))
+	}
+}
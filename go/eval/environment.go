@@ -0,0 +1,34 @@
+package eval
+
+import (
+	"mooss/harp/env"
+	"sort"
+)
+
+// Environment maps symbol names to values, see the env package for its scoping rules.
+type Environment = env.Environment
+
+// NewGlobalEnvironment creates a root environment holding the predefined values and giving access
+// to the builtins, which are resolved on first use.
+func NewGlobalEnvironment() *Environment {
+	global := env.New(lookupBuiltin)
+	global.Define("nil", nil)
+	return global
+}
+
+// lookupBuiltin returns the builtin named name, as a predeclared value of global environments.
+func lookupBuiltin(name string) (any, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// Predeclared returns the names defined by every global environment, sorted, so that tools can
+// tell them apart from unbound names.
+func Predeclared() []string {
+	res := []string{"nil"}
+	for name := range builtins {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
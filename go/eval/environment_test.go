@@ -0,0 +1,61 @@
+package eval
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestBuiltinsResolution(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Builtin", "str", "<builtin str>"},
+		{"Shadowed by a definition", "(def str 1) str", "1"},
+		{"Shadowed by an assignment", "(set str 2) str", "2"},
+		{"Shadowed by a parameter", "((lambda [str] str) 3)", "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+
+	// Shadowing in one environment does not leak to others.
+	first, second := NewGlobalEnvironment(), NewGlobalEnvironment()
+	first.Set("str", nil)
+	if value, _ := second.Get("str"); value != builtins["str"] {
+		t.Errorf("expected the str builtin, got %s", Repr(value))
+	}
+}
+
+// globalSink keeps the benchmarked environments on the heap, like a real interpreter would.
+var globalSink *Environment
+
+func BenchmarkNewGlobalEnvironment(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		globalSink = NewGlobalEnvironment()
+	}
+}
+
+func TestPredeclared(t *testing.T) {
+	names := Predeclared()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("expected sorted names, got %v", names)
+	}
+	for _, name := range names {
+		if _, ok := NewGlobalEnvironment().Get(name); !ok {
+			t.Errorf("expected %s to be defined in global environments", name)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package eval
+
+import (
+	"fmt"
+	"mooss/harp/ast"
+	"mooss/harp/value"
+	"slices"
+	"strings"
+)
+
+////////////
+// Errors //
+////////////
+
+type RuntimeError struct {
+	// Reason explains what triggered the error.
+	Reason RuntimeFailure
+	// Pos is the position of the innermost node whose evaluation failed, invalid when the error was
+	// not raised by Eval (e.g. by a direct call to Apply).
+	Pos ast.Pos
+}
+
+func (re RuntimeError) Error() string {
+	if !re.Pos.IsValid() {
+		return fmt.Sprintf("runtime error: %s", re.Reason)
+	}
+	return fmt.Sprintf("runtime error at line %d column %d: %s", re.Pos.Line, re.Pos.Column, re.Reason)
+}
+
+// RuntimeFailure describes what caused the evaluation to fail.
+// It can be followed by additional information specified after `: `.
+// When testing if two runtime failures are of the same kind, use the `Same` method.
+type RuntimeFailure string
+
+func (rf RuntimeFailure) Cause() string {
+	colon := strings.Index(string(rf), ": ")
+	if colon < 0 {
+		return string(rf)
+	}
+
+	return string(rf[:colon])
+}
+
+func (rf RuntimeFailure) Same(other RuntimeFailure) bool {
+	return rf.Cause() == other.Cause()
+}
+
+// With builds a new RuntimeFailure by adding the given detail at the end.
+func (rf RuntimeFailure) With(format string, args ...any) RuntimeFailure {
+	return RuntimeFailure(fmt.Sprintf("%s: %s", rf, fmt.Sprintf(format, args...)))
+}
+
+const (
+	UnboundSymbol       RuntimeFailure = "met unbound symbol"
+	NotCallable         RuntimeFailure = "met call of a value that is not a function"
+	WrongArity          RuntimeFailure = "met call with the wrong number of arguments"
+	UnhashableKey       RuntimeFailure = "met map key that cannot be hashed"
+	BreakOutsideLoop    RuntimeFailure = "met break outside of a loop"
+	ContinueOutsideLoop RuntimeFailure = "met continue outside of a loop"
+	UnsupportedNode     RuntimeFailure = "met node that cannot be evaluated"
+	TooDeep             RuntimeFailure = "met maximum recursion depth"
+)
+
+// MaxDepth is the number of calls that can be in progress in a call chain, past which calls fail
+// with TooDeep instead of overflowing the stack of the Go runtime, which cannot be recovered from.
+// A call chain is made of the calls of an evaluation or of Apply, including the calls made by the
+// builtins, so that concurrent evaluations and the workers of parallel builtins are limited
+// independently of each other.
+var MaxDepth = 10000
+
+// breakSignal is returned as an error by break to unwind the evaluation up to the enclosing loop.
+type breakSignal struct {
+	value any
+}
+
+func (breakSignal) Error() string { return string(BreakOutsideLoop) }
+
+// continueSignal is returned as an error by continue to unwind the evaluation up to the enclosing
+// loop.
+type continueSignal struct{}
+
+func (continueSignal) Error() string { return string(ContinueOutsideLoop) }
+
+// escaped turns the loop signals that escaped their scope (function body or top level) into
+// runtime errors.
+func escaped(err error) error {
+	switch err.(type) {
+	case breakSignal:
+		return &RuntimeError{Reason: BreakOutsideLoop}
+	case continueSignal:
+		return &RuntimeError{Reason: ContinueOutsideLoop}
+	}
+
+	return err
+}
+
+////////////////
+// Evaluation //
+////////////////
+
+// EvalAll evaluates top-level forms in order and returns the value of the last one (nil if there
+// are no forms).
+func EvalAll(forms []ast.Expr, env *Environment) (any, error) {
+	res, err := evalBody(forms, env)
+	return res, escaped(err)
+}
+
+// Eval evaluates a single node in the given environment.
+// Runtime errors are positioned at the innermost node whose evaluation failed, e.g. the call of a
+// builtin rejecting its arguments.
+func Eval(node ast.Expr, env *Environment) (any, error) {
+	res, err := eval(node, env)
+	if rerr, ok := err.(*RuntimeError); ok && !rerr.Pos.IsValid() && node != nil {
+		rerr.Pos = node.Pos()
+	}
+	return res, err
+}
+
+// eval evaluates a node without positioning the errors.
+func eval(node ast.Expr, env *Environment) (any, error) {
+	switch node := node.(type) {
+	case ast.Int64:
+		return node.Value, nil
+	case ast.BigInt:
+		return node.Value, nil
+	case ast.Float64:
+		return node.Value, nil
+	case ast.String:
+		return node.Value, nil
+	case ast.Bool:
+		return node.Value, nil
+	case ast.Byte:
+		return node.Value, nil
+	case ast.Rune:
+		return node.Value, nil
+	case ast.Keyword:
+		return Keyword(node.Name), nil
+	case ast.Symbol:
+		if value, ok := env.Get(node.Name); ok {
+			return value, nil
+		}
+		return nil, &RuntimeError{Reason: UnboundSymbol.With(node.Name)}
+	case ast.Array:
+		return evalArray(node, env)
+	case ast.Map:
+		return evalMap(node, env)
+	case ast.Set:
+		return evalSet(node, env)
+	case ast.Call:
+		return evalCall(node, env)
+	case ast.Def:
+		value, err := Eval(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		env.Define(node.Name.Name, value)
+		return value, nil
+	case ast.Assign:
+		return evalAssign(node, env)
+	case ast.Fun:
+		fun := &Closure{
+			Name: node.Name.Name, Parameters: node.Parameters, Rest: node.Rest, Body: node.Body, Env: env,
+		}
+		env.Define(node.Name.Name, fun)
+		return fun, nil
+	case ast.Lambda:
+		return &Closure{Parameters: node.Parameters, Rest: node.Rest, Body: node.Body, Env: env}, nil
+	case ast.Let:
+		local, err := bind(node.Bindings, env)
+		if err != nil {
+			return nil, err
+		}
+		return evalBody(node.Body, local)
+	case ast.Loop:
+		return evalLoop(node, env)
+	case ast.When:
+		return evalWhen(node, env)
+	case ast.Break:
+		value, err := Eval(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		return nil, breakSignal{value}
+	case ast.Continue:
+		return nil, continueSignal{}
+	case ast.Struct:
+		return evalStruct(node, env)
+	case ast.Access:
+		return evalAccess(node, env)
+	case ast.Meta: // Metadata is only meant for tools.
+		return Eval(node.Form, env)
+	case ast.Quote:
+		return quote(node.Form)
+	case nil: // Absent optional value, e.g. in (break).
+		return nil, nil
+	}
+
+	return nil, &RuntimeError{Reason: UnsupportedNode.With("%T", node)}
+}
+
+// evalBody evaluates forms in order and returns the value of the last one.
+func evalBody(body []ast.Expr, env *Environment) (any, error) {
+	var res any
+	for _, form := range body {
+		var err error
+		if res, err = Eval(form, env); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// bind creates a child environment where the bindings are defined in order, so that each binding
+// can refer to the previous ones.
+func bind(bindings []ast.Binding, env *Environment) (*Environment, error) {
+	local := env.NewChild()
+	for _, binding := range bindings {
+		value, err := Eval(binding.Value, local)
+		if err != nil {
+			return nil, err
+		}
+		local.Define(binding.Variable.Name, value)
+	}
+
+	return local, nil
+}
+
+// quote returns the value of a quoted form: symbols are not looked up but become Symbol values,
+// arrays hold the quoted values of their elements and the other atoms evaluate to themselves.
+// Other forms, like calls and maps, cannot be quoted since there is no value representing code.
+func quote(node ast.Expr) (any, error) {
+	switch node := node.(type) {
+	case ast.Symbol:
+		return Symbol(node.Name), nil
+	case ast.Array:
+		res := make([]any, len(node.Elements))
+		for i, element := range node.Elements {
+			value, err := quote(element)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = value
+		}
+		return res, nil
+	case ast.Int64, ast.BigInt, ast.Float64, ast.String, ast.Bool, ast.Byte, ast.Rune, ast.Keyword:
+		return eval(node, nil)
+	}
+
+	return nil, &RuntimeError{Reason: UnsupportedNode.With("quotation of %T", node)}
+}
+
+func evalArray(node ast.Array, env *Environment) (any, error) {
+	res := make([]any, len(node.Elements))
+	for i, element := range node.Elements {
+		value, err := Eval(element, env)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = value
+	}
+
+	return res, nil
+}
+
+func evalMap(node ast.Map, env *Environment) (any, error) {
+	res := make(map[any]any, len(node.Entries))
+	for _, entry := range node.Entries {
+		key, err := hashable(entry.Key, env)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := Eval(entry.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		res[key] = value
+	}
+
+	return res, nil
+}
+
+func evalSet(node ast.Set, env *Environment) (any, error) {
+	res := make(map[any]struct{}, len(node.Elements))
+	for _, elementNode := range node.Elements {
+		element, err := hashable(elementNode, env)
+		if err != nil {
+			return nil, err
+		}
+		res[element] = struct{}{}
+	}
+
+	return res, nil
+}
+
+// hashable evaluates a node whose value is meant to be used as a map key.
+func hashable(node ast.Expr, env *Environment) (any, error) {
+	value, err := Eval(node, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, checkHashable(value)
+}
+
+// checkHashable returns an error if v cannot be used as a map key.
+func checkHashable(v any) error {
+	if !value.Hashable(v) {
+		return &RuntimeError{Reason: UnhashableKey.With(Repr(v))}
+	}
+
+	return nil
+}
+
+func evalCall(node ast.Call, env *Environment) (any, error) {
+	function, err := Eval(node.Function, env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		if args[i], err = Eval(arg, env); err != nil {
+			return nil, err
+		}
+	}
+
+	return apply(function, args, env.Depth()+1)
+}
+
+// Apply calls a function value with already evaluated arguments, starting a new call chain.
+func Apply(function any, args []any) (any, error) {
+	return apply(function, args, 1)
+}
+
+// apply calls a function value, depth being the number of calls in progress in the call chain
+// including this one.
+func apply(function any, args []any, depth int) (any, error) {
+	if depth > MaxDepth {
+		return nil, &RuntimeError{Reason: TooDeep.With("more than %d calls in progress", MaxDepth)}
+	}
+
+	switch function := function.(type) {
+	case *Closure:
+		switch {
+		case function.Rest == nil && len(args) != len(function.Parameters):
+			return nil, &RuntimeError{Reason: WrongArity.With(
+				"%s expects %d, got %d", Repr(function), len(function.Parameters), len(args),
+			)}
+		case len(args) < len(function.Parameters):
+			return nil, &RuntimeError{Reason: WrongArity.With(
+				"%s expects at least %d, got %d", Repr(function), len(function.Parameters), len(args),
+			)}
+		}
+
+		local := function.Env.NewCall(depth)
+		for i, param := range function.Parameters {
+			local.Define(param.Name, args[i])
+		}
+		if function.Rest != nil {
+			local.Define(function.Rest.Name, slices.Clone(args[len(function.Parameters):]))
+		}
+
+		res, err := evalBody(function.Body, local)
+		return res, escaped(err) // Loops cannot be broken from inside a function.
+	case *Builtin:
+		if function.Calls != nil {
+			return function.Calls(func(f any, args []any) (any, error) { return apply(f, args, depth+1) }, args)
+		}
+		return function.Fun(args)
+	case *value.StructType:
+		return construct(function, args, depth)
+	}
+
+	return nil, &RuntimeError{Reason: NotCallable.With(Repr(function))}
+}
+
+// evalAssign sets a variable, or a field of a struct.
+func evalAssign(node ast.Assign, env *Environment) (any, error) {
+	value, err := Eval(node.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch target := node.Target.(type) {
+	case ast.Symbol:
+		if !env.Set(target.Name, value) {
+			return nil, &RuntimeError{Reason: UnboundSymbol.With(target.Name)}
+		}
+		return value, nil
+	case ast.Access:
+		return assignField(target, value, env)
+	}
+	return nil, &RuntimeError{Reason: UnsupportedNode.With("assignment of %T", node.Target)}
+}
+
+// evalLoop evaluates the body of the loop while its condition is truthy.
+// The value of a loop is the value given to the break that ended it, nil otherwise.
+func evalLoop(node ast.Loop, env *Environment) (any, error) {
+	local, err := bind(node.Bindings, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		condition, err := Eval(node.Condition, local)
+		if err != nil {
+			return nil, err
+		}
+		if !Truthy(condition) {
+			return nil, nil
+		}
+
+		_, err = evalBody(node.Body, local)
+		switch err := err.(type) {
+		case nil, continueSignal:
+		case breakSignal:
+			return err.value, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// evalWhen evaluates the body of the first clause whose condition is truthy, or the else clause if
+// there is none.
+// A clause without body evaluates to the value of its condition.
+func evalWhen(node ast.When, env *Environment) (any, error) {
+	for _, clause := range node.Clauses {
+		condition, err := Eval(clause.Condition, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if Truthy(condition) {
+			if len(clause.Body) == 0 {
+				return condition, nil
+			}
+			return evalBody(clause.Body, env)
+		}
+	}
+
+	return evalBody(node.Else, env)
+}
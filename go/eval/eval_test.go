@@ -0,0 +1,231 @@
+package eval
+
+import (
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"testing"
+)
+
+// testEnvironment returns a global environment with the few builtins needed to write tests.
+func testEnvironment() *Environment {
+	env := NewGlobalEnvironment()
+	env.Define("add", &Builtin{Name: "add", Fun: func(args []any) (any, error) {
+		return args[0].(int64) + args[1].(int64), nil
+	}})
+	env.Define("sub", &Builtin{Name: "sub", Fun: func(args []any) (any, error) {
+		return args[0].(int64) - args[1].(int64), nil
+	}})
+	env.Define("lt", &Builtin{Name: "lt", Fun: func(args []any) (any, error) {
+		return args[0].(int64) < args[1].(int64), nil
+	}})
+	env.Define("first", &Builtin{Name: "first", Fun: func(args []any) (any, error) {
+		return args[0].([]any)[0], nil
+	}})
+	return env
+}
+
+// run parses and evaluates the input.
+func run(t *testing.T, input string) (any, error) {
+	t.Helper()
+
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+
+	return EvalAll(forms, testEnvironment())
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"No forms", "", "nil"},
+		{"Atoms", `1 2.5 "a\nb"`, `"a\nb"`},
+		{"Whole float", "2.0", "2.0"},
+		{"Nil and booleans", "[nil true false]", "[nil true false]"},
+		{"Collections", `[1 [2] {"b" 2}]`, `[1 [2] {"b" 2}]`},
+		{"Keywords", "(def m {:a 1}) [:a m]", "[:a {:a 1}]"},
+		{"Characters", `[\a \newline \u03bb]`, `[\a \newline \λ]`},
+		{"Map with evaluated keys", `(def a "k") {a 1 "b" [2]}`, `{"b" [2] "k" 1}`},
+		{"Quoted symbol", "'x", "x"},
+		{"Quoted array", `'[a 1 "b" :c [d]]`, `[a 1 "b" :c [d]]`},
+		{"Quoted symbols are values", "(def s 'x) {s 1}", "{x 1}"},
+		{"Def", "(def x 1) (add x x)", "2"},
+		{"Set", "(def x 1) (set x 2) x", "2"},
+		{"Let", "(let [x 1 y (add x 1)] (add x y))", "3"},
+		{"Let shadows", "(def x 1) (let [x 2] x)", "2"},
+		{"Let does not leak", "(def x 1) (let [x 2] x) x", "1"},
+		{"Fun", "(fun inc [x] (add x 1)) (inc 41)", "42"},
+		{"Fun value", "(fun inc [x] (add x 1))", "<fun inc>"},
+		{"Lambda", "((lambda [a b] b) 1 2)", "2"},
+		{"Lambda value", "(lambda [] 1)", "<lambda>"},
+		{"Empty body", "((lambda []))", "nil"},
+		{"Rest parameter", "((lambda [a & more] [a more]) 1 2 3)", "[1 [2 3]]"},
+		{"Metadata", `(fun ^{:doc "Increment."} inc [x] (add x 1)) ^:checked (inc 1)`, "2"},
+		{"Empty rest parameter", "((lambda [a & more] more) 1)", "[]"},
+		{"Recursion", `
+			(fun sum [from to acc]
+				(when [(lt to from) acc]
+				      [else (sum (add from 1) to (add acc from))]))
+			(sum 1 10 0)`, "55"},
+		{"Closure captures its environment", `
+			(fun adder [n] (lambda [x] (add x n)))
+			(def add2 (adder 2))
+			(add2 40)`, "42"},
+		{"Closure sees later mutations", `
+			(def n 1)
+			(fun get-n [] n)
+			(set n 2)
+			(get-n)`, "2"},
+		{"Closure keeps its own state", `
+			(fun counter []
+				(let [count 0]
+					(lambda [] (set count (add count 1)))))
+			(def c1 (counter))
+			(def c2 (counter))
+			(c1) (c1) (c2)
+			[(c1) (c2)]`, "[3 2]"},
+		{"Closure sees later definitions", "(fun f [] (g)) (fun g [] 1) (f)", "1"},
+		{"Closures share the scope they capture", `
+			(let [n 0
+			      inc (lambda [] (set n (add n 1)))
+			      get (lambda [] n)]
+				(inc) (inc) (get))`, "2"},
+		{"Parameters shadow globals", "(def x 1) (fun f [x] x) [(f 2) x]", "[2 1]"},
+		{"When", "(when [false 1] [nil 2] [true 3] [else 4])", "3"},
+		{"When else", "(when [false 1] [else 4])", "4"},
+		{"When without match", "(when [false 1])", "nil"},
+		{"When clause without body", "(when [false] [7])", "7"},
+		{"Loop", `
+			(def total 0)
+			(loop [i 0] (lt i 5)
+				(set total (add total i))
+				(set i (add i 1)))
+			total`, "10"},
+		{"Loop value", "(loop [i 0] (lt i 5) (set i (add i 1)))", "nil"},
+		{"Break", "(loop [i 0] true (when [(lt 3 i) (break i)]) (set i (add i 1)))", "4"},
+		{"Break without value", "(loop [] true (break))", "nil"},
+		{"Continue", `
+			(def odd 0)
+			(loop [i 0] (lt i 5)
+				(set i (add i 1))
+				(when [(lt i 3) (continue)])
+				(set odd (add odd 1)))
+			odd`, "3"},
+		{"Nested loops", `
+			(loop [i 0] true
+				(loop [] true (break))
+				(break i))`, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Unbound symbol", "x", UnboundSymbol},
+		{"Set unbound symbol", "(set x 1)", UnboundSymbol},
+		{"Let scope", "(let [x 1] x) x", UnboundSymbol},
+		{"Parameter scope", "(fun f [x] x) (f 1) x", UnboundSymbol},
+		{"Definition in a function", "(fun f [] (def y 1)) (f) y", UnboundSymbol},
+		{"Not callable", "(1 2)", NotCallable},
+		{"Too many arguments", "((lambda [x] x) 1 2)", WrongArity},
+		{"Too few arguments", "((lambda [x] x))", WrongArity},
+		{"Too few arguments before rest", "((lambda [x & r] x))", WrongArity},
+		{"Unhashable key", "(def k [1]) {k 1}", UnhashableKey},
+		{"Break outside loop", "(break 1)", BreakOutsideLoop},
+		{"Continue outside loop", "(continue)", ContinueOutsideLoop},
+		{"Break inside function", "(loop [] true ((lambda [] (break))))", BreakOutsideLoop},
+		{"Unsupported node", "(tie f 1)", UnsupportedNode},
+		{"Quoted call", "'(f x)", UnsupportedNode},
+		{"Quoted call in an array", "'[a (f x)]", UnsupportedNode},
+		{"Unbounded recursion", "(fun f [] (f)) (f)", TooDeep},
+		{"Unbounded mutual recursion", "(fun f [] (g)) (fun g [] (f)) (f)", TooDeep},
+		{"Recursive struct default", "(struct A [a (A)]) (A)", TooDeep},
+		{"Recursion through a builtin", "(fun f [x] (into [] (mapping f) [x])) (f 1)", TooDeep},
+		{"Recursion through a returned builtin", "(fun f [x] ((comp f) x)) (f 1)", TooDeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	defer func(max int) { MaxDepth = max }(MaxDepth)
+	MaxDepth = 100
+
+	input := "(fun down [n] (when [(lt n 1) n] [else (down (sub n 1))]))"
+	if _, err := run(t, input+"(down 100)"); err == nil {
+		t.Fatal("expected recursion past the limit to fail")
+	}
+	got, err := run(t, input+"(down 90)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if Repr(got) != "0" {
+		t.Errorf("expected 0, got %s", Repr(got))
+	}
+}
+
+func TestMaxDepthPerCallChain(t *testing.T) {
+	defer func(max int) { MaxDepth = max }(MaxDepth)
+	MaxDepth = 100
+
+	// Each worker recurses close to the limit, which they would exceed together.
+	input := `(fun down [n] (when [(lt n 1) n] [else (down (sub n 1))]))
+		(pmap down [95 95 95 95 95 95 95 95] 8)`
+	got, err := run(t, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "[0 0 0 0 0 0 0 0]"; Repr(got) != expected {
+		t.Errorf("expected %s, got %s", expected, Repr(got))
+	}
+
+	// So are concurrent evaluations.
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	errs := make(chan error, 8)
+	for range 8 {
+		go func() {
+			_, err := EvalAll(forms, testEnvironment())
+			errs <- err
+		}()
+	}
+	for range 8 {
+		if err := <-errs; err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+}
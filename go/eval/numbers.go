@@ -0,0 +1,214 @@
+package eval
+
+import (
+	"cmp"
+	"math"
+	"math/big"
+	"mooss/harp/value"
+)
+
+// Numbers form a tower of three levels: ints (int64, or *big.Int outside its range), rationals
+// (*big.Rat) and floats (float64). An arithmetic operation on two numbers is done at the higher
+// level of both, so that the promotion matrix is:
+//
+//	          | int      | rational | float
+//	----------+----------+----------+------
+//	int       | int      | rational | float
+//	rational  | rational | rational | float
+//	float     | float    | float    | float
+//
+// Ints and rationals are exact: ints overflowing int64 are promoted to big ints, and dividing ints
+// gives a rational when the division is not exact, e.g. (/ 1 3). Exact results are then demoted to
+// the lowest representation holding them, a rational with a denominator of 1 being an int and a big
+// int in the range of int64 being an int64, so that each exact number has a single representation.
+// Floats are inexact and contagious, (float x) converts a number to a float explicitly.
+// Numbers of different levels compare by value, except that ints and rationals are converted to the
+// closest float when compared with a float.
+
+func init() {
+	Register(&Builtin{Name: "float", Fun: builtinFloat})
+}
+
+// Levels of the numeric tower.
+const (
+	intLevel = iota
+	ratLevel
+	floatLevel
+)
+
+// level returns the level of a number in the tower.
+func level(number any) int {
+	switch number.(type) {
+	case *big.Rat:
+		return ratLevel
+	case float64:
+		return floatLevel
+	}
+	return intLevel
+}
+
+// numbers checks that all the arguments of a builtin are numbers.
+func numbers(name string, args []any) error {
+	for i, arg := range args {
+		switch arg.(type) {
+		case int64, *big.Int, *big.Rat, float64:
+		default:
+			return &RuntimeError{Reason: WrongType.With(
+				"argument %d of %s must be a number, got %s", i+1, name, Repr(arg),
+			)}
+		}
+	}
+	return nil
+}
+
+/////////////////
+// Conversions //
+
+// toFloat converts a number to the closest float.
+func toFloat(number any) float64 {
+	switch n := number.(type) {
+	case int64:
+		return float64(n)
+	case *big.Int:
+		res, _ := new(big.Float).SetInt(n).Float64()
+		return res
+	case *big.Rat:
+		res, _ := n.Float64()
+		return res
+	}
+	return number.(float64)
+}
+
+// toRat converts an int or a rational to a rational.
+func toRat(number any) *big.Rat {
+	switch n := number.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(n)
+	case *big.Int:
+		return new(big.Rat).SetInt(n)
+	}
+	return number.(*big.Rat)
+}
+
+// toBig converts an int to a big int.
+func toBig(number any) *big.Int {
+	if n, ok := number.(int64); ok {
+		return big.NewInt(n)
+	}
+	return number.(*big.Int)
+}
+
+// (float x), x converted to the closest float.
+func builtinFloat(args []any) (any, error) {
+	if err := arity("float", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if err := numbers("float", args); err != nil {
+		return nil, err
+	}
+	return toFloat(args[0]), nil
+}
+
+////////////////
+// Operations //
+
+// operator is an arithmetic operation, implemented for each representation of numbers.
+type operator struct {
+	// ints returns false when the result overflows, so that the operation is done on big ints.
+	ints   func(a, b int64) (int64, bool)
+	bigs   func(res, a, b *big.Int) *big.Int
+	rats   func(res, a, b *big.Rat) *big.Rat
+	floats func(a, b float64) float64
+}
+
+// apply applies the operator to two numbers, at the higher level of both.
+func (op operator) apply(a, b any) any {
+	switch max(level(a), level(b)) {
+	case floatLevel:
+		return op.floats(toFloat(a), toFloat(b))
+	case ratLevel:
+		return value.Rat(op.rats(new(big.Rat), toRat(a), toRat(b)))
+	}
+
+	x, xIsInt := a.(int64)
+	y, yIsInt := b.(int64)
+	if xIsInt && yIsInt {
+		if res, ok := op.ints(x, y); ok {
+			return res
+		}
+	}
+	return value.Int(op.bigs(new(big.Int), toBig(a), toBig(b)))
+}
+
+var (
+	addition = operator{
+		ints: func(a, b int64) (int64, bool) {
+			res := a + b
+			return res, (res > a) == (b > 0)
+		},
+		bigs:   (*big.Int).Add,
+		rats:   (*big.Rat).Add,
+		floats: func(a, b float64) float64 { return a + b },
+	}
+	subtraction = operator{
+		ints: func(a, b int64) (int64, bool) {
+			res := a - b
+			return res, (res < a) == (b > 0)
+		},
+		bigs:   (*big.Int).Sub,
+		rats:   (*big.Rat).Sub,
+		floats: func(a, b float64) float64 { return a - b },
+	}
+	multiplication = operator{
+		ints: func(a, b int64) (int64, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			res := a * b
+			return res, res/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+		},
+		bigs:   (*big.Int).Mul,
+		rats:   (*big.Rat).Mul,
+		floats: func(a, b float64) float64 { return a * b },
+	}
+)
+
+// divide divides two numbers. Dividing exact numbers by zero fails, whereas dividing floats by zero
+// gives an infinity or NaN.
+func divide(a, b any) (any, error) {
+	switch {
+	case max(level(a), level(b)) == floatLevel:
+		return toFloat(a) / toFloat(b), nil
+	case b == int64(0): // Other exact numbers are never 0.
+		return nil, &RuntimeError{Reason: DivisionByZero.With("%s / 0", Repr(a))}
+	}
+
+	x, xIsInt := a.(int64)
+	y, yIsInt := b.(int64)
+	if xIsInt && yIsInt && x%y == 0 && !(x == math.MinInt64 && y == -1) {
+		return x / y, nil
+	}
+	return value.Rat(new(big.Rat).Quo(toRat(a), toRat(b))), nil
+}
+
+// compareNumbers compares a number with b, and returns false when b is not a number.
+func compareNumbers(a, b any) (int, bool) {
+	switch b.(type) {
+	case int64, *big.Int, *big.Rat, float64:
+	default:
+		return 0, false
+	}
+
+	switch max(level(a), level(b)) {
+	case floatLevel:
+		return cmp.Compare(toFloat(a), toFloat(b)), true
+	case ratLevel:
+		return toRat(a).Cmp(toRat(b)), true
+	}
+	x, xIsInt := a.(int64)
+	y, yIsInt := b.(int64)
+	if xIsInt && yIsInt {
+		return cmp.Compare(x, y), true
+	}
+	return toBig(a).Cmp(toBig(b)), true
+}
@@ -0,0 +1,110 @@
+package eval
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPromotion(t *testing.T) {
+	// One number per level of the tower, and the level of the result of each operator for each pair
+	// of levels.
+	third, big63 := big.NewRat(1, 3), new(big.Int).Lsh(big.NewInt(1), 63)
+	levels := []struct {
+		name   string
+		number any
+	}{
+		{"int", int64(2)},
+		{"big int", big63},
+		{"rational", third},
+		{"float", 0.5},
+	}
+	expected := [][]string{
+		{"int", "big int", "rational", "float"},
+		{"big int", "big int", "rational", "float"},
+		{"rational", "rational", "rational", "float"},
+		{"float", "float", "float", "float"},
+	}
+	kind := func(v any) string {
+		switch v.(type) {
+		case int64:
+			return "int"
+		case *big.Int:
+			return "big int"
+		case *big.Rat:
+			return "rational"
+		case float64:
+			return "float"
+		}
+		return Repr(v)
+	}
+
+	for i, a := range levels {
+		for j, b := range levels {
+			for _, name := range []string{"+", "*"} {
+				if got := kind(call(t, name, a.number, b.number)); got != expected[i][j] {
+					t.Errorf("(%s %s %s): expected a %s, got a %s", name, a.name, b.name, expected[i][j], got)
+				}
+			}
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Exact division", "[(/ 1 3) (/ 6 4) (/ (- 2) 4) (/ 6 3)]", "[1/3 3/2 -1/2 2]"},
+		{"Rationals are demoted", "(+ (/ 1 3) (/ 2 3))", "1"},
+		{"Rational arithmetic", "[(* (/ 2 3) 3) (- (/ 1 2) (/ 1 3)) (/ (/ 1 2) (/ 1 4))]", "[2 1/6 2]"},
+		{"Rational and big int", "(+ (/ 1 2) 9223372036854775808)", "18446744073709551617/2"},
+		{"Rational and float", "(+ (/ 1 2) 0.25)", "0.75"},
+		{"Float division by zero", "(/ 1.0 0)", "+Inf"},
+		{"Float", "[(float 1) (float (/ 1 4)) (float 9223372036854775808) (float 2.5)]",
+			"[1.0 0.25 9.223372036854776e+18 2.5]"},
+		{"Equal rationals", "[(= (/ 1 2) (/ 2 4)) (= (/ 1 2) 0.5) (= (/ 4 2) 2)]", "[true false true]"},
+		{"Compare rationals", "[(< (/ 1 3) (/ 1 2) 1) (> (/ 1 3) 0.3) (< 9223372036854775808 (/ 1 2))]",
+			"[true true false]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestNumbersErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Divide a rational by zero", "(/ (/ 1 2) 0)", DivisionByZero},
+		{"Float of a string", `(float "1")`, WrongType},
+		{"Float without argument", "(float)", WrongArity},
+		{"Rational as a map key", "(put {} (/ 1 2) 1)", UnhashableKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
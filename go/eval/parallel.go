@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallel builtins call functions from several goroutines at once.
+// Environments are not synchronized, so this is only safe for functions that do not define or
+// assign variables visible to other calls, which is the case of pure functions: their parameters
+// and local bindings live in an environment of their own and reading shared variables is safe.
+
+func init() {
+	Register(
+		&Builtin{Name: "pmap", Calls: builtinPmap},
+	)
+}
+
+// (pmap f coll) or (pmap f coll workers), like (into [] (mapping f) coll) but with the calls
+// spread over a bounded number of goroutines, GOMAXPROCS by default.
+// The results are in the order of the elements.
+//
+// When calls fail, no new call is started and the error of the first failing element is returned,
+// which is the error that a sequential map would have returned.
+// Each call is limited by MaxDepth on its own, like a sequential call would.
+func builtinPmap(call Caller, args []any) (any, error) {
+	if err := arity("pmap", args, 2, 3); err != nil {
+		return nil, err
+	}
+	f := args[0]
+	arr, err := argument[[]any]("pmap", args, 1, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	workers := int64(runtime.GOMAXPROCS(0))
+	if len(args) == 3 {
+		if workers, err = argument[int64]("pmap", args, 2, "an int"); err != nil {
+			return nil, err
+		}
+		if workers < 1 {
+			return nil, &RuntimeError{Reason: InvalidValue.With("pmap needs at least one worker, got %d", workers)}
+		}
+	}
+
+	res := make([]any, len(arr))
+	errs := make([]error, len(arr))
+	// Elements are claimed in increasing order, so every element before a failing one is evaluated.
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range min(workers, int64(len(arr))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := next.Add(1) - 1
+				if i >= int64(len(arr)) {
+					return
+				}
+				if res[i], errs[i] = call(f, []any{arr[i]}); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestPmap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Results in order", "(pmap (lambda [x] (add x 1)) [1 2 3 4 5 6 7 8 9])", "[2 3 4 5 6 7 8 9 10]"},
+		{"Single worker", "(pmap (lambda [x] [x]) [1 2] 1)", "[[1] [2]]"},
+		{"More workers than elements", "(pmap (lambda [x] x) [1] 8)", "[1]"},
+		{"Empty array", "(pmap (lambda [x] x) [])", "[]"},
+		{"Closure over a shared variable", "(def n 10) (pmap (lambda [x] (add x n)) [1 2])", "[11 12]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestPmapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Failing call", "(pmap (lambda [x] (x)) [1 2])", NotCallable},
+		{"No worker", "(pmap (lambda [x] x) [1] 0)", InvalidValue},
+		{"Not an array", "(pmap (lambda [x] x) 1)", WrongType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func TestPmapFirstError(t *testing.T) {
+	arr := make([]any, 100)
+	for i := range arr {
+		arr[i] = int64(i)
+	}
+
+	var calls atomic.Int64
+	f := &Builtin{Name: "f", Fun: func(args []any) (any, error) {
+		calls.Add(1)
+		if args[0].(int64) >= 10 {
+			return nil, &RuntimeError{Reason: InvalidValue.With("%d", args[0])}
+		}
+		return args[0], nil
+	}}
+
+	_, err := builtinPmap(Apply, []any{f, arr, int64(4)})
+	if rerr, ok := err.(*RuntimeError); !ok || rerr.Reason != InvalidValue.With("10") {
+		t.Errorf("expected the error of element 10, got: %v", err)
+	}
+	if calls.Load() == int64(len(arr)) {
+		t.Errorf("expected the remaining calls to be cancelled")
+	}
+}
@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Rate limiters and retries help scripts calling external services that throttle their clients
+// or fail intermittently.
+
+// sleep pauses the current goroutine, it is replaced in tests.
+var sleep = time.Sleep
+
+//////////////////
+// Rate limiter //
+
+// RateLimiter allows at most n calls per period, calls beyond it wait for their turn.
+// It keeps the times of the last n calls (sliding window): a call waits until the oldest of them is
+// a period old, so that no period ever holds more than n calls.
+type RateLimiter struct {
+	n      int
+	period time.Duration
+	// calls are the times of the last n calls, oldest first, including the calls still waiting.
+	calls []time.Time
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+// NewRateLimiter returns a limiter allowing n calls per period, n must be positive.
+func NewRateLimiter(n int, period time.Duration) *RateLimiter {
+	return &RateLimiter{n: n, period: period, now: time.Now}
+}
+
+func (rl *RateLimiter) String() string {
+	return fmt.Sprintf("<rate-limit %d/%s>", rl.n, rl.period)
+}
+
+// Reserve takes the next call slot and returns how long the caller must wait before using it.
+func (rl *RateLimiter) Reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	at := now
+	if len(rl.calls) == rl.n {
+		if free := rl.calls[0].Add(rl.period); free.After(now) {
+			at = free
+		}
+		rl.calls = rl.calls[1:]
+	}
+	rl.calls = append(rl.calls, at)
+	return at.Sub(now)
+}
+
+// Wait blocks until the caller is allowed to make a call.
+func (rl *RateLimiter) Wait() {
+	if delay := rl.Reserve(); delay > 0 {
+		sleep(delay)
+	}
+}
+
+///////////
+// Retry //
+
+// Retry calls fn until it succeeds or has been called times times, returning the last error.
+// The pause between two calls starts at backoff and doubles after each failure.
+// times must be positive, so that fn is called at least once.
+func Retry(fn any, times int64, backoff time.Duration) (any, error) {
+	return retry(Apply, fn, times, backoff)
+}
+
+// retry implements Retry, calling fn with call.
+func retry(call Caller, fn any, times int64, backoff time.Duration) (any, error) {
+	var err error
+	for attempt := int64(0); attempt < times; attempt++ {
+		if attempt > 0 {
+			sleep(backoff)
+			backoff *= 2
+		}
+
+		var res any
+		if res, err = call(fn, nil); err == nil {
+			return res, nil
+		}
+	}
+	return nil, err
+}
+
+//////////////
+// Builtins //
+
+func init() {
+	Register(
+		&Builtin{Name: "rate-limit", Fun: builtinRateLimit},
+		&Builtin{Name: "rate-wait", Fun: builtinRateWait},
+		&Builtin{Name: "throttle", Fun: builtinThrottle},
+		&Builtin{Name: "retry", Calls: builtinRetry},
+	)
+}
+
+// (rate-limit n per), a limiter allowing n calls per duration, e.g. (rate-limit 10 "1s").
+func builtinRateLimit(args []any) (any, error) {
+	if err := arity("rate-limit", args, 2, 2); err != nil {
+		return nil, err
+	}
+	n, err := argument[int64]("rate-limit", args, 0, "an int")
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, &RuntimeError{Reason: InvalidValue.With("rate-limit needs at least one call, got %d", n)}
+	}
+	per, ok := duration(args[1])
+	if !ok || per == 0 {
+		return nil, &RuntimeError{Reason: InvalidValue.With(
+			"rate-limit period must be a duration like \"1s\", got %s", Repr(args[1]),
+		)}
+	}
+
+	return NewRateLimiter(int(n), per), nil
+}
+
+// (rate-wait rl), blocks until the limiter allows a call.
+func builtinRateWait(args []any) (any, error) {
+	if err := arity("rate-wait", args, 1, 1); err != nil {
+		return nil, err
+	}
+	rl, err := argument[*RateLimiter]("rate-wait", args, 0, "a rate limiter")
+	if err != nil {
+		return nil, err
+	}
+
+	rl.Wait()
+	return nil, nil
+}
+
+// (throttle rl f), a function calling f with its arguments once the limiter allows it.
+func builtinThrottle(args []any) (any, error) {
+	if err := arity("throttle", args, 2, 2); err != nil {
+		return nil, err
+	}
+	rl, err := argument[*RateLimiter]("throttle", args, 0, "a rate limiter")
+	if err != nil {
+		return nil, err
+	}
+	f := args[1]
+
+	return &Builtin{Name: "throttled", Calls: func(call Caller, args []any) (any, error) {
+		rl.Wait()
+		return call(f, args)
+	}}, nil
+}
+
+// (retry fn) or (retry {:times 5 :backoff "1s"} fn), calls fn without arguments until it succeeds.
+// fn is called at most :times times (3 by default, at least 1), the pause between two calls starts
+// at :backoff (no pause by default) and doubles after each failure.
+func builtinRetry(call Caller, args []any) (any, error) {
+	if err := arity("retry", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	opts := map[any]any{}
+	if len(args) == 2 {
+		var err error
+		if opts, err = argument[map[any]any]("retry", args, 0, "a map of options"); err != nil {
+			return nil, err
+		}
+	}
+
+	times, err := intOption("retry", opts, "times", 3)
+	if err != nil {
+		return nil, err
+	}
+	if times < 1 {
+		return nil, &RuntimeError{Reason: InvalidValue.With("retry needs at least one call, got %d", times)}
+	}
+	backoff, err := durationOption("retry", opts, "backoff", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return retry(call, args[len(args)-1], times, backoff)
+}
@@ -0,0 +1,140 @@
+package eval
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	rl := NewRateLimiter(2, time.Second)
+	rl.now = func() time.Time { return now }
+
+	// The first two calls are immediate, the next ones wait for the calls a period before them.
+	expected := []time.Duration{0, 0, time.Second, time.Second, 2 * time.Second}
+	for i, exp := range expected {
+		if got := rl.Reserve(); got != exp {
+			t.Errorf("call %d: expected to wait %s, got %s", i, exp, got)
+		}
+	}
+
+	// After a quiet time, the calls are immediate again.
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if got := rl.Reserve(); got != 0 {
+			t.Errorf("call %d after a pause: expected no wait, got %s", i, got)
+		}
+	}
+}
+
+func TestRateLimiterWindows(t *testing.T) {
+	// Calls arrive at irregular times, no period may hold more than n of them once they waited.
+	n, period := 3, time.Second
+	now := time.Unix(0, 0)
+	rl := NewRateLimiter(n, period)
+	rl.now = func() time.Time { return now }
+
+	calls := []time.Time{}
+	for i := 0; i < 50; i++ {
+		now = now.Add(time.Duration(i%7) * 90 * time.Millisecond)
+		calls = append(calls, now.Add(rl.Reserve()))
+	}
+
+	for i := n; i < len(calls); i++ {
+		if calls[i].Sub(calls[i-n]) < period {
+			t.Fatalf("calls %d to %d happen within %s, less than %s", i-n, i, calls[i].Sub(calls[i-n]), period)
+		}
+	}
+}
+
+// withoutSleep records pauses instead of sleeping for the duration of a test.
+func withoutSleep(t *testing.T) *[]time.Duration {
+	pauses := &[]time.Duration{}
+	sleep = func(d time.Duration) { *pauses = append(*pauses, d) }
+	t.Cleanup(func() { sleep = time.Sleep })
+	return pauses
+}
+
+func TestRetry(t *testing.T) {
+	pauses := withoutSleep(t)
+
+	got, err := run(t, `
+		(def attempts 0)
+		(retry {:times 5 :backoff "1s"}
+		       (lambda [] (set attempts (add attempts 1))
+		                  (when [(lt attempts 3) (undefined)])
+		                  attempts))`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if Repr(got) != "3" {
+		t.Errorf("expected to succeed at the third attempt, got %s", Repr(got))
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second}
+	if len(*pauses) != len(expected) || (*pauses)[0] != expected[0] || (*pauses)[1] != expected[1] {
+		t.Errorf("expected pauses %v, got %v", expected, *pauses)
+	}
+}
+
+func TestRetrySingleAttempt(t *testing.T) {
+	pauses := withoutSleep(t)
+
+	got, err := run(t, `
+		(def attempts 0)
+		(retry {:times 1 :backoff "1s"} (lambda [] (set attempts (add attempts 1))))`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if Repr(got) != "1" || len(*pauses) != 0 {
+		t.Errorf("expected a single attempt without pause, got %s after %v", Repr(got), *pauses)
+	}
+}
+
+func TestRetryBuiltinsErrors(t *testing.T) {
+	withoutSleep(t)
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Last error", "(retry {:times 2} (lambda [] (undefined)))", UnboundSymbol},
+		{"Invalid times", `(retry {:times "a"} (lambda [] 1))`, InvalidValue},
+		{"No attempt", `(retry {:times 0} (lambda [] 1))`, InvalidValue},
+		{"Negative times", `(retry {:times (sub 0 1)} (lambda [] 1))`, InvalidValue},
+		{"Invalid backoff", `(retry {"backoff" 1} (lambda [] 1))`, InvalidValue},
+		{"No call allowed", `(rate-limit 0 "1s")`, InvalidValue},
+		{"Invalid period", `(rate-limit 1 "soon")`, InvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	pauses := withoutSleep(t)
+
+	got, err := run(t, `
+		(def f (throttle (rate-limit 1 "1h") (lambda [x] (add x 1))))
+		[(f 1) (f 2) (rate-limit 1 "1h")]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "[2 3 <rate-limit 1/1h0m0s>]"; Repr(got) != expected {
+		t.Errorf("expected:\n> %s\ngot:\n> %s", expected, Repr(got))
+	}
+	if len(*pauses) != 1 {
+		t.Errorf("expected the second call to wait, got pauses %v", *pauses)
+	}
+}
@@ -0,0 +1,156 @@
+package eval
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Subsequences (subs, slice, take and drop) never copy: they are views sharing the memory of the
+// value they come from, so they are constant time (linear in the number of runes skipped for
+// strings) but keep the whole original value alive.
+// When a small part of a large value must outlive it, copy it explicitly with (copy x).
+//
+// Indices start at 0 and ends are exclusive, strings are indexed by rune.
+
+const OutOfBounds RuntimeFailure = "met index out of bounds"
+
+func init() {
+	Register(
+		&Builtin{Name: "subs", Fun: builtinSubs},
+		&Builtin{Name: "slice", Fun: builtinSlice},
+		&Builtin{Name: "take", Fun: builtinTake},
+		&Builtin{Name: "drop", Fun: builtinDrop},
+		&Builtin{Name: "copy", Fun: builtinCopy},
+	)
+}
+
+// bounds extracts the start and optional end arguments of a subsequence builtin and checks them
+// against the length of the sequence.
+func bounds(name string, args []any, length int) (int, int, error) {
+	start, err := argument[int64](name, args, 1, "an int")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := int64(length)
+	if len(args) == 3 {
+		if end, err = argument[int64](name, args, 2, "an int"); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if start < 0 || end < start || end > int64(length) {
+		return 0, 0, &RuntimeError{Reason: OutOfBounds.With(
+			"%s of [%d, %d) in a sequence of length %d", name, start, end, length,
+		)}
+	}
+	return int(start), int(end), nil
+}
+
+// runeOffset returns the byte offset of the n-th rune of str (len(str) if n is the rune count).
+func runeOffset(str string, n int) int {
+	offset := 0
+	for ; n > 0; n-- {
+		_, width := utf8.DecodeRuneInString(str[offset:])
+		offset += width
+	}
+	return offset
+}
+
+// (subs s start) or (subs s start end), view of a string.
+func builtinSubs(args []any) (any, error) {
+	if err := arity("subs", args, 2, 3); err != nil {
+		return nil, err
+	}
+	str, err := argument[string]("subs", args, 0, "a string")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := bounds("subs", args, utf8.RuneCountInString(str))
+	if err != nil {
+		return nil, err
+	}
+
+	from := runeOffset(str, start)
+	return str[from : from+runeOffset(str[from:], end-start)], nil
+}
+
+// (slice arr start) or (slice arr start end), view of an array.
+func builtinSlice(args []any) (any, error) {
+	if err := arity("slice", args, 2, 3); err != nil {
+		return nil, err
+	}
+	arr, err := argument[[]any]("slice", args, 0, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := bounds("slice", args, len(arr))
+	if err != nil {
+		return nil, err
+	}
+
+	// Capping the capacity ensures that the view can never write past its end.
+	return arr[start:end:end], nil
+}
+
+// count extracts the count argument of take and drop, clamped to the length of the array.
+func count(name string, args []any) (int, []any, error) {
+	if err := arity(name, args, 2, 2); err != nil {
+		return 0, nil, err
+	}
+	n, err := argument[int64](name, args, 0, "an int")
+	if err != nil {
+		return 0, nil, err
+	}
+	arr, err := argument[[]any](name, args, 1, "an array")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return int(min(max(n, 0), int64(len(arr)))), arr, nil
+}
+
+// (take n arr), view of the first n elements of an array (all of them if there are fewer).
+func builtinTake(args []any) (any, error) {
+	n, arr, err := count("take", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return arr[:n:n], nil
+}
+
+// (drop n arr), view of an array without its first n elements.
+func builtinDrop(args []any) (any, error) {
+	n, arr, err := count("drop", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return arr[n:], nil
+}
+
+// (copy x), shallow copy of a string, array, map or set, detached from the memory of x.
+// Other values are returned as is.
+func builtinCopy(args []any) (any, error) {
+	if err := arity("copy", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch value := args[0].(type) {
+	case string:
+		return strings.Clone(value), nil
+	case []any:
+		return slices.Clone(value), nil
+	case map[any]any:
+		return maps.Clone(value), nil
+	case map[any]struct{}:
+		return maps.Clone(value), nil
+	}
+
+	return args[0], nil
+}
@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Subs", `(subs "héllo" 1 3)`, `"él"`},
+		{"Subs to the end", `(subs "héllo" 2)`, `"llo"`},
+		{"Empty subs", `(subs "abc" 3)`, `""`},
+		{"Slice", "(slice [1 2 3 4] 1 3)", "[2 3]"},
+		{"Slice to the end", "(slice [1 2 3] 1)", "[2 3]"},
+		{"Take", "(take 2 [1 2 3])", "[1 2]"},
+		{"Take more than available", "(take 5 [1 2])", "[1 2]"},
+		{"Drop", "(drop 1 [1 2 3])", "[2 3]"},
+		{"Drop more than available", "(drop 5 [1 2])", "[]"},
+		{"Copy", `[(copy "a") (copy [1]) (copy {1 2}) (copy 3)]`, `["a" [1] {1 2} 3]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestSequencesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Subs past the end", `(subs "abc" 1 4)`, OutOfBounds},
+		{"Subs with reversed bounds", `(subs "abc" 2 1)`, OutOfBounds},
+		{"Slice of a string", `(slice "abc" 1)`, WrongType},
+		{"Take from a string", `(take 1 "abc")`, WrongType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func TestSequencesAreViews(t *testing.T) {
+	arr := []any{int64(1), int64(2), int64(3)}
+	for _, view := range []any{
+		call(t, "slice", arr, int64(1)),
+		call(t, "drop", int64(1), arr),
+	} {
+		if &view.([]any)[0] != &arr[1] {
+			t.Errorf("expected %s to share the memory of the original array", Repr(view))
+		}
+	}
+	if copied := call(t, "copy", arr).([]any); &copied[0] == &arr[0] {
+		t.Errorf("expected copy to allocate a new array")
+	}
+
+	str := "abcdef"
+	sub := call(t, "subs", str, int64(2)).(string)
+	if unsafe.Pointer(unsafe.StringData(sub)) != unsafe.Add(unsafe.Pointer(unsafe.StringData(str)), 2) {
+		t.Errorf("expected subs to share the memory of the original string")
+	}
+}
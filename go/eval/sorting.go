@@ -0,0 +1,282 @@
+package eval
+
+import (
+	"cmp"
+	"container/heap"
+	"math/big"
+	"slices"
+)
+
+// Sorting builtins return new arrays and leave their argument untouched.
+// All of them are stable: elements that compare equal keep their relative order.
+//
+// Without a comparator, values are ordered naturally: numbers by value (ints and floats can be
+// mixed), strings, keywords and runes lexicographically, arrays lexicographically by element.
+// A comparator is a function of two values returning either an int (negative, zero or positive,
+// like Go's cmp.Compare) or a boolean telling whether the first value is strictly less than the
+// second one.
+
+const NotComparable RuntimeFailure = "met values that cannot be compared"
+
+func init() {
+	Register(
+		&Builtin{Name: "sort", Calls: builtinSort},
+		&Builtin{Name: "sort-by", Calls: builtinSortBy},
+		&Builtin{Name: "min-by", Calls: builtinMinBy},
+		&Builtin{Name: "max-by", Calls: builtinMaxBy},
+		&Builtin{Name: "top-k", Calls: builtinTopK},
+	)
+}
+
+// Compare orders two values naturally, see the documentation of the sorting builtins.
+func Compare(a, b any) (int, error) {
+	switch a := a.(type) {
+	case int64, *big.Int, *big.Rat, float64:
+		if res, ok := compareNumbers(a, b); ok {
+			return res, nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case Keyword:
+		if b, ok := b.(Keyword); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case rune:
+		if b, ok := b.(rune); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			for i := 0; i < len(a) && i < len(b); i++ {
+				if res, err := Compare(a[i], b[i]); res != 0 || err != nil {
+					return res, err
+				}
+			}
+			return cmp.Compare(len(a), len(b)), nil
+		}
+	}
+
+	return 0, &RuntimeError{Reason: NotComparable.With("%s and %s", Repr(a), Repr(b))}
+}
+
+// comparator returns the comparison function described by the optional i-th argument, which is
+// called with call.
+func comparator(call Caller, args []any, i int) func(a, b any) (int, error) {
+	if len(args) <= i {
+		return Compare
+	}
+
+	function := args[i]
+	return func(a, b any) (int, error) {
+		res, err := call(function, []any{a, b})
+		if err != nil {
+			return 0, err
+		}
+
+		switch res := res.(type) {
+		case int64:
+			return cmp.Compare(res, 0), nil
+		case bool:
+			if res {
+				return -1, nil
+			}
+			// a is not less than b, it is either equal or greater.
+			greater, err := call(function, []any{b, a})
+			if Truthy(greater) {
+				return 1, err
+			}
+			return 0, err
+		}
+
+		return 0, &RuntimeError{Reason: WrongType.With(
+			"comparators must return an int or a bool, got %s", Repr(res),
+		)}
+	}
+}
+
+// keyed is an element along with its sorting key.
+type keyed struct {
+	key, element any
+}
+
+// keys computes the key of every element of an array once, calling keyfn with call.
+func keys(call Caller, keyfn any, arr []any) ([]keyed, error) {
+	res := make([]keyed, len(arr))
+	for i, element := range arr {
+		key, err := call(keyfn, []any{element})
+		if err != nil {
+			return nil, err
+		}
+		res[i] = keyed{key, element}
+	}
+	return res, nil
+}
+
+// sortStable sorts elements in place, returning the first comparison error.
+func sortStable[T any](elements []T, compare func(a, b T) (int, error)) error {
+	var failure error
+	slices.SortStableFunc(elements, func(a, b T) int {
+		if failure != nil {
+			return 0
+		}
+		res, err := compare(a, b)
+		failure = err
+		return res
+	})
+	return failure
+}
+
+// (sort coll) or (sort coll cmp)
+func builtinSort(call Caller, args []any) (any, error) {
+	if err := arity("sort", args, 1, 2); err != nil {
+		return nil, err
+	}
+	arr, err := argument[[]any]("sort", args, 0, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	res := slices.Clone(arr)
+	return res, sortStable(res, comparator(call, args, 1))
+}
+
+// (sort-by keyfn coll) or (sort-by keyfn coll cmp), keyfn is called once per element.
+func builtinSortBy(call Caller, args []any) (any, error) {
+	if err := arity("sort-by", args, 2, 3); err != nil {
+		return nil, err
+	}
+	arr, err := argument[[]any]("sort-by", args, 1, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := keys(call, args[0], arr)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := comparator(call, args, 2)
+	err = sortStable(pairs, func(a, b keyed) (int, error) {
+		return compare(a.key, b.key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]any, len(pairs))
+	for i, pair := range pairs {
+		res[i] = pair.element
+	}
+	return res, nil
+}
+
+// extremum returns the first element of an array whose key is the smallest (sign = 1) or the
+// greatest (sign = -1), nil if the array is empty.
+func extremum(call Caller, name string, args []any, sign int) (any, error) {
+	if err := arity(name, args, 2, 2); err != nil {
+		return nil, err
+	}
+	arr, err := argument[[]any](name, args, 1, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := keys(call, args[0], arr)
+	if err != nil || len(pairs) == 0 {
+		return nil, err
+	}
+
+	best := pairs[0]
+	for _, pair := range pairs[1:] {
+		res, err := Compare(pair.key, best.key)
+		if err != nil {
+			return nil, err
+		}
+		if res*sign < 0 {
+			best = pair
+		}
+	}
+	return best.element, nil
+}
+
+// (min-by keyfn coll)
+func builtinMinBy(call Caller, args []any) (any, error) {
+	return extremum(call, "min-by", args, 1)
+}
+
+// (max-by keyfn coll)
+func builtinMaxBy(call Caller, args []any) (any, error) {
+	return extremum(call, "max-by", args, -1)
+}
+
+// topHeap is a min-heap of the k best elements found so far, its root being the worst of them.
+// Ties are broken by position so that earlier elements are preferred, like in a stable sort.
+type topHeap struct {
+	elements []indexedKey
+	failure  error
+}
+
+type indexedKey struct {
+	keyed
+	index int
+}
+
+// worse returns true if a must come after b in the result.
+func (h *topHeap) worse(a, b indexedKey) bool {
+	res, err := Compare(a.key, b.key)
+	if err != nil && h.failure == nil {
+		h.failure = err
+	}
+	return res < 0 || (res == 0 && a.index > b.index)
+}
+
+func (h *topHeap) Len() int           { return len(h.elements) }
+func (h *topHeap) Less(i, j int) bool { return h.worse(h.elements[i], h.elements[j]) }
+func (h *topHeap) Swap(i, j int)      { h.elements[i], h.elements[j] = h.elements[j], h.elements[i] }
+func (h *topHeap) Push(x any)         { h.elements = append(h.elements, x.(indexedKey)) }
+func (h *topHeap) Pop() any {
+	last := h.elements[len(h.elements)-1]
+	h.elements = h.elements[:len(h.elements)-1]
+	return last
+}
+
+// (top-k k keyfn coll), the k elements with the greatest keys, from the greatest to the smallest.
+// It runs in O(n log k) instead of sorting the whole array.
+func builtinTopK(call Caller, args []any) (any, error) {
+	if err := arity("top-k", args, 3, 3); err != nil {
+		return nil, err
+	}
+	k, err := argument[int64]("top-k", args, 0, "an int")
+	if err != nil {
+		return nil, err
+	}
+	arr, err := argument[[]any]("top-k", args, 2, "an array")
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := keys(call, args[1], arr)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &topHeap{}
+	for i, pair := range pairs {
+		candidate := indexedKey{pair, i}
+		switch {
+		case int64(h.Len()) < k:
+			heap.Push(h, candidate)
+		case h.Len() > 0 && h.worse(h.elements[0], candidate):
+			h.elements[0] = candidate
+			heap.Fix(h, 0)
+		}
+	}
+
+	res := make([]any, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(indexedKey).element
+	}
+	return res, h.failure
+}
@@ -0,0 +1,72 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestSorting(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Natural order", "(sort [3 1.5 2 0.5])", "[0.5 1.5 2 3]"},
+		{"Strings", `(sort ["b" "ab" "a"])`, `["a" "ab" "b"]`},
+		{"Arrays", "(sort [[1 2] [1] [0 5]])", "[[0 5] [1] [1 2]]"},
+		{"Argument is not modified", "(def a [2 1]) (sort a) a", "[2 1]"},
+		{"Int comparator", "(sort [1 2 3] (lambda [a b] (sub b a)))", "[3 2 1]"},
+		{"Bool comparator", "(sort [1 3 2] (lambda [a b] (lt b a)))", "[3 2 1]"},
+		{"Stable with comparator", `
+			(sort [[1 :a] [0 :b] [1 :c] [0 :d]]
+			      (lambda [a b] (lt (first a) (first b))))`, "[[0 :b] [0 :d] [1 :a] [1 :c]]"},
+		{"Sort by", "(sort-by first [[2 :a] [1 :b] [2 :c] [1 :d]])", "[[1 :b] [1 :d] [2 :a] [2 :c]]"},
+		{"Sort by with comparator", "(sort-by first [[1 :a] [2 :b]] (lambda [a b] (lt b a)))", "[[2 :b] [1 :a]]"},
+		{"Min by", "(min-by first [[2 :a] [1 :b] [1 :c]])", "[1 :b]"},
+		{"Max by", "(max-by first [[2 :a] [1 :b] [2 :c]])", "[2 :a]"},
+		{"Min by of nothing", "(min-by first [])", "nil"},
+		{"Top k", "(top-k 2 first [[1 :a] [3 :b] [2 :c] [3 :d]])", "[[3 :b] [3 :d]]"},
+		{"Top k keeps earlier ties", "(top-k 3 first [[1 :a] [2 :b] [1 :c] [1 :d]])", "[[2 :b] [1 :a] [1 :c]]"},
+		{"Top k larger than the array", "(top-k 5 first [[1 :a] [2 :b]])", "[[2 :b] [1 :a]]"},
+		{"Top 0", "(top-k 0 first [[1 :a]])", "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestSortingErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Incomparable values", `(sort [1 "a"])`, NotComparable},
+		{"Invalid comparator result", `(sort [1 2] (lambda [a b] "a"))`, WrongType},
+		{"Incomparable keys", `(max-by (lambda [x] x) [1 :a])`, NotComparable},
+		{"Incomparable top keys", `(top-k 1 (lambda [x] x) [1 :a])`, NotComparable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StrBuilder accumulates strings in amortized linear time, unlike repeated concatenation.
+type StrBuilder struct {
+	builder strings.Builder
+}
+
+func (sb *StrBuilder) String() string {
+	return fmt.Sprintf("<str-builder %d>", sb.builder.Len())
+}
+
+// display writes the textual form of a value to a builder: strings are written as is, other values
+// are written as their representation.
+func display(builder *strings.Builder, value any) {
+	if str, ok := value.(string); ok {
+		builder.WriteString(str)
+		return
+	}
+
+	builder.WriteString(Repr(value))
+}
+
+//////////////
+// Builtins //
+
+func init() {
+	Register(
+		&Builtin{Name: "str", Fun: builtinStr},
+		&Builtin{Name: "str-builder", Fun: builtinStrBuilder},
+		&Builtin{Name: "sb/append!", Fun: builtinSbAppend},
+		&Builtin{Name: "sb/build", Fun: builtinSbBuild},
+	)
+}
+
+// (str values...), concatenates the textual form of all values in one pass.
+func builtinStr(args []any) (any, error) {
+	builder := strings.Builder{}
+	for _, arg := range args {
+		display(&builder, arg)
+	}
+
+	return builder.String(), nil
+}
+
+// (str-builder) or (str-builder initial)
+func builtinStrBuilder(args []any) (any, error) {
+	if err := arity("str-builder", args, 0, 1); err != nil {
+		return nil, err
+	}
+
+	res := &StrBuilder{}
+	if len(args) == 1 {
+		display(&res.builder, args[0])
+	}
+	return res, nil
+}
+
+// (sb/append! b values...), appends the textual form of the values to b and returns b.
+func builtinSbAppend(args []any) (any, error) {
+	if err := arity("sb/append!", args, 1, -1); err != nil {
+		return nil, err
+	}
+	sb, err := argument[*StrBuilder]("sb/append!", args, 0, "a string builder")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arg := range args[1:] {
+		display(&sb.builder, arg)
+	}
+	return sb, nil
+}
+
+// (sb/build b), returns the accumulated string. The builder can still be appended to afterwards.
+func builtinSbBuild(args []any) (any, error) {
+	if err := arity("sb/build", args, 1, 1); err != nil {
+		return nil, err
+	}
+	sb, err := argument[*StrBuilder]("sb/build", args, 0, "a string builder")
+	if err != nil {
+		return nil, err
+	}
+
+	return sb.builder.String(), nil
+}
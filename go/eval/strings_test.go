@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"testing"
+)
+
+// call applies the builtin with the given name to already evaluated arguments.
+// It is used when the test needs the Go values, e.g. to check that a builtin returns its argument.
+func call(t *testing.T, name string, args ...any) any {
+	t.Helper()
+
+	function, ok := NewGlobalEnvironment().Get(name)
+	if !ok {
+		t.Fatalf("builtin %s is not defined", name)
+	}
+
+	res, err := Apply(function, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return res
+}
+
+func TestStr(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"No arguments", "(str)", `""`},
+		{"Strings are not quoted", `(str "a" "b")`, `"ab"`},
+		{"Other values", `(str 1 " " 2.5 " " [1 "x"] nil)`, `"1 2.5 [1 \"x\"]nil"`},
+		{"Builder value", `(str-builder "abc")`, "<str-builder 3>"},
+		{"Builder methods", `(let [sb (str-builder "a")] (sb/append! sb 1 2) (sb/build sb))`, `"a12"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestStrBuilder(t *testing.T) {
+	sb := call(t, "str-builder")
+	for i := int64(0); i < 3; i++ {
+		if got := call(t, "sb/append!", sb, i, ","); got != sb {
+			t.Fatalf("expected sb/append! to return its builder, got %s", Repr(got))
+		}
+	}
+
+	if got := call(t, "sb/build", sb); got != "0,1,2," {
+		t.Errorf("expected %q, got %s", "0,1,2,", Repr(got))
+	}
+
+	call(t, "sb/append!", sb, "end")
+	if got := call(t, "sb/build", sb); got != "0,1,2,end" {
+		t.Errorf("expected %q, got %s", "0,1,2,end", Repr(got))
+	}
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/value"
+	"strings"
+)
+
+// Structs are records with named fields. (struct Point [x 0] [y 0]) defines Point as a struct type,
+// which is called to build instances: (Point 1 2) gives the fields in order, and the fields that
+// are not given take their default value, evaluated for each instance after the previous fields so
+// that a default can refer to them, e.g. (struct Rect [width 1] [height width]).
+// Fields are read with p.x and assigned with (set p.x value), which modifies the instance in place.
+
+const UnknownField RuntimeFailure = "met unknown field"
+
+// evalStruct defines a struct type.
+func evalStruct(node ast.Struct, env *Environment) (any, error) {
+	typ := &value.StructType{Name: node.Name.Name, Env: env}
+	for _, field := range node.Fields {
+		typ.Fields = append(typ.Fields, field.Variable.Name)
+		typ.Defaults = append(typ.Defaults, field.Value)
+	}
+	env.Define(typ.Name, typ)
+	return typ, nil
+}
+
+// construct builds an instance of a struct from the values of its first fields, depth being the
+// number of calls in progress, including the call of the type.
+func construct(typ *value.StructType, args []any, depth int) (any, error) {
+	if len(args) > len(typ.Fields) {
+		return nil, &RuntimeError{Reason: WrongArity.With(
+			"%s has %d fields, got %d values", typ.Name, len(typ.Fields), len(args),
+		)}
+	}
+
+	res := &value.Struct{Type: typ, Fields: make([]any, len(typ.Fields))}
+	local := typ.Env.NewCall(depth)
+	for i, field := range typ.Fields {
+		if i < len(args) {
+			res.Fields[i] = args[i]
+		} else {
+			var err error
+			if res.Fields[i], err = Eval(typ.Defaults[i], local); err != nil {
+				return nil, err
+			}
+		}
+		local.Define(field, res.Fields[i])
+	}
+	return res, nil
+}
+
+// evalAccess reads a field.
+func evalAccess(node ast.Access, env *Environment) (any, error) {
+	target, err := Eval(node.Target, env)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := fieldOf(target, node.Field)
+	if err != nil {
+		return nil, err
+	}
+	res, _ := instance.Field(node.Field.Name)
+	return res, nil
+}
+
+// assignField sets a field to value.
+func assignField(node ast.Access, value any, env *Environment) (any, error) {
+	target, err := Eval(node.Target, env)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := fieldOf(target, node.Field)
+	if err != nil {
+		return nil, err
+	}
+	instance.SetField(node.Field.Name, value)
+	return value, nil
+}
+
+// fieldOf checks that target is an instance of a struct with the given field. Errors are positioned
+// at the field.
+func fieldOf(target any, field ast.Symbol) (*value.Struct, error) {
+	instance, ok := target.(*value.Struct)
+	if !ok {
+		return nil, &RuntimeError{
+			Reason: WrongType.With(
+				"cannot access field %s of %s, which is not a struct", field.Name, Repr(target),
+			),
+			Pos: field.Pos(),
+		}
+	}
+
+	if _, ok := instance.Field(field.Name); !ok {
+		fields := "it has no fields"
+		if len(instance.Type.Fields) > 0 {
+			fields = "its fields are " + strings.Join(instance.Type.Fields, ", ")
+		}
+		return nil, &RuntimeError{
+			Reason: UnknownField.With("%s has no field %s, %s", instance.Type.Name, field.Name, fields),
+			Pos:    field.Pos(),
+		}
+	}
+	return instance, nil
+}
@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Definition", "(struct Point [x 0] [y 0])", "<struct Point>"},
+		{"Defaults", "(struct Point [x 0] [y 0])\n[(Point) (Point 1) (Point 1 2)]",
+			"[<Point :x 0 :y 0> <Point :x 1 :y 0> <Point :x 1 :y 2>]"},
+		{"Default using a previous field", "(struct Rect [w 1] [h w])\n(Rect 3)", "<Rect :w 3 :h 3>"},
+		{"Default using the definition scope", "(let [n 5] (struct Box [size n]) (Box))", "<Box :size 5>"},
+		{"Read", "(struct Point [x 0] [y 0])\n(def p (Point 1 2))\n[p.x p.y]", "[1 2]"},
+		{"Nested read", "(struct Point [x 0] [y 0])\n(struct Line [start (Point)])\n" +
+			"(Line (Point 3)).start.x", "3"},
+		{"Assign", "(struct Point [x 0] [y 0])\n(def p (Point))\n[(set p.x 5) p]", "[5 <Point :x 5 :y 0>]"},
+		{"Nested assign", "(struct Point [x 0] [y 0])\n(struct Line [start (Point)])\n" +
+			"(def l (Line))\n(set l.start.y 4)\nl.start", "<Point :x 0 :y 4>"},
+		{"Defaults are not shared", "(struct Point [x 0] [y 0])\n(struct Line [start (Point)])\n" +
+			"(def a (Line))\n(def b (Line))\n(set a.start.x 1)\nb.start.x", "0"},
+		{"Equal instances", "(struct Point [x 0] [y 0])\n[(= (Point 1) (Point 1)) (= (Point 1) (Point 2))]",
+			"[true false]"},
+		{"Get a field", "(struct Point [x 0] [y 0])\n(get (Point 1) :x)", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestStructsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		reason  RuntimeFailure
+		message string
+	}{
+		{"Unknown field", "(struct Point [x 0] [y 0])\n(Point).z", UnknownField,
+			"runtime error at line 2 column 8: met unknown field: Point has no field z, its fields are x, y"},
+		{"Assign an unknown field", "(struct Empty)\n(def e (Empty))\n(set e.x 1)", UnknownField,
+			"runtime error at line 3 column 7: met unknown field: Empty has no field x, it has no fields"},
+		{"Field of a non-struct", "(def m {:x 1})\nm.x", WrongType,
+			"runtime error at line 2 column 2: met argument of the wrong type: cannot access field x of {:x 1}, " +
+				"which is not a struct"},
+		{"Too many values", "(struct Point [x 0] [y 0])\n(Point 1 2 3)", WrongArity,
+			"runtime error at line 2 column 0: met call with the wrong number of arguments: " +
+				"Point has 2 fields, got 3 values"},
+		{"Failing default", "(struct Point [x (+ :a 1)])\n(Point)", WrongType,
+			"runtime error at line 1 column 17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+			if !strings.HasPrefix(rerr.Error(), tt.message) {
+				t.Errorf("expected message:\n> %s\ngot:\n> %s", tt.message, rerr.Error())
+			}
+		})
+	}
+}
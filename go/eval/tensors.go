@@ -0,0 +1,332 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tensors are n-dimensional arrays of floats stored contiguously in row-major order, so that
+// numeric code does not box every element like arrays do.
+// Their builtins return new tensors and leave their arguments untouched, except reshape which
+// returns a view sharing the elements of its argument.
+
+const ShapeMismatch RuntimeFailure = "met tensors of incompatible shapes"
+
+// Tensor is an n-dimensional array of floats.
+type Tensor struct {
+	Shape []int
+	Data  []float64
+}
+
+func (t *Tensor) String() string {
+	if len(t.Shape) == 0 {
+		return "<tensor scalar>"
+	}
+
+	dims := make([]string, len(t.Shape))
+	for i, dim := range t.Shape {
+		dims[i] = fmt.Sprint(dim)
+	}
+	return fmt.Sprintf("<tensor %s>", strings.Join(dims, "x"))
+}
+
+// Array returns the elements of the tensor as nested arrays of floats.
+func (t *Tensor) Array() any {
+	if len(t.Shape) == 0 {
+		return t.Data[0]
+	}
+
+	var nest func(data []float64, shape []int) []any
+	nest = func(data []float64, shape []int) []any {
+		res := make([]any, shape[0])
+		stride := len(data) / max(shape[0], 1)
+		for i := range res {
+			if len(shape) == 1 {
+				res[i] = data[i]
+			} else {
+				res[i] = nest(data[i*stride:(i+1)*stride], shape[1:])
+			}
+		}
+		return res
+	}
+	return nest(t.Data, t.Shape)
+}
+
+// size returns the number of elements of a tensor of the given shape.
+func size(shape []int) int {
+	res := 1
+	for _, dim := range shape {
+		res *= dim
+	}
+	return res
+}
+
+// sameShape returns true if both shapes have the same dimensions.
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTensor builds a tensor from a number or from nested arrays of numbers with a regular shape.
+func NewTensor(value any) (*Tensor, error) {
+	res := &Tensor{}
+	if err := res.fill(value, 0); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// fill appends the numbers of value at the given depth, inferring the shape from the first array
+// met at each depth.
+func (t *Tensor) fill(value any, depth int) error {
+	switch value := value.(type) {
+	case int64:
+		if depth != len(t.Shape) {
+			break
+		}
+		t.Data = append(t.Data, float64(value))
+		return nil
+	case float64:
+		if depth != len(t.Shape) {
+			break
+		}
+		t.Data = append(t.Data, value)
+		return nil
+	case []any:
+		if depth == len(t.Shape) && len(t.Data) == 0 {
+			t.Shape = append(t.Shape, len(value))
+		}
+		if depth >= len(t.Shape) || t.Shape[depth] != len(value) {
+			break
+		}
+		for _, element := range value {
+			if err := t.fill(element, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return &RuntimeError{Reason: WrongType.With("tensors hold numbers, got %s", Repr(value))}
+	}
+
+	return &RuntimeError{Reason: ShapeMismatch.With("irregular nesting at %s", Repr(value))}
+}
+
+// tensorArgument extracts a tensor argument, numbers and arrays being converted to tensors.
+func tensorArgument(name string, args []any, i int) (*Tensor, error) {
+	if t, ok := args[i].(*Tensor); ok {
+		return t, nil
+	}
+	switch args[i].(type) {
+	case int64, float64, []any:
+		return NewTensor(args[i])
+	}
+	return nil, &RuntimeError{Reason: WrongType.With(
+		"argument %d of %s must be a tensor, got %s", i+1, name, Repr(args[i]),
+	)}
+}
+
+// Elementwise applies op to the elements of two tensors of the same shape.
+// A tensor without dimensions (a scalar) is broadcast to the shape of the other one.
+func Elementwise(a, b *Tensor, op func(x, y float64) float64) (*Tensor, error) {
+	shape := a.Shape
+	switch {
+	case len(a.Shape) == 0:
+		shape = b.Shape
+	case len(b.Shape) == 0:
+	case !sameShape(a.Shape, b.Shape):
+		return nil, &RuntimeError{Reason: ShapeMismatch.With("%s and %s", a, b)}
+	}
+
+	res := &Tensor{shape, make([]float64, size(shape))}
+	for i := range res.Data {
+		res.Data[i] = op(a.Data[i%len(a.Data)], b.Data[i%len(b.Data)])
+	}
+	return res, nil
+}
+
+// Dot computes the dot product of two vectors, the product of two matrices or the product of a
+// matrix and a vector.
+func Dot(a, b *Tensor) (*Tensor, error) {
+	switch {
+	case len(a.Shape) == 1 && len(b.Shape) == 1 && a.Shape[0] == b.Shape[0]:
+		res := 0.
+		for i := range a.Data {
+			res += a.Data[i] * b.Data[i]
+		}
+		return &Tensor{nil, []float64{res}}, nil
+	case len(a.Shape) == 2 && len(b.Shape) >= 1 && len(b.Shape) <= 2 && a.Shape[1] == b.Shape[0]:
+		rows, inner, cols := a.Shape[0], a.Shape[1], 1
+		shape := []int{rows}
+		if len(b.Shape) == 2 {
+			cols = b.Shape[1]
+			shape = append(shape, cols)
+		}
+
+		res := &Tensor{shape, make([]float64, rows*cols)}
+		for i := 0; i < rows; i++ {
+			for k := 0; k < inner; k++ {
+				aik := a.Data[i*inner+k]
+				for j := 0; j < cols; j++ {
+					res.Data[i*cols+j] += aik * b.Data[k*cols+j]
+				}
+			}
+		}
+		return res, nil
+	}
+
+	return nil, &RuntimeError{Reason: ShapeMismatch.With("cannot compute the dot product of %s and %s", a, b)}
+}
+
+//////////////
+// Builtins //
+
+func init() {
+	Register(
+		&Builtin{Name: "tensor", Fun: builtinTensor},
+		&Builtin{Name: "zeros", Fun: builtinZeros},
+		&Builtin{Name: "tensor-shape", Fun: builtinTensorShape},
+		&Builtin{Name: "tensor-array", Fun: builtinTensorArray},
+		&Builtin{Name: "reshape", Fun: builtinReshape},
+		&Builtin{Name: "dot", Fun: builtinDot},
+		elementwise("tensor-add", func(x, y float64) float64 { return x + y }),
+		elementwise("tensor-sub", func(x, y float64) float64 { return x - y }),
+		elementwise("tensor-mul", func(x, y float64) float64 { return x * y }),
+		elementwise("tensor-div", func(x, y float64) float64 { return x / y }),
+	)
+}
+
+// (tensor value), a tensor built from a number or nested arrays of numbers.
+func builtinTensor(args []any) (any, error) {
+	if err := arity("tensor", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return tensorArgument("tensor", args, 0)
+}
+
+// shapeArgument extracts the dimensions given as int arguments.
+func shapeArgument(name string, args []any) ([]int, error) {
+	shape := make([]int, len(args))
+	for i := range args {
+		dim, err := argument[int64](name, args, i, "an int")
+		if err != nil {
+			return nil, err
+		}
+		if dim < 0 {
+			return nil, &RuntimeError{Reason: InvalidValue.With("negative dimension %d in %s", dim, name)}
+		}
+		shape[i] = int(dim)
+	}
+	return shape, nil
+}
+
+// (zeros dims...), a tensor of the given shape filled with zeros.
+func builtinZeros(args []any) (any, error) {
+	shape, err := shapeArgument("zeros", args)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{shape, make([]float64, size(shape))}, nil
+}
+
+// (tensor-shape t), the dimensions of t as an array of ints.
+func builtinTensorShape(args []any) (any, error) {
+	if err := arity("tensor-shape", args, 1, 1); err != nil {
+		return nil, err
+	}
+	t, err := argument[*Tensor]("tensor-shape", args, 0, "a tensor")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]any, len(t.Shape))
+	for i, dim := range t.Shape {
+		res[i] = int64(dim)
+	}
+	return res, nil
+}
+
+// (tensor-array t), the elements of t as nested arrays of floats.
+func builtinTensorArray(args []any) (any, error) {
+	if err := arity("tensor-array", args, 1, 1); err != nil {
+		return nil, err
+	}
+	t, err := argument[*Tensor]("tensor-array", args, 0, "a tensor")
+	if err != nil {
+		return nil, err
+	}
+	return t.Array(), nil
+}
+
+// (reshape t dims...), a view of the elements of t with another shape of the same size.
+func builtinReshape(args []any) (any, error) {
+	if err := arity("reshape", args, 1, -1); err != nil {
+		return nil, err
+	}
+	t, err := argument[*Tensor]("reshape", args, 0, "a tensor")
+	if err != nil {
+		return nil, err
+	}
+	shape, err := shapeArgument("reshape", args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if size(shape) != len(t.Data) {
+		return nil, &RuntimeError{Reason: ShapeMismatch.With(
+			"cannot reshape %s with %d elements to %v", t, len(t.Data), shape,
+		)}
+	}
+	return &Tensor{shape, t.Data}, nil
+}
+
+// (dot a b), the dot product of two vectors (a float) or the product of a matrix with a matrix or
+// a vector (a tensor).
+func builtinDot(args []any) (any, error) {
+	if err := arity("dot", args, 2, 2); err != nil {
+		return nil, err
+	}
+	a, err := tensorArgument("dot", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := tensorArgument("dot", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := Dot(a, b)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Shape) == 0 {
+		return res.Data[0], nil
+	}
+	return res, nil
+}
+
+// elementwise creates a builtin applying op to the elements of two tensors, numbers being
+// broadcast to the shape of the other argument.
+func elementwise(name string, op func(x, y float64) float64) *Builtin {
+	return &Builtin{Name: name, Fun: func(args []any) (any, error) {
+		if err := arity(name, args, 2, 2); err != nil {
+			return nil, err
+		}
+		a, err := tensorArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := tensorArgument(name, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Elementwise(a, b, op)
+	}}
+}
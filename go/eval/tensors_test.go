@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestTensors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Shape", "(tensor-shape (tensor [[1 2 3] [4 5 6]]))", "[2 3]"},
+		{"Scalar", "(tensor 2)", "<tensor scalar>"},
+		{"Round trip", "(tensor-array (tensor [[1 2] [3 4.5]]))", "[[1.0 2.0] [3.0 4.5]]"},
+		{"Zeros", "(tensor-array (zeros 2 1))", "[[0.0] [0.0]]"},
+		{"Reshape", "(tensor-array (reshape (tensor [1 2 3 4 5 6]) 3 2))", "[[1.0 2.0] [3.0 4.0] [5.0 6.0]]"},
+		{"Addition", "(tensor-array (tensor-add [1 2] [10 20]))", "[11.0 22.0]"},
+		{"Broadcast", "(tensor-array (tensor-mul 2 [[1 2] [3 4]]))", "[[2.0 4.0] [6.0 8.0]]"},
+		{"Vector dot product", "(dot [1 2 3] [4 5 6])", "32.0"},
+		{"Matrix product", "(tensor-array (dot [[1 2] [3 4]] [[5 6] [7 8]]))", "[[19.0 22.0] [43.0 50.0]]"},
+		{"Matrix vector product", "(tensor-array (dot [[1 2] [3 4]] [1 1]))", "[3.0 7.0]"},
+		{"Empty dimension", "(tensor-array (tensor [[] []]))", "[[] []]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestTensorsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Irregular rows", "(tensor [[1 2] [3]])", ShapeMismatch},
+		{"Mixed nesting", "(tensor [1 [2]])", ShapeMismatch},
+		{"Not a number", `(tensor ["a"])`, WrongType},
+		{"Different shapes", "(tensor-add [1 2] [1 2 3])", ShapeMismatch},
+		{"Wrong reshape size", "(reshape (zeros 2 2) 3)", ShapeMismatch},
+		{"Incompatible product", "(dot [[1 2]] [1 2 3])", ShapeMismatch},
+		{"Negative dimension", "(zeros 2 (sub 0 1))", InvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	n := 64
+	a := &Tensor{[]int{n, n}, make([]float64, n*n)}
+	for i := range a.Data {
+		a.Data[i] = float64(i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Dot(a, a); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package eval
+
+import (
+	"fmt"
+)
+
+// Transducers are composable transformations of the steps of a reduction, independent of the
+// collection they read from and of the one they build.
+// Applying a composition of transducers with (into to xf coll) goes through coll once and adds the
+// transformed elements directly to the result, without building intermediate collections.
+
+// reducer adds an element to an accumulated result.
+type reducer func(acc, element any) (any, error)
+
+// Transducer transforms a reducer into another reducer.
+type Transducer struct {
+	// Name describes the transformation.
+	Name string
+
+	// transform builds the reducer, whose calls of functions are made with call.
+	transform func(call Caller, rf reducer) reducer
+}
+
+func (xf *Transducer) String() string {
+	return fmt.Sprintf("<transducer %s>", xf.Name)
+}
+
+func init() {
+	Register(
+		&Builtin{Name: "mapping", Fun: builtinMapping},
+		&Builtin{Name: "filtering", Fun: builtinFiltering},
+		&Builtin{Name: "comp", Fun: builtinComp},
+		&Builtin{Name: "into", Calls: builtinInto},
+	)
+}
+
+// (mapping f), transducer replacing each element x by (f x).
+func builtinMapping(args []any) (any, error) {
+	if err := arity("mapping", args, 1, 1); err != nil {
+		return nil, err
+	}
+	f := args[0]
+
+	return &Transducer{"mapping", func(call Caller, rf reducer) reducer {
+		return func(acc, element any) (any, error) {
+			mapped, err := call(f, []any{element})
+			if err != nil {
+				return nil, err
+			}
+			return rf(acc, mapped)
+		}
+	}}, nil
+}
+
+// (filtering pred), transducer keeping the elements x for which (pred x) is truthy.
+func builtinFiltering(args []any) (any, error) {
+	if err := arity("filtering", args, 1, 1); err != nil {
+		return nil, err
+	}
+	pred := args[0]
+
+	return &Transducer{"filtering", func(call Caller, rf reducer) reducer {
+		return func(acc, element any) (any, error) {
+			keep, err := call(pred, []any{element})
+			if err != nil || !Truthy(keep) {
+				return acc, err
+			}
+			return rf(acc, element)
+		}
+	}}, nil
+}
+
+// (comp xf...) composes transducers, elements go through them from left to right.
+// (comp f...) composes functions, arguments go through them from right to left.
+func builtinComp(args []any) (any, error) {
+	if err := arity("comp", args, 1, -1); err != nil {
+		return nil, err
+	}
+
+	if _, ok := args[0].(*Transducer); ok {
+		xfs := make([]*Transducer, len(args))
+		for i := range args {
+			xf, err := argument[*Transducer]("comp", args, i, "a transducer like the first one")
+			if err != nil {
+				return nil, err
+			}
+			xfs[i] = xf
+		}
+
+		return &Transducer{"comp", func(call Caller, rf reducer) reducer {
+			// The outermost reducer is seen first by elements, so it comes from the first transducer.
+			for i := len(xfs) - 1; i >= 0; i-- {
+				rf = xfs[i].transform(call, rf)
+			}
+			return rf
+		}}, nil
+	}
+
+	functions := args
+	return &Builtin{Name: "comp", Calls: func(call Caller, args []any) (any, error) {
+		res, err := call(functions[len(functions)-1], args)
+		for i := len(functions) - 2; i >= 0 && err == nil; i-- {
+			res, err = call(functions[i], []any{res})
+		}
+		return res, err
+	}}, nil
+}
+
+// (into to coll) adds the elements of coll to the collection to.
+// (into to xf coll) adds the elements of coll transformed by xf.
+//
+// to is not modified, a new collection is returned.
+// Arrays and sets receive the elements, maps receive [key value] arrays.
+// Elements of a map are its [key value] pairs (in no particular order), elements of a deque are
+// read from front to back.
+func builtinInto(call Caller, args []any) (any, error) {
+	if err := arity("into", args, 2, 3); err != nil {
+		return nil, err
+	}
+
+	to, coll := args[0], args[len(args)-1]
+	acc, rf, err := collector(to)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 3 {
+		xf, err := argument[*Transducer]("into", args, 1, "a transducer")
+		if err != nil {
+			return nil, err
+		}
+		rf = xf.transform(call, rf)
+	}
+
+	return reduce(rf, acc, coll)
+}
+
+// collector returns a fresh copy of a collection and the reducer adding elements to it.
+func collector(to any) (any, reducer, error) {
+	switch to := to.(type) {
+	case []any:
+		return append([]any{}, to...), func(acc, element any) (any, error) {
+			return append(acc.([]any), element), nil
+		}, nil
+	case map[any]any:
+		res := make(map[any]any, len(to))
+		for key, value := range to {
+			res[key] = value
+		}
+		return res, func(acc, element any) (any, error) {
+			pair, ok := element.([]any)
+			if !ok || len(pair) != 2 {
+				return nil, &RuntimeError{Reason: WrongType.With(
+					"elements added to a map must be [key value] arrays, got %s", Repr(element),
+				)}
+			}
+			if err := checkHashable(pair[0]); err != nil {
+				return nil, err
+			}
+			acc.(map[any]any)[pair[0]] = pair[1]
+			return acc, nil
+		}, nil
+	case map[any]struct{}:
+		res := make(map[any]struct{}, len(to))
+		for element := range to {
+			res[element] = struct{}{}
+		}
+		return res, func(acc, element any) (any, error) {
+			if err := checkHashable(element); err != nil {
+				return nil, err
+			}
+			acc.(map[any]struct{})[element] = struct{}{}
+			return acc, nil
+		}, nil
+	}
+
+	return nil, nil, &RuntimeError{Reason: WrongType.With("cannot add elements to %s", Repr(to))}
+}
+
+// reduce goes through the elements of a collection, accumulating them with rf.
+func reduce(rf reducer, acc any, coll any) (any, error) {
+	var err error
+	switch coll := coll.(type) {
+	case []any:
+		for _, element := range coll {
+			if acc, err = rf(acc, element); err != nil {
+				return nil, err
+			}
+		}
+	case map[any]any:
+		for key, value := range coll {
+			if acc, err = rf(acc, []any{key, value}); err != nil {
+				return nil, err
+			}
+		}
+	case map[any]struct{}:
+		for element := range coll {
+			if acc, err = rf(acc, element); err != nil {
+				return nil, err
+			}
+		}
+	case *Deque:
+		for i := 0; i < coll.Len(); i++ {
+			if acc, err = rf(acc, coll.At(i)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, &RuntimeError{Reason: WrongType.With("cannot iterate over %s", Repr(coll))}
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,106 @@
+package eval
+
+import (
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"testing"
+)
+
+func TestTransducers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Into without transducer", "(into [0] [1 2])", "[0 1 2]"},
+		{"Mapping", "(into [] (mapping (lambda [x] (add x 1))) [1 2])", "[2 3]"},
+		{"Filtering", "(into [] (filtering (lambda [x] (lt 1 x))) [1 2 3])", "[2 3]"},
+		{"Composition order", `
+			(def xf (comp (mapping (lambda [x] (add x 10)))
+			              (filtering (lambda [x] (lt x 12)))))
+			(into [] xf [1 2 3])`, "[11]"},
+		{"Into a map", "(into {1 1} (mapping (lambda [x] [x true])) [2])", "{1 1 2 true}"},
+		{"Map elements are pairs", "(into [] (into {} [[1 2]]))", "[[1 2]]"},
+		{"Target is not modified", "(def to [1]) (into to [2]) to", "[1]"},
+		{"Function composition", "((comp (lambda [x] [x]) (lambda [a b] (add a b))) 1 2)", "[3]"},
+		{"Transducer value", "(mapping 1)", "<transducer mapping>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, Repr(got))
+			}
+		})
+	}
+}
+
+func TestTransducersErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason RuntimeFailure
+	}{
+		{"Mixed composition", "(comp (mapping 1) 2)", WrongType},
+		{"Invalid map element", "(into {} [1])", WrongType},
+		{"Not a collection", "(into [] 1)", WrongType},
+		{"Error in the transformation", "(into [] (mapping 1) [1])", NotCallable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			rerr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("expected a runtime error, got: %v", err)
+			}
+
+			if !rerr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, rerr.Reason)
+			}
+		})
+	}
+}
+
+// benchmarkPipeline evaluates input repeatedly, with coll bound to a large array.
+func benchmarkPipeline(b *testing.B, input string) {
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	env := testEnvironment()
+	coll := make([]any, 10000)
+	for i := range coll {
+		coll[i] = int64(i)
+	}
+	env.Define("coll", coll)
+	env.Define("inc", &Builtin{Name: "inc", Fun: func(args []any) (any, error) {
+		return args[0].(int64) + 1, nil
+	}})
+	env.Define("small", &Builtin{Name: "small", Fun: func(args []any) (any, error) {
+		return args[0].(int64) < 100, nil
+	}})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EvalAll(forms, env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The transducer pipeline goes through the collection once and does not build the intermediate
+// array of the two-step pipeline.
+func BenchmarkIntoTransducer(b *testing.B) {
+	benchmarkPipeline(b, "(into [] (comp (mapping inc) (filtering small)) coll)")
+}
+
+func BenchmarkIntoTwoSteps(b *testing.B) {
+	benchmarkPipeline(b, "(into [] (filtering small) (into [] (mapping inc) coll))")
+}
@@ -0,0 +1,22 @@
+package eval
+
+import "mooss/harp/value"
+
+// Runtime values are defined by the value package, see value.Value for their representation.
+type (
+	Keyword = value.Keyword
+	Symbol  = value.Symbol
+	Closure = value.Closure
+	Builtin = value.Builtin
+	Caller  = value.Caller
+)
+
+// Truthy returns false for nil and false, true for everything else.
+func Truthy(v any) bool {
+	return value.Truthy(v)
+}
+
+// Repr returns the representation of a value, as printed by the REPL.
+func Repr(v any) string {
+	return value.String(v)
+}
@@ -0,0 +1,417 @@
+// Package format reprints Harp source code with a consistent layout.
+//
+// Unlike ast.Printer, the formatter works on the tokens of the source rather than on its syntax
+// tree, so that comments are kept and literals are written exactly as they were (raw strings,
+// heredocs, hexadecimal numbers...). Only the whitespace between tokens changes.
+package format
+
+import (
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formatter lays out forms like ast.Printer and the indent package: a form is written on a single
+// line when it fits in Width, otherwise the body of special forms is indented by Indent columns, the
+// arguments of a call are aligned with the first one and the elements of collections are aligned
+// after the opening delimiter. Maps are broken into one key/value pair per line and the values of
+// let and loop bindings are aligned.
+//
+// Comments stay where they are relative to the forms, comments at the end of a line stay at the end
+// of the line, and blank lines between forms are kept (but not repeated).
+type Formatter struct {
+	// Indent is the number of columns by which the body of special forms is indented.
+	Indent int
+
+	// Width is the number of columns that forms should fit in, 0 meaning that they are never broken.
+	Width int
+}
+
+// DefaultFormatter is the formatter used by Source.
+var DefaultFormatter = Formatter{Indent: 2, Width: 80}
+
+// Source formats input with the default formatter.
+func Source(input string) (string, error) {
+	return DefaultFormatter.Source(input)
+}
+
+// Source formats input, which must parse without error.
+// Formatting is idempotent: formatting formatted source does not change it.
+func (f Formatter) Source(input string) (string, error) {
+	if _, err := parse.NewParser(lex.NewLexer(input)).Parse(); err != nil {
+		return "", err
+	}
+
+	tokens, _ := lex.NewLexer(input).Tokenize() // Parsing succeeded, so lexing did too.
+	r := &reader{tokens: lex.AttachTrivia(tokens)}
+	forms, _ := r.elements()
+
+	w := &writer{}
+	for i, form := range forms {
+		switch {
+		case i == 0:
+		case form.trailing:
+			w.write(" ")
+		default:
+			if form.blank {
+				w.newline(0)
+			}
+			w.newline(0)
+		}
+		f.write(w, form)
+	}
+	if len(forms) > 0 {
+		w.newline(0)
+	}
+	return w.String(), nil
+}
+
+//////////
+// Tree //
+
+type kind uint8
+
+const (
+	atom     kind = iota // A token written as is.
+	comment              // A line or block comment.
+	list                 // Elements between delimiters.
+	prefixed             // A prefix token followed by a form, e.g. 'x or #_x.
+	meta                 // ^metadata followed by the form it applies to.
+	access               // A form followed by a dot and a field, e.g. p.x.
+)
+
+// node is a form of the source, along with the comments found inside of it.
+type node struct {
+	kind kind
+
+	// tok is the atom, the comment, the opening delimiter, the prefix or the dot of an access.
+	tok lex.Token
+
+	// closer is the closing delimiter of a list.
+	closer string
+
+	// children are the elements of a list, the forms following a prefix, comments included, or the
+	// form and the field of an access.
+	children []*node
+
+	// blank is true when a blank line precedes the node in the source.
+	blank bool
+
+	// trailing is true for a comment on the same line as the end of the previous token.
+	trailing bool
+
+	// pairs is true for a list whose elements go by pairs (maps, bindings and reader conditionals),
+	// which are kept on the same line when the list is broken.
+	pairs bool
+
+	// aligned is true for a list of pairs whose values are aligned (bindings).
+	aligned bool
+}
+
+// forms returns the children that are not comments.
+func (n *node) forms() []*node {
+	res := []*node{}
+	for _, child := range n.children {
+		if child.kind != comment {
+			res = append(res, child)
+		}
+	}
+	return res
+}
+
+// reader builds the tree of a token stream whose trivia is attached.
+type reader struct {
+	tokens []lex.Token
+	pos    int
+}
+
+func (r *reader) next() lex.Token {
+	r.pos++
+	return r.tokens[r.pos-1]
+}
+
+// elements reads nodes until a closing delimiter or EOF, which is consumed and returned.
+// The elements end with the comments leading the delimiter.
+func (r *reader) elements() ([]*node, lex.Token) {
+	res := []*node{}
+	for {
+		switch tok := r.tokens[r.pos]; tok.Type {
+		case lex.TOKEN_RPAREN, lex.TOKEN_RBRACKET, lex.TOKEN_RBRACE, lex.TOKEN_EOF:
+			return append(res, leading(r.next())...), tok
+		}
+
+		before, n, after := r.node()
+		res = slices.Concat(res, before, []*node{n}, after)
+	}
+}
+
+// node reads the next form, returning it along with the comments before and after it.
+func (r *reader) node() (before []*node, res *node, after []*node) {
+	tok := r.next()
+	res = &node{tok: tok, blank: tok.Trivia.Blank > 0}
+	before, after = leading(tok), trailing(tok)
+
+	switch tok.Type {
+	case lex.TOKEN_LPAREN, lex.TOKEN_LBRACKET, lex.TOKEN_LBRACE, lex.TOKEN_SET:
+		res.kind = list
+		children, closer := r.elements()
+		res.children = append(after, children...)
+		res.closer, after = closer.Literal, trailing(closer)
+		res.pairs = tok.Type == lex.TOKEN_LBRACE
+
+		forms := res.forms()
+		if len(forms) > 1 && isHead(forms[0], "let", "loop") && forms[1].tok.Type == lex.TOKEN_LBRACKET {
+			forms[1].pairs, forms[1].aligned = true, true
+		}
+	case lex.TOKEN_QUOTE, lex.TOKEN_BACKQUOTE, lex.TOKEN_UNQUOTE, lex.TOKEN_SPLICE, lex.TOKEN_AT,
+		lex.TOKEN_DISCARD, lex.TOKEN_CONDITIONAL:
+		res.kind = prefixed
+		formBefore, form, formAfter := r.node()
+		res.children = slices.Concat(after, formBefore, []*node{form})
+		after = formAfter
+		if tok.Type == lex.TOKEN_CONDITIONAL {
+			form.pairs = true
+		}
+	case lex.TOKEN_CARET:
+		res.kind = meta
+		dataBefore, data, dataAfter := r.node()
+		formBefore, form, formAfter := r.node()
+		res.children = slices.Concat(after, dataBefore, []*node{data}, dataAfter, formBefore, []*node{form})
+		after = formAfter
+	default:
+		res.kind = atom
+	}
+
+	// The fields accessed on the form are glued to it, see parse.Parser.
+	for r.tokens[r.pos].Type == lex.TOKEN_DOT && r.tokens[r.pos].Offset == r.tokens[r.pos-1].End() {
+		dot, field := r.next(), r.next()
+		res = &node{kind: access, tok: dot, children: []*node{res, {tok: field}}, blank: res.blank}
+		after = trailing(field)
+	}
+	return before, res, after
+}
+
+// leading returns the comment nodes preceding a token.
+func leading(tok lex.Token) []*node {
+	res := []*node{}
+	for _, c := range tok.Trivia.Leading {
+		res = append(res, &node{kind: comment, tok: c.Token, blank: c.Blank > 0})
+	}
+	return res
+}
+
+// trailing returns the comment nodes on the line where a token ends.
+func trailing(tok lex.Token) []*node {
+	res := []*node{}
+	for _, c := range tok.Trivia.Trailing {
+		res = append(res, &node{kind: comment, tok: c, trailing: true})
+	}
+	return res
+}
+
+// isHead tells whether a node is a symbol among the given names.
+func isHead(n *node, names ...string) bool {
+	if n.kind != atom || n.tok.Type != lex.TOKEN_SYMBOL {
+		return false
+	}
+	for _, name := range names {
+		if n.tok.Literal == name {
+			return true
+		}
+	}
+	return false
+}
+
+////////////
+// Layout //
+
+// writer builds the output while keeping track of the current column.
+type writer struct {
+	strings.Builder
+	column int
+}
+
+func (w *writer) write(text string) {
+	w.WriteString(text)
+	if last := strings.LastIndexByte(text, '\n'); last >= 0 {
+		w.column = utf8.RuneCountInString(text[last+1:])
+	} else {
+		w.column += utf8.RuneCountInString(text)
+	}
+}
+
+func (w *writer) newline(indent int) {
+	w.WriteByte('\n')
+	w.WriteString(strings.Repeat(" ", indent))
+	w.column = indent
+}
+
+// flat renders a node on a single line, ok being false when it cannot be, because it contains a
+// line comment or a multi-line token.
+func (n *node) flat() (res string, ok bool) {
+	switch n.kind {
+	case atom, comment:
+		line := !strings.Contains(n.tok.Literal, "\n")
+		return n.tok.Literal, line && !isLineComment(n)
+	}
+
+	parts := make([]string, len(n.children))
+	for i, child := range n.children {
+		if parts[i], ok = child.flat(); !ok {
+			return "", false
+		}
+	}
+
+	switch n.kind {
+	case list:
+		return n.tok.Literal + strings.Join(parts, " ") + n.closer, true
+	case access:
+		return parts[0] + n.tok.Literal + parts[1], true
+	}
+	return n.prefix() + strings.Join(parts, " "), true
+}
+
+// prefix returns the prefix of a prefixed or meta node, followed by a space when the next child
+// would otherwise merge with it (,@ would be read as a splice) or is a comment.
+func (n *node) prefix() string {
+	first := n.children[0]
+	if first.kind == comment || (n.tok.Type == lex.TOKEN_UNQUOTE && first.tok.Type == lex.TOKEN_AT) {
+		return n.tok.Literal + " "
+	}
+	return n.tok.Literal
+}
+
+// bodyInline is the number of forms kept on the line of the opening parenthesis of the special
+// forms whose body is indented, head included.
+var bodyInline = map[string]int{
+	"break": 2, "def": 2, "fun": 3, "lambda": 2, "let": 2, "loop": 3, "set": 2, "struct": 2, "when": 1,
+}
+
+func (f Formatter) write(w *writer, n *node) {
+	if flat, ok := n.flat(); ok && (f.Width <= 0 || w.column+utf8.RuneCountInString(flat) <= f.Width) {
+		w.write(flat)
+		return
+	}
+
+	switch n.kind {
+	case atom, comment:
+		w.write(n.tok.Literal)
+	case prefixed:
+		w.write(n.prefix())
+		f.writeGlued(w, n.children, w.column)
+	case meta:
+		start := w.column
+		w.write(n.prefix())
+		f.writeGlued(w, n.children, start)
+	case list:
+		f.writeList(w, n)
+	case access:
+		f.write(w, n.children[0])
+		w.write(n.tok.Literal + n.children[1].tok.Literal)
+	}
+}
+
+// writeGlued writes the children of a prefixed or meta node on the same line, except after a line
+// comment.
+func (f Formatter) writeGlued(w *writer, children []*node, indent int) {
+	for i, child := range children {
+		switch {
+		case i == 0:
+		case isLineComment(children[i-1]):
+			w.newline(indent)
+		default:
+			w.write(" ")
+		}
+		f.write(w, child)
+	}
+}
+
+func (f Formatter) writeList(w *writer, n *node) {
+	start := w.column
+	w.write(n.tok.Literal)
+
+	inline, body := 1, false
+	if forms := n.forms(); n.tok.Type == lex.TOKEN_LPAREN && len(forms) > 0 {
+		inline = 2
+		if count, ok := bodyInline[forms[0].tok.Literal]; ok && forms[0].tok.Type == lex.TOKEN_SYMBOL {
+			inline, body = count, true
+		}
+	}
+	if n.pairs {
+		inline = 2
+	}
+
+	indent := w.column
+	if body {
+		indent = start + f.Indent
+	}
+	valueColumn := -1
+	if n.aligned {
+		valueColumn = f.keyWidth(n)
+	}
+
+	form := 0 // Index of the child among the forms.
+	for i, child := range n.children {
+		switch {
+		case i == 0:
+		case child.trailing:
+			w.write(" ")
+		case child.kind == comment || isLineComment(n.children[i-1]) ||
+			(n.pairs && form%2 == 0) || (!n.pairs && form >= inline):
+			if child.blank {
+				w.WriteByte('\n')
+			}
+			w.newline(indent)
+		case n.pairs: // Value on the line of its key.
+			w.write(" ")
+			if valueColumn >= 0 {
+				w.write(strings.Repeat(" ", max(0, indent+valueColumn-w.column)))
+			}
+		default:
+			w.write(" ")
+			if form == 1 && !body {
+				indent = w.column
+			}
+		}
+
+		f.write(w, child)
+		if child.kind != comment {
+			form++
+		}
+	}
+
+	if len(n.children) > 0 && isLineComment(n.children[len(n.children)-1]) {
+		w.newline(indent)
+	}
+	w.write(n.closer)
+}
+
+// keyWidth returns the column (relative to the first key) where the values of a list of pairs are
+// aligned, or -1 when they cannot be aligned because a key does not fit on a line or the pairs are
+// interleaved with comments.
+func (f Formatter) keyWidth(n *node) int {
+	res := 0
+	for i, child := range n.children {
+		if child.kind == comment {
+			return -1
+		}
+		if i%2 == 1 {
+			continue
+		}
+
+		flat, ok := child.flat()
+		if !ok {
+			return -1
+		}
+		res = max(res, utf8.RuneCountInString(flat)+1)
+	}
+	return res
+}
+
+// isLineComment tells whether a node is a comment that ends its line (; or shebang), as opposed to a
+// block comment.
+func isLineComment(n *node) bool {
+	return n.kind == comment && !strings.HasPrefix(n.tok.Literal, "#|")
+}
@@ -0,0 +1,163 @@
+package format
+
+import (
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var tests = []struct {
+	name      string
+	formatter Formatter
+	input     string
+	expected  string
+}{
+	{
+		name:     "Whitespace",
+		input:    "  (def   x\n 42)\n\n\n\n(print   x)",
+		expected: "(def x 42)\n\n(print x)\n",
+	},
+	{
+		name:     "Literals are kept",
+		input:    "(f 0x2A #\"raw\"# \\space 'a `(b ,c ,@d , @e))",
+		expected: "(f 0x2A #\"raw\"# \\space 'a `(b ,c ,@d , @e))\n",
+	},
+	{
+		name:     "Comments",
+		input:    "#!/usr/bin/env harp\n; Header.\n(f 1 ; one\n 2)\n#| block |#\n(g)",
+		expected: "#!/usr/bin/env harp\n; Header.\n(f 1 ; one\n   2)\n#| block |#\n(g)\n",
+	},
+	{
+		name:     "Comment before a closer",
+		input:    "(f 1\n ; last\n )",
+		expected: "(f 1\n   ; last\n   )\n",
+	},
+	{
+		name:      "Special form body",
+		formatter: Formatter{Indent: 4, Width: 20},
+		input:     "(fun add [a b] (print a b) (+ a b))",
+		expected:  "(fun add [a b]\n    (print a b)\n    (+ a b))\n",
+	},
+	{
+		name:      "Aligned bindings",
+		formatter: Formatter{Indent: 2, Width: 16},
+		input:     "(let [a 1 longer 2] (combine a longer))",
+		expected:  "(let [a      1\n      longer 2]\n  (combine a\n           longer))\n",
+	},
+	{
+		name:      "Broken map",
+		formatter: Formatter{Indent: 2, Width: 16},
+		input:     "(def m {:a 1 :bb \"two\"})",
+		expected:  "(def m\n  {:a 1\n   :bb \"two\"})\n",
+	},
+	{
+		name:      "Field access",
+		formatter: Formatter{Indent: 2, Width: 11},
+		input:     "(set  p.x  (make  a b).y) ; x",
+		expected:  "(set p.x\n  (make a\n        b).y) ; x\n",
+	},
+	{
+		name:      "Broken call",
+		formatter: Formatter{Indent: 2, Width: 16},
+		input:     "(combine first second)",
+		expected:  "(combine first\n         second)\n",
+	},
+	{
+		name:     "Metadata and dispatch",
+		input:    "(def  ^:private x #{1 2})\n#_ (ignored)\n#?(:harp 1\n:default 2)",
+		expected: "(def ^:private x #{1 2})\n#_(ignored)\n#?(:harp 1 :default 2)\n",
+	},
+	{
+		name:     "Comment after a prefix",
+		input:    "(x #_ ; hi\n y #_#| b |# z)\n^ ; meta\n{:a 1} w",
+		expected: "(x #_ ; hi\n      y\n   #_ #| b |# z)\n^ ; meta\n{:a 1} w\n",
+	},
+	{
+		name:      "Multi-line string",
+		formatter: Formatter{Indent: 2},
+		input:     "(print #\"a\nb\"# 1)",
+		expected:  "(print #\"a\nb\"#\n       1)\n",
+	},
+	{
+		name:     "Empty input",
+		input:    "\n\n",
+		expected: "",
+	},
+}
+
+func (tt Formatter) orDefault() Formatter {
+	if tt == (Formatter{}) {
+		return DefaultFormatter
+	}
+	return tt
+}
+
+func TestSource(t *testing.T) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.formatter.orDefault().Source(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	if _, err := Source("(f [1)"); err == nil {
+		t.Error("expected the parse error to be returned")
+	}
+}
+
+// TestIdempotence formats the test cases and the files of the lexer corpus that parse twice,
+// checking that the second pass does not change anything and that the forms are the same as before
+// formatting.
+func TestIdempotence(t *testing.T) {
+	inputs := map[string]string{}
+	for _, tt := range tests {
+		inputs[tt.name] = tt.input
+	}
+
+	paths, _ := filepath.Glob(filepath.Join("..", "lex", "testdata", "*.harp"))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parse.NewParser(lex.NewLexer(string(content))).Parse(); err != nil {
+			continue // The corpus is meant for the lexer.
+		}
+		inputs[filepath.Base(path)] = string(content)
+	}
+
+	for name, input := range inputs {
+		for _, formatter := range []Formatter{DefaultFormatter, {Indent: 1, Width: 1}} {
+			t.Run(name, func(t *testing.T) {
+				once, err := formatter.Source(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				twice, err := formatter.Source(once)
+				if err != nil {
+					t.Fatalf("formatted source does not parse: %s\n%s", err, once)
+				}
+				if twice != once {
+					t.Errorf("formatting is not idempotent:\n%s\nthen:\n%s", once, twice)
+				}
+
+				// The canonical rendering of forms ignores their positions.
+				before, _ := parse.NewParser(lex.NewLexer(input)).Parse()
+				after, _ := parse.NewParser(lex.NewLexer(once)).Parse()
+				if ast.PrintAll(after) != ast.PrintAll(before) {
+					t.Errorf("formatting changed the forms:\n%s\nbecame:\n%s", ast.PrintAll(before), ast.PrintAll(after))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Package gen builds Harp code from Go, so that tools can generate configurations and scripts
+// without templating source code by hand:
+//
+//	src, err := gen.Print(gen.Call("map", gen.Sym("inc"), gen.Vec(1, 2, 3)))
+//
+// Builders return syntax trees, which can be mixed with trees of the ast package. Their arguments
+// are either expressions or Go values converted by Value, so that Go numbers, strings and slices
+// can be used directly. Print writes the trees as source code and checks that the code reads back
+// as the same trees, which catches the names that are not valid symbols or keywords.
+package gen
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+////////////
+// Errors //
+////////////
+
+// Failure describes why generated code cannot be printed.
+// It can be followed by additional information specified after `: `.
+type Failure string
+
+func (f Failure) Error() string {
+	return string(f)
+}
+
+// Is makes errors.Is compare failures by kind, ignoring the additional information.
+func (f Failure) Is(target error) bool {
+	other, ok := target.(Failure)
+	return ok && f.Cause() == other.Cause()
+}
+
+func (f Failure) Cause() string {
+	cause, _, _ := strings.Cut(string(f), ": ")
+	return cause
+}
+
+const (
+	// Unreadable means that the printed code does not read back, e.g. because of a symbol with a
+	// space.
+	Unreadable Failure = "generated code cannot be read"
+	// Ambiguous means that the printed code reads back as another tree, e.g. a symbol named 1.
+	Ambiguous Failure = "generated code reads as another tree"
+)
+
+// with adds the details of the failure.
+func (f Failure) with(format string, args ...any) Failure {
+	return Failure(string(f) + ": " + fmt.Sprintf(format, args...))
+}
+
+///////////
+// Atoms //
+///////////
+
+// Sym builds a symbol.
+func Sym(name string) ast.Symbol {
+	return ast.Symbol{Name: name}
+}
+
+// Kw builds a keyword, name being written without its colon.
+func Kw(name string) ast.Keyword {
+	return ast.Keyword{Name: name}
+}
+
+// Nil builds nil.
+func Nil() ast.Symbol {
+	return Sym("nil")
+}
+
+// Str builds a string.
+func Str(value string) ast.String {
+	return ast.String{Value: value}
+}
+
+// Bool builds a boolean.
+func Bool(value bool) ast.Bool {
+	return ast.Bool{Value: value}
+}
+
+// Char builds a character.
+func Char(value rune) ast.Rune {
+	return ast.Rune{Value: value}
+}
+
+// Int builds an integer. Negative integers have no literal syntax, so they are built as a negation,
+// e.g. (- 1).
+func Int(value int64) ast.Expr {
+	switch {
+	case value == math.MinInt64: // Its opposite does not fit in an int64.
+		return Call("-", ast.BigInt{Value: new(big.Int).Neg(big.NewInt(value))})
+	case value < 0:
+		return Call("-", ast.Int64{Value: -value})
+	}
+	return ast.Int64{Value: value}
+}
+
+// Float builds a float. Negative and non-finite floats have no literal syntax, so they are built as
+// arithmetic, e.g. (- 1.5) or (/ 1.0 0). The negative zero is built as 0.0.
+func Float(value float64) ast.Expr {
+	switch {
+	case math.IsNaN(value):
+		return Call("-", Float(math.Inf(1)), Float(math.Inf(1)))
+	case math.IsInf(value, 1):
+		return Call("/", 1.0, 0)
+	case value == 0:
+		return ast.Float64{Value: 0}
+	case value < 0:
+		return Call("-", Float(-value))
+	}
+	return ast.Float64{Value: value}
+}
+
+// Value converts a Go value to an expression:
+//   - expressions are kept as is,
+//   - nil, booleans, integers, floats and strings become the corresponding atoms, except that
+//     runes (int32) become characters,
+//   - *big.Int values become integers,
+//   - slices and arrays become arrays, maps become maps with their entries sorted by key.
+//
+// Value panics on other types, like the builders given one of them.
+func Value(value any) ast.Expr {
+	switch value := value.(type) {
+	case ast.Expr:
+		return value
+	case nil:
+		return Nil()
+	case bool:
+		return Bool(value)
+	case rune:
+		return Char(value)
+	case string:
+		return Str(value)
+	case *big.Int:
+		if value.IsInt64() {
+			return Int(value.Int64())
+		}
+		if value.Sign() < 0 {
+			return Call("-", ast.BigInt{Value: new(big.Int).Neg(value)})
+		}
+		return ast.BigInt{Value: new(big.Int).Set(value)}
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return Value(new(big.Int).SetUint64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return Float(v.Float())
+	case reflect.Slice, reflect.Array:
+		elements := make([]any, v.Len())
+		for i := range elements {
+			elements[i] = v.Index(i).Interface()
+		}
+		return Vec(elements...)
+	case reflect.Map:
+		entries := []ast.Entry{}
+		for iter := v.MapRange(); iter.Next(); {
+			key, value := iter.Key().Interface(), iter.Value().Interface()
+			entries = append(entries, ast.Entry{Key: Value(key), Value: Value(value)})
+		}
+		// Go maps are unordered, sorting keeps the generated code stable.
+		slices.SortFunc(entries, func(a, b ast.Entry) int {
+			return cmp.Compare(ast.Print(a.Key), ast.Print(b.Key))
+		})
+		return ast.Map{Entries: entries}
+	}
+	panic(fmt.Sprintf("gen: cannot convert %T to an expression", value))
+}
+
+// values converts Go values with Value.
+func values(values []any) []ast.Expr {
+	res := make([]ast.Expr, len(values))
+	for i, value := range values {
+		res[i] = Value(value)
+	}
+	return res
+}
+
+/////////////////
+// Collections //
+/////////////////
+
+// Vec builds an array.
+func Vec(elements ...any) ast.Array {
+	return ast.Array{Elements: values(elements)}
+}
+
+// Map builds a map from alternating keys and values, in this order. It panics when a key has no
+// value.
+func Map(pairs ...any) ast.Map {
+	if len(pairs)%2 != 0 {
+		panic("gen: map with a key without value")
+	}
+	res := ast.Map{Entries: []ast.Entry{}}
+	for i := 0; i < len(pairs); i += 2 {
+		entry := ast.Entry{Key: Value(pairs[i]), Value: Value(pairs[i+1])}
+		res.Entries = append(res.Entries, entry)
+	}
+	return res
+}
+
+// Set builds a set.
+func Set(elements ...any) ast.Set {
+	return ast.Set{Elements: values(elements)}
+}
+
+///////////
+// Forms //
+///////////
+
+// Call builds a call of function, which is a symbol when given as a string.
+func Call(function any, args ...any) ast.Call {
+	if name, ok := function.(string); ok {
+		function = Sym(name)
+	}
+	return ast.Call{Function: Value(function), Arguments: values(args)}
+}
+
+// Quote builds a quoted form, 'form.
+func Quote(form any) ast.Quote {
+	return ast.Quote{Form: Value(form)}
+}
+
+// Def builds a global definition, (def name value).
+func Def(name string, value any) ast.Def {
+	return ast.Def{Name: Sym(name), Value: Value(value)}
+}
+
+// Assign builds an assignment, (set name value).
+func Assign(name string, value any) ast.Assign {
+	return ast.Assign{Target: Sym(name), Value: Value(value)}
+}
+
+// Fun builds a function definition, (fun name [parameters] body...). Like in Harp, a parameter
+// named & introduces the rest parameter, e.g. []string{"a", "&", "more"}.
+func Fun(name string, parameters []string, body ...any) ast.Fun {
+	params, rest := signature(parameters)
+	return ast.Fun{Name: Sym(name), Parameters: params, Rest: rest, Body: values(body)}
+}
+
+// Lambda builds an anonymous function, (lambda [parameters] body...), see Fun for the parameters.
+func Lambda(parameters []string, body ...any) ast.Lambda {
+	params, rest := signature(parameters)
+	return ast.Lambda{Parameters: params, Rest: rest, Body: values(body)}
+}
+
+// signature splits parameters at &.
+func signature(parameters []string) ([]ast.Symbol, *ast.Symbol) {
+	res := []ast.Symbol{}
+	for i, name := range parameters {
+		if name == "&" && i == len(parameters)-2 {
+			rest := Sym(parameters[i+1])
+			return res, &rest
+		}
+		res = append(res, Sym(name))
+	}
+	return res, nil
+}
+
+// Bind builds a binding of let, to be given to Let.
+func Bind(name string, value any) ast.Binding {
+	return ast.Binding{Variable: Sym(name), Value: Value(value)}
+}
+
+// Let builds local definitions, (let [bindings] body...).
+func Let(bindings []ast.Binding, body ...any) ast.Let {
+	return ast.Let{Bindings: append([]ast.Binding{}, bindings...), Body: values(body)}
+}
+
+//////////////
+// Printing //
+//////////////
+
+// Print writes forms as source code, one per line, and checks that the code reads back as the same
+// forms.
+func Print(forms ...ast.Expr) (string, error) {
+	res := ast.PrintAll(forms)
+	parsed, err := parse.NewParser(lex.NewLexer(res)).Parse()
+	if err != nil {
+		return "", Unreadable.with("%s", err)
+	}
+	if diffs := ast.DiffAll(forms, parsed); len(diffs) > 0 {
+		return "", Ambiguous.with("%s", diffs[0])
+	}
+	return res, nil
+}
@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"mooss/harp/ast"
+	"mooss/harp/eval"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	tests := []struct {
+		name     string
+		forms    []ast.Expr
+		expected string
+	}{
+		{"Call", []ast.Expr{Call("map", Sym("inc"), Vec(1, 2, 3))}, "(map inc [1 2 3])"},
+		{"Atoms", []ast.Expr{Vec(nil, true, 'a', "a\"b", 1.5, Kw("k"), huge)},
+			`[nil true \a "a\"b" 1.5 :k 100000000000000000000]`},
+		{"Negative numbers", []ast.Expr{Vec(-1, -2.5, math.MinInt64)},
+			"[(- 1) (- 2.5) (- 9223372036854775808)]"},
+		{"Non-finite floats", []ast.Expr{Vec(math.Inf(1), math.Inf(-1), math.NaN())},
+			"[(/ 1.0 0) (- (/ 1.0 0)) (- (/ 1.0 0) (/ 1.0 0))]"},
+		{"Go collections", []ast.Expr{Vec([]int{1, 2}, map[string]int{"b": 2, "a": 1})},
+			`[[1 2] {"a" 1 "b" 2}]`},
+		{"Collections", []ast.Expr{Vec(Map(Kw("a"), 1), Set(1, 2), Quote(Sym("x")))},
+			"[{:a 1} #{1 2} 'x]"},
+		{"Definitions", []ast.Expr{
+			Def("x", 1),
+			Fun("f", []string{"a", "&", "more"}, Call("+", Sym("a"), Sym("x"))),
+			Assign("x", Call(Lambda([]string{"y"}, Sym("y")), 2)),
+		}, "(def x 1)\n(fun f [a & more] (+ a x))\n(set x ((lambda [y] y) 2))"},
+		{"Let", []ast.Expr{
+			Let([]ast.Binding{Bind("a", 1), Bind("b", 2)}, Call("+", Sym("a"), Sym("b"))),
+		},
+			"(let [a 1 b 2] (+ a b))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Print(tt.forms...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if strings.TrimSpace(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// mustParse parses the input, which must be valid.
+func mustParse(t *testing.T, input string) []ast.Expr {
+	t.Helper()
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	return forms
+}
+
+func TestValues(t *testing.T) {
+	// The generated code evaluates to the Go values it was generated from.
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"Negative int", -3, "-3"},
+		{"Smallest int", int64(math.MinInt64), "-9223372036854775808"},
+		{"Largest uint", uint64(math.MaxUint64), "18446744073709551615"},
+		{"Negative float", -0.25, "-0.25"},
+		{"Infinities", []float64{math.Inf(1), math.Inf(-1)}, "[+Inf -Inf]"},
+		{"Nested", []any{"a", []any{true, nil}}, `["a" [true nil]]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := Print(Value(tt.value))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			got, err := eval.EvalAll(mustParse(t, src), eval.NewGlobalEnvironment())
+			if err != nil {
+				t.Fatalf("unexpected error evaluating %s: %s", src, err)
+			}
+
+			if eval.Repr(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, eval.Repr(got))
+			}
+		})
+	}
+}
+
+func TestPrintErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     ast.Expr
+		expected Failure
+	}{
+		{"Symbol with a space", Call("f", Sym("a b")), Ambiguous},
+		{"Symbol read as a number", Sym("1"), Ambiguous},
+		{"Empty keyword", Vec(Kw("")), Unreadable},
+		{"Unterminated call", Sym("f("), Unreadable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Print(tt.form)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPanics(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func()
+	}{
+		{"Unsupported type", func() { Value(struct{}{}) }},
+		{"Key without value", func() { Map(1, 2, 3) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic")
+				}
+			}()
+			tt.build()
+		})
+	}
+}
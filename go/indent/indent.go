@@ -0,0 +1,131 @@
+// Package indent computes the suggested indentation of lines of Harp source code.
+// It only relies on the token stream, so it works on incomplete or invalid input, which is what an
+// editor usually holds while the user is typing.
+package indent
+
+import (
+	"mooss/harp/lex"
+	"strings"
+)
+
+// bodyForms are the special forms whose remaining lines are indented by two columns relative to
+// their opening parenthesis instead of being aligned with their first argument.
+var bodyForms = map[string]bool{
+	"def":    true,
+	"fun":    true,
+	"lambda": true,
+	"let":    true,
+	"loop":   true,
+	"struct": true,
+	"when":   true,
+}
+
+// opener is a delimiter that is still open at the line being indented.
+type opener struct {
+	// delimiter is the opening token.
+	delimiter lex.Token
+
+	// forms is the number of forms started inside the delimiter.
+	forms int
+
+	// head is the symbol in head position, if any (only meaningful for parentheses).
+	head lex.Token
+
+	// firstArg is the column of the first argument when it is on the same line as the head, -1
+	// otherwise.
+	firstArg int
+}
+
+// add registers a form starting with tok inside the delimiter.
+func (op *opener) add(tok lex.Token) {
+	op.forms++
+	switch op.forms {
+	case 1:
+		op.head = tok
+	case 2:
+		if op.head.Type == lex.TOKEN_SYMBOL && op.head.Line == tok.Line {
+			op.firstArg = tok.Column
+		}
+	}
+}
+
+// At returns the suggested indentation (in columns) of the given line (starting at 1) of input.
+//
+// Forms inside brackets and braces are aligned right after the delimiter.
+// Inside parentheses, the body of special forms is indented by two columns, arguments are aligned
+// with the first argument when it is on the same line as the function and one column after the
+// parenthesis otherwise.
+// A line starting with a closing delimiter is aligned with the matching opening delimiter.
+// A line inside a raw string, a heredoc or a block comment keeps its indentation, since it is a part
+// of the token.
+func At(input string, line int) int {
+	stack := []*opener{}
+	closing := false
+
+	for tok, err := range lex.NewLexer(input).Tokens() {
+		if err != nil && tok.Type == lex.TOKEN_INVALID {
+			continue
+		}
+
+		if tok.Type == lex.TOKEN_EOF || tok.Line > line {
+			break
+		}
+		if tok.Line == line {
+			closing = isCloser(tok.Type)
+			break
+		}
+		multiline := tok.Type == lex.TOKEN_RAWSTRING || tok.Type == lex.TOKEN_HEREDOC ||
+			tok.Type == lex.TOKEN_COMMENT // Block comments.
+		if multiline && tok.Line+strings.Count(tok.Literal, "\n") >= line {
+			return currentIndentation(input, line)
+		}
+
+		switch {
+		case tok.Type == lex.TOKEN_COMMENT:
+		case isCloser(tok.Type):
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			if len(stack) > 0 {
+				stack[len(stack)-1].add(tok)
+			}
+			if isOpener(tok.Type) {
+				stack = append(stack, &opener{delimiter: tok, firstArg: -1})
+			}
+		}
+	}
+
+	if len(stack) == 0 {
+		return 0
+	}
+
+	top := stack[len(stack)-1]
+	switch {
+	case closing:
+		return top.delimiter.Column
+	case top.delimiter.Type != lex.TOKEN_LPAREN:
+		return top.delimiter.Column + len(top.delimiter.Literal)
+	case top.head.Type == lex.TOKEN_SYMBOL && bodyForms[top.head.Literal]:
+		return top.delimiter.Column + 2
+	case top.firstArg >= 0:
+		return top.firstArg
+	}
+
+	return top.delimiter.Column + 1
+}
+
+// currentIndentation returns the number of spaces and tabs at the start of the given line.
+func currentIndentation(input string, line int) int {
+	text := strings.Split(input, "\n")[line-1]
+	return len(text) - len(strings.TrimLeft(text, " \t"))
+}
+
+func isOpener(typ lex.TokenType) bool {
+	return typ == lex.TOKEN_LPAREN || typ == lex.TOKEN_LBRACKET || typ == lex.TOKEN_LBRACE ||
+		typ == lex.TOKEN_SET
+}
+
+func isCloser(typ lex.TokenType) bool {
+	return typ == lex.TOKEN_RPAREN || typ == lex.TOKEN_RBRACKET || typ == lex.TOKEN_RBRACE
+}
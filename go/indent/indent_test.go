@@ -0,0 +1,125 @@
+package indent
+
+import (
+	"testing"
+)
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		line     int
+		expected int
+	}{
+		{
+			name:     "Top level",
+			input:    "(f x)\n",
+			line:     2,
+			expected: 0,
+		},
+		{
+			name:     "First line",
+			input:    "(f x",
+			line:     1,
+			expected: 0,
+		},
+		{
+			name:     "Aligned with first argument",
+			input:    "(add 1\n2)",
+			line:     2,
+			expected: 5,
+		},
+		{
+			name:     "No argument on the head line",
+			input:    "(add\n1 2)",
+			line:     2,
+			expected: 1,
+		},
+		{
+			name:     "Special form body",
+			input:    "(fun inc [x]\n(add x 1))",
+			line:     2,
+			expected: 2,
+		},
+		{
+			name:     "Nested special form body",
+			input:    "(def f\n  (lambda [x]\n(g x)))",
+			line:     3,
+			expected: 4,
+		},
+		{
+			name:     "Inside brackets",
+			input:    "[1 2\n3]",
+			line:     2,
+			expected: 1,
+		},
+		{
+			name:     "Inside a set",
+			input:    "(f #{1 2\n3})",
+			line:     2,
+			expected: 5,
+		},
+		{
+			name:     "Inside a block comment",
+			input:    "(f #| a\n     b |#\n1)",
+			line:     2,
+			expected: 5,
+		},
+		{
+			name:     "Inside braces after a closed form",
+			input:    "(f {a (g 1)\nb 2})",
+			line:     2,
+			expected: 4,
+		},
+		{
+			name:     "Closing delimiter",
+			input:    "(let [x 1\n      y 2\n]",
+			line:     3,
+			expected: 5,
+		},
+		{
+			name:     "Comments and strings do not count",
+			input:    "(f \"(\" ; (\n1)",
+			line:     2,
+			expected: 3,
+		},
+		{
+			name:     "Invalid characters are ignored",
+			input:    "(f $ 1\n2)",
+			line:     2,
+			expected: 5,
+		},
+		{
+			name:     "Unbalanced closers",
+			input:    "))\n(f",
+			line:     3,
+			expected: 1,
+		},
+		{
+			name:     "Inside a raw string",
+			input:    "(f #\"a\n   b\"#\nc)",
+			line:     2,
+			expected: 3,
+		},
+		{
+			name:     "Inside a heredoc",
+			input:    "(f <<END\n     a\nEND\nc)",
+			line:     2,
+			expected: 5,
+		},
+		{
+			name:     "After a raw string",
+			input:    "(f #\"a\n   b\"#\nc)",
+			line:     3,
+			expected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := At(tt.input, tt.line); got != tt.expected {
+				t.Errorf("expected indentation %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package lex
+
+// Unbalanced returns true when input ends while a delimiter, a string or a block comment is still
+// open, that is to say when more input is needed to complete it (e.g. when a REPL must read another
+// line).
+//
+// Input with extra closing delimiters or with lexical errors (other than a string or a comment
+// reaching EOF) is never unbalanced, because reading more input cannot fix it.
+func Unbalanced(input string) bool {
+	depth := 0
+	for tok, err := range NewLexer(input).Tokens() {
+		if err != nil {
+			return err.Reason.Same(EofInString) || err.Reason.Same(EofInComment)
+		}
+
+		switch tok.Type {
+		case TOKEN_LPAREN, TOKEN_LBRACKET, TOKEN_LBRACE, TOKEN_SET:
+			depth++
+		case TOKEN_RPAREN, TOKEN_RBRACKET, TOKEN_RBRACE:
+			depth--
+		}
+	}
+	return depth > 0
+}
@@ -0,0 +1,43 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestUnbalanced(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"Empty input", "", false},
+		{"Atom", "x", false},
+		{"Closed form", "(f [1] {a 2})", false},
+		{"Open parenthesis", "(let [x 1]\n", true},
+		{"Open bracket", "(f [1\n2", true},
+		{"Open brace", "{a", true},
+		{"Unterminated string", `(f "abc`, true},
+		{"Delimiters in strings", `"(["`, false},
+		{"Unterminated raw string", "(f #\"abc\"\n", true},
+		{"Closed raw string", "(f #\"(\n\"#)", false},
+		{"Unterminated heredoc", "(f <<END\n(", true},
+		{"Closed heredoc", "(f <<END\n(\nEND)", false},
+		{"Delimiters in comments", "1 ; (", false},
+		{"Open set", "#{a", true},
+		{"Unterminated block comment", "#| a\n", true},
+		{"Closed block comment", "#| ( |#", false},
+		{"Comment inside an open form", "(f ; )\n", true},
+		{"Extra closer", "(f))", false},
+		{"Lexical error in an open form", "(f 1.2.3", false},
+		{"Newline in string", "(f \"a\nb\"", false},
+		{"Lexical error in a closed form", "(f 1.2.3)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unbalanced(tt.input); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package lex
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character literals start with a backslash followed by either the character itself (\a, \( or \λ),
+// the name of a character in charNames (\newline) or a code point written \uHHHH (λ).
+
+const (
+	EmptyChar     LexicalFailure = "met backslash without character"
+	MultiRuneChar LexicalFailure = "met several characters in a character literal"
+)
+
+// charNames are the characters written by name, mostly because they are invisible.
+var charNames = map[string]rune{
+	"newline":   '\n',
+	"space":     ' ',
+	"tab":       '\t',
+	"return":    '\r',
+	"backspace": '\b',
+	"formfeed":  '\f',
+}
+
+// DecodeChar returns the character represented by a character literal, as produced by the lexer in
+// TOKEN_CHAR tokens. The failure is empty when the literal is valid.
+func DecodeChar(literal string) (rune, LexicalFailure) {
+	if len(literal) < 2 || literal[0] != '\\' {
+		return 0, EmptyChar
+	}
+
+	name := literal[1:]
+	if run, size := utf8.DecodeRuneInString(name); size == len(name) {
+		return run, ""
+	}
+	if run, ok := charNames[name]; ok {
+		return run, ""
+	}
+
+	if len(name) == 5 && name[0] == 'u' {
+		if value, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			if !utf8.ValidRune(rune(value)) {
+				return 0, InvalidCodepoint.WithStrhex(name)
+			}
+			return rune(value), ""
+		}
+	}
+
+	return 0, MultiRuneChar.WithStrhex(name)
+}
+
+// EncodeChar returns the literal representing a character, the reverse of DecodeChar.
+func EncodeChar(run rune) string {
+	for name, value := range charNames {
+		if value == run {
+			return `\` + name
+		}
+	}
+
+	if unicode.IsGraphic(run) && !unicode.IsSpace(run) {
+		return `\` + string(run)
+	}
+	return fmt.Sprintf(`\u%04X`, run)
+}
+
+// readChar reads a character literal, whose validity is checked by DecodeChar.
+func readChar(lex *Lexer, tok *Token) LexicalFailure {
+	start := lex.currentPosition
+	lex.forward() // Consume backslash.
+
+	switch lex.current {
+	case eof, ' ', '\t', '\r', '\n':
+		return EmptyChar
+	}
+
+	// The first character is always a part of the literal, even if it is a delimiter.
+	lex.forward()
+	for !lex.isStoprune(lex.current) {
+		lex.forward()
+	}
+
+	_, fail := DecodeChar(lex.input[start:lex.currentPosition])
+	return fail
+}
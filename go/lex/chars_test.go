@@ -0,0 +1,33 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestEncodeChar(t *testing.T) {
+	tests := []struct {
+		char     rune
+		expected string
+	}{
+		{'a', `\a`},
+		{'(', `\(`},
+		{'λ', `\λ`},
+		{'\n', `\newline`},
+		{' ', `\space`},
+		{'\u00a0', `\u00A0`},
+		{0, `\u0000`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			got := EncodeChar(tt.char)
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+
+			if back, fail := DecodeChar(got); back != tt.char || fail != "" {
+				t.Errorf("expected %s to decode to %q, got %q (%s)", got, tt.char, back, fail)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package lex
+
+// Reader extensions are introduced by # followed by a dispatch character, so that new syntax does
+// not take over runes that could otherwise start symbols:
+//
+//	#"raw string"#   raw string, where backslashes are not escapes
+//	#{a b c}         set literal
+//	#| comment |#    block comment, which can be nested
+//	#_ form          discarded form, e.g. to comment out a whole expression
+//	#?(:harp a :default b)
+//	                 reader conditional, selecting a form by feature
+//
+// Every extension is registered in the dispatch table. The shebang line (#! at the very start of
+// the input) is not, since its meaning depends on its position.
+
+const EofInComment LexicalFailure = "met EOF while reading block comment"
+
+// dispatch maps the character following # to the reader of the token it starts.
+// Entries of type TOKEN_COMMENT are skipped along with the other comments.
+var dispatch = map[rune]struct {
+	read reader
+	typ  TokenType
+}{
+	'"': {readRawString, TOKEN_RAWSTRING},
+	'{': {readPair, TOKEN_SET},
+	'|': {readBlockComment, TOKEN_COMMENT},
+	'_': {readPair, TOKEN_DISCARD},
+	'?': {readPair, TOKEN_CONDITIONAL},
+}
+
+// readBlockComment reads a comment delimited by #| and |#, which can contain nested block comments
+// and span several lines.
+func readBlockComment(lex *Lexer, tok *Token) LexicalFailure {
+	depth := 0
+	for {
+		switch {
+		case lex.current == eof:
+			return EofInComment
+		case lex.atNewline():
+			lex.nextLine()
+		case lex.current == '#' && lex.peekChar() == '|':
+			lex.forward()
+			depth++
+		case lex.current == '|' && lex.peekChar() == '#':
+			lex.forward()
+			depth--
+			if depth == 0 {
+				lex.forward()
+				return ""
+			}
+		}
+
+		lex.forward()
+	}
+}
@@ -0,0 +1,149 @@
+package lex
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	UnquotedString   LexicalFailure = "met string literal without surrounding double quotes"
+	UnknownEscape    LexicalFailure = "met unknown escape sequence"
+	IncompleteEscape LexicalFailure = "met escape sequence with missing digits"
+	InvalidCodepoint LexicalFailure = "met escape sequence outside of the unicode range"
+)
+
+// EscapeError is an invalid escape sequence found while decoding a string literal.
+type EscapeError struct {
+	// Column is the position of the backslash, in runes from the start of the literal.
+	Column int
+	// Offset is the position of the backslash, in bytes from the start of the literal.
+	Offset int
+	// Sequence is the invalid escape sequence, as written in the literal.
+	Sequence string
+	Reason   LexicalFailure
+}
+
+func (ee *EscapeError) Error() string {
+	return fmt.Sprintf("%s at column %d of the literal: %q", ee.Reason, ee.Column, ee.Sequence)
+}
+
+// simpleEscapes maps the character following a backslash to the character it represents.
+var simpleEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '"': '"',
+}
+
+// DecodeString interprets the escape sequences of a double quoted string literal, as produced by
+// the lexer in TOKEN_DQSTRING tokens, and returns the string it represents without the quotes.
+//
+// The supported sequences are the one-character escapes \a \b \f \n \r \t \v \\ \", bytes written
+// as \xHH (two hexadecimal digits) or \OOO (three octal digits), and code points written as \uHHHH
+// or \UHHHHHHHH.
+// Invalid sequences are reported with an *EscapeError.
+func DecodeString(literal string) (string, error) {
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return "", &EscapeError{Sequence: literal, Reason: UnquotedString}
+	}
+
+	body := literal[1 : len(literal)-1]
+	if !strings.ContainsRune(body, '\\') { // Fast path for the common case.
+		return body, nil
+	}
+
+	res := strings.Builder{}
+	res.Grow(len(body))
+	for i := 0; i < len(body); {
+		if body[i] != '\\' {
+			res.WriteByte(body[i])
+			i++
+			continue
+		}
+
+		width, err := decodeEscape(&res, body[i:])
+		if err != nil {
+			err.Column = 1 + utf8.RuneCountInString(body[:i]) // 1 for the opening quote.
+			err.Offset = 1 + i
+			return "", err
+		}
+		i += width
+	}
+
+	return res.String(), nil
+}
+
+// decodeEscape writes the value of the escape sequence at the start of seq and returns its width.
+// The column and offset of the returned error are set by the caller.
+func decodeEscape(res *strings.Builder, seq string) (int, *EscapeError) {
+	fail := func(width int, reason LexicalFailure) (int, *EscapeError) {
+		return 0, &EscapeError{Sequence: seq[:min(width, len(seq))], Reason: reason}
+	}
+	if len(seq) < 2 {
+		return fail(len(seq), IncompleteEscape)
+	}
+
+	if value, ok := simpleEscapes[seq[1]]; ok {
+		res.WriteByte(value)
+		return 2, nil
+	}
+
+	base, digits := 16, 0
+	switch seq[1] {
+	case 'x':
+		digits = 2
+	case 'u':
+		digits = 4
+	case 'U':
+		digits = 8
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		base, digits = 8, 3
+	default:
+		_, size := utf8.DecodeRuneInString(seq[1:])
+		return fail(1+size, UnknownEscape)
+	}
+
+	start := 2
+	if base == 8 {
+		start = 1 // The first digit is the character after the backslash.
+	}
+	width := start + digits
+
+	value := 0
+	for i := start; i < width; i++ {
+		if i >= len(seq) {
+			return fail(i, IncompleteEscape)
+		}
+		digit, ok := digitValue(seq[i], base)
+		if !ok {
+			return fail(i, IncompleteEscape)
+		}
+		value = value*base + digit
+	}
+
+	switch {
+	case seq[1] == 'u' || seq[1] == 'U':
+		if !utf8.ValidRune(rune(value)) {
+			return fail(width, InvalidCodepoint)
+		}
+		res.WriteRune(rune(value))
+	case value > 255: // Octal bytes above \377.
+		return fail(width, InvalidCodepoint)
+	default:
+		res.WriteByte(byte(value))
+	}
+	return width, nil
+}
+
+// digitValue returns the value of an ASCII digit in the given base (8 or 16).
+func digitValue(char byte, base int) (int, bool) {
+	res := -1
+	switch {
+	case '0' <= char && char <= '9':
+		res = int(char - '0')
+	case 'a' <= char && char <= 'f':
+		res = int(char-'a') + 10
+	case 'A' <= char && char <= 'F':
+		res = int(char-'A') + 10
+	}
+	return res, 0 <= res && res < base
+}
@@ -0,0 +1,73 @@
+package lex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		literal  string
+		expected string
+	}{
+		{"No escape", `"hello"`, "hello"},
+		{"Empty", `""`, ""},
+		{"Simple escapes", `"\a\b\f\n\r\t\v\\\""`, "\a\b\f\n\r\t\v\\\""},
+		{"Hexadecimal byte", `"\x41\x7a"`, "Az"},
+		{"Octal byte", `"\040\101"`, " A"},
+		{"Code points", `"λ \U0001F600"`, "λ 😀"},
+		{"Unicode around escapes", `"é\té"`, "é\té"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeString(tt.literal)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeStringErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		literal  string
+		column   int
+		sequence string
+		reason   LexicalFailure
+	}{
+		{"Unknown escape", `"ab\q"`, 3, `\q`, UnknownEscape},
+		{"Unknown escape after unicode", `"λ\é"`, 2, `\é`, UnknownEscape},
+		{"Missing hexadecimal digit", `"\x4"`, 1, `\x4`, IncompleteEscape},
+		{"Invalid hexadecimal digit", `"\x4g"`, 1, `\x4`, IncompleteEscape},
+		{"Missing octal digits", `"\0"`, 1, `\0`, IncompleteEscape},
+		{"Octal above a byte", `"\400"`, 1, `\400`, InvalidCodepoint},
+		{"Surrogate", `"\uD800"`, 1, `\uD800`, InvalidCodepoint},
+		{"Beyond unicode", `"\U00110000"`, 1, `\U00110000`, InvalidCodepoint},
+		{"Trailing backslash", `"a\"`, 2, `\`, IncompleteEscape},
+		{"No quotes", `abc`, 0, `abc`, UnquotedString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeString(tt.literal)
+			eerr, ok := err.(*EscapeError)
+			if !ok {
+				t.Fatalf("expected an escape error, got: %v", err)
+			}
+
+			if eerr.Column != tt.column || eerr.Sequence != tt.sequence || !eerr.Reason.Same(tt.reason) {
+				t.Errorf("expected %q at column %d (%s), got %q at column %d (%s)",
+					tt.sequence, tt.column, tt.reason, eerr.Sequence, eerr.Column, eerr.Reason)
+			}
+			if !strings.HasPrefix(tt.literal[eerr.Offset:], eerr.Sequence) {
+				t.Errorf("offset %d does not point to %q", eerr.Offset, eerr.Sequence)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package lex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// FuzzLexer checks the invariants of the lexer on arbitrary inputs:
+//   - it never panics and always reaches EOF,
+//   - every token but EOF consumes input, and tokens are in order without overlapping,
+//   - only whitespace is skipped between tokens, so that the input can be rebuilt from them.
+func FuzzLexer(f *testing.F) {
+	seeds := []string{
+		"", "(f [1 2.5] {:a \"b\"})", "#!/usr/bin/env harp\n; Comment.\n(def x 0xff)",
+		`"\x41é" #"raw"# \newline \a`, "`(f ,a ,@b)", "<<~END\n  text\n  END", "1.2.3 1e 0x 0b2",
+		"\uFEFF\u200B§ abc\r\ndef\t", "\"unterminated", "(<= a b) empty? set! x.y",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	// The golden corpus provides realistic seeds.
+	paths, _ := filepath.Glob(filepath.Join("testdata", "*.harp"))
+	for _, path := range paths {
+		if input, err := os.ReadFile(path); err == nil {
+			f.Add(string(input))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		lexer := NewLexer(input)
+		var rebuilt strings.Builder
+		end := 0
+
+		// Every token but EOF consumes at least a byte, which bounds the number of tokens.
+		for range len(input) + 1 {
+			tok, err := lexer.NextToken()
+			if err != nil {
+				tok = err.Token
+			}
+
+			if tok.Offset < end || tok.End() > len(input) {
+				t.Fatalf("token %+v is out of order or out of the input (previous end %d)", tok, end)
+			}
+			if input[tok.Offset:tok.End()] != tok.Literal {
+				t.Fatalf("literal of %+v does not match its position", tok)
+			}
+			if gap := input[end:tok.Offset]; strings.Trim(gap, " \t\r\n") != "" {
+				t.Fatalf("non-whitespace %q skipped before %+v", gap, tok)
+			}
+
+			rebuilt.WriteString(input[end:tok.Offset])
+			rebuilt.WriteString(tok.Literal)
+			end = tok.End()
+
+			if tok.Type == TOKEN_EOF {
+				if rebuilt.String() != input {
+					t.Fatalf("tokens rebuild %q", rebuilt.String())
+				}
+				return
+			}
+			if tok.Length == 0 {
+				t.Fatalf("token %+v consumes no input", tok)
+			}
+		}
+
+		t.Fatalf("EOF not reached after %d tokens", len(input)+1)
+	})
+}
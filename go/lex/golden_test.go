@@ -0,0 +1,61 @@
+package lex
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// dump formats the tokens of input one per line, with their position and their failure if any.
+func dump(input string) string {
+	var res strings.Builder
+	for tok, err := range NewLexer(input).Tokens() {
+		fmt.Fprintf(&res, "%d:%d %s %q", tok.Line, tok.Column, tok.Type, tok.Literal)
+		if err != nil {
+			fmt.Fprintf(&res, " ! %s", err.Reason)
+		}
+		res.WriteString("\n")
+	}
+	return res.String()
+}
+
+// TestGolden lexes the Harp files of testdata and compares their tokens with the .tokens golden file
+// next to them. Run `go test ./lex -run TestGolden -update` to rewrite the golden files after a
+// deliberate change, and review the diff.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.harp"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no Harp file found in testdata: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			input, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := dump(string(input))
+			golden := strings.TrimSuffix(path, ".harp") + ".tokens"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s (run with -update to create it)", err)
+			}
+			if got != string(expected) {
+				t.Errorf("tokens differ from %s, run with -update and review the diff:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package lex
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Heredocs embed verbatim text spanning several lines, like SQL queries or shell snippets:
+//
+//	(query <<~SQL
+//	    SELECT name
+//	    FROM users
+//	    SQL)
+//
+// The header is << followed by a tag made of letters, digits and underscores, and nothing else
+// until the end of the line. The text ends at the first line containing only the tag, possibly
+// indented and followed by stoprunes. With <<~, the indentation common to all the non-blank lines
+// of the text is removed.
+
+const (
+	EmptyHeredocTag     LexicalFailure = "met heredoc without tag"
+	TextAfterHeredocTag LexicalFailure = "met text after the tag of a heredoc"
+)
+
+// isHeredocTag returns true when given a rune that can be a part of a heredoc tag.
+func isHeredocTag(run rune) bool {
+	return run == '_' || ('a' <= run && run <= 'z') || ('A' <= run && run <= 'Z') || isDigit(run)
+}
+
+// readHeredoc reads a heredoc up to and including its closing tag.
+// Reaching EOF is reported as EofInString, so that the heredoc is considered unbalanced.
+func readHeredoc(lex *Lexer, tok *Token) LexicalFailure {
+	lex.forward() // Consume first <.
+	lex.forward() // Consume second <.
+	if lex.current == '~' {
+		lex.forward()
+	}
+
+	start := lex.currentPosition
+	for isHeredocTag(lex.current) {
+		lex.forward()
+	}
+	tag := lex.input[start:lex.currentPosition]
+	if tag == "" {
+		return EmptyHeredocTag
+	}
+
+	for lex.current == ' ' || lex.current == '\t' {
+		lex.forward()
+	}
+	if lex.current == '\r' && lex.peekChar() == '\n' {
+		lex.forward() // The header ends with \r\n.
+	}
+	switch {
+	case lex.current == eof:
+		return EofInString
+	case !lex.atNewline():
+		return TextAfterHeredocTag
+	}
+
+	for {
+		// Move to the start of the next line and check whether it closes the heredoc.
+		lex.nextLine()
+		lex.forward()
+		for lex.current == ' ' || lex.current == '\t' {
+			lex.forward()
+		}
+
+		if rest := lex.input[lex.currentPosition:]; strings.HasPrefix(rest, tag) {
+			after, _ := utf8.DecodeRuneInString(rest[len(tag):])
+			if len(rest) == len(tag) || lex.isStoprune(after) {
+				for range tag { // Tags are ASCII.
+					lex.forward()
+				}
+				return ""
+			}
+		}
+
+		for !lex.atNewline() {
+			if lex.current == eof {
+				return EofInString
+			}
+			lex.forward()
+		}
+	}
+}
+
+// HeredocValue returns the text of a heredoc literal validated by the lexer, every line of which ends
+// with a newline. Lines ending with \r\n, or with \r when the header does, end with \n in the text.
+func HeredocValue(literal string) string {
+	first := strings.IndexAny(literal, "\r\n")
+	crNewlines := literal[first] == '\r' && !strings.HasPrefix(literal[first:], "\r\n")
+	if strings.HasPrefix(literal[first:], "\r\n") {
+		first++
+	}
+	last := strings.LastIndexAny(literal, "\r\n")
+	text := strings.ReplaceAll(literal[first+1:last+1], "\r\n", "\n")
+	if crNewlines {
+		text = strings.ReplaceAll(text, "\r", "\n")
+	}
+	if literal[2] != '~' {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	indent := -1
+	for _, line := range lines {
+		content := strings.TrimLeft(line, " \t")
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		if width := len(line) - len(content); indent < 0 || width < indent {
+			indent = width
+		}
+	}
+	if indent <= 0 {
+		return text
+	}
+
+	var res strings.Builder
+	for _, line := range lines {
+		// Blank lines can be less indented than the others.
+		content := strings.TrimLeft(line, " \t")
+		res.WriteString(line[min(indent, len(line)-len(content)):])
+	}
+	return res.String()
+}
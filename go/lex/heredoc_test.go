@@ -0,0 +1,31 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestHeredocValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		literal  string
+		expected string
+	}{
+		{"Verbatim", "<<END\n  a \"b\"\n\\n\n  END", "  a \"b\"\n\\n\n"},
+		{"Empty", "<<END\nEND", ""},
+		{"Stripped indentation", "<<~SQL\n    SELECT *\n\n      FROM t\n    SQL", "SELECT *\n\n  FROM t\n"},
+		{"Stripped blank lines only", "<<~X\n  \nX", "  \n"},
+		{"Tabs", "<<~X\n\t\ta\n\tb\nX", "\ta\nb\n"},
+		{"CRLF", "<<END \r\n  a\r\nb\r\nEND", "  a\nb\n"},
+		{"Stripped CRLF", "<<~END\r\n  a\r\n\r\n  b\r\n  END", "a\n\nb\n"},
+		{"Carriage returns", "<<~END\r  a\r  b\r  END", "a\nb\n"},
+		{"Carriage return in a line", "<<END\na\rb\nEND", "a\rb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HeredocValue(tt.literal); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package lex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONPosition is a position in the input, as represented in JSON token streams.
+type JSONPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// JSONToken is the stable JSON representation of a token, meant for external tools (e.g. editor
+// grammars) checking their output against the reference lexer.
+//
+// End is exclusive, columns are counted in runes like Token.Column and offsets in bytes like
+// Position.Offset.
+// Error and Code are only set when the token could not be lexed, in which case they hold the reason
+// and its code (e.g. LEX0003).
+type JSONToken struct {
+	Type    TokenType    `json:"type"`
+	Literal string       `json:"literal"`
+	Start   JSONPosition `json:"start"`
+	End     JSONPosition `json:"end"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+}
+
+// NewJSONToken builds the JSON representation of a token returned by NextToken or Tokens.
+// When err is not nil, its token is used instead of tok.
+func NewJSONToken(tok Token, err *LexicalError) JSONToken {
+	res := JSONToken{}
+	if err != nil {
+		tok = err.Token
+		res.Error = string(err.Reason)
+		res.Code = err.Reason.Code()
+	}
+
+	res.Type = tok.Type
+	res.Literal = tok.Literal
+	res.Start = JSONPosition{tok.Line, tok.Column, tok.Offset}
+	res.End = JSONPosition{tok.Line, tok.Column + utf8.RuneCountInString(tok.Literal), tok.End()}
+	// Only raw strings, heredocs and block comments can span several lines.
+	if last := strings.LastIndexByte(tok.Literal, '\n'); last >= 0 {
+		res.End.Line += strings.Count(tok.Literal, "\n")
+		res.End.Column = utf8.RuneCountInString(tok.Literal[last+1:])
+	}
+	return res
+}
+
+// JSONTokens lexes the whole input and returns the resulting tokens, EOF included.
+// Lexical errors do not stop the process, they are reported on their tokens.
+func JSONTokens(input string) []JSONToken {
+	res := []JSONToken{}
+	for tok, err := range NewLexer(input).Tokens() {
+		res = append(res, NewJSONToken(tok, err))
+	}
+	return res
+}
+
+// MarshalTokens lexes the whole input and encodes the resulting tokens as a JSON array.
+func MarshalTokens(input string) ([]byte, error) {
+	return json.Marshal(JSONTokens(input))
+}
+
+// MarshalTokenLines lexes the whole input and encodes the resulting tokens as newline-delimited
+// JSON (NDJSON), one object per line, so that tools can process a token stream line by line.
+func MarshalTokenLines(input string) ([]byte, error) {
+	var res bytes.Buffer
+	encoder := json.NewEncoder(&res)
+	for _, tok := range JSONTokens(input) {
+		if err := encoder.Encode(tok); err != nil {
+			return nil, err
+		}
+	}
+	return res.Bytes(), nil
+}
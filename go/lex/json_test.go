@@ -0,0 +1,81 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestMarshalTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "Valid tokens",
+			input: `(f "你好")`,
+			expected: `[{"type":"LPAREN","literal":"(","start":{"line":1,"column":0,"offset":0},` +
+				`"end":{"line":1,"column":1,"offset":1}},` +
+				`{"type":"SYMBOL","literal":"f","start":{"line":1,"column":1,"offset":1},` +
+				`"end":{"line":1,"column":2,"offset":2}},` +
+				`{"type":"STRING","literal":"\"你好\"","start":{"line":1,"column":3,"offset":3},` +
+				`"end":{"line":1,"column":7,"offset":11}},` +
+				`{"type":"RPAREN","literal":")","start":{"line":1,"column":7,"offset":11},` +
+				`"end":{"line":1,"column":8,"offset":12}},` +
+				`{"type":"EOF","literal":"","start":{"line":1,"column":8,"offset":12},` +
+				`"end":{"line":1,"column":8,"offset":12}}]`,
+		},
+		{
+			name:  "Errors are reported on their tokens",
+			input: "1a\n$",
+			expected: `[{"type":"INT","literal":"1","start":{"line":1,"column":0,"offset":0},` +
+				`"end":{"line":1,"column":1,"offset":1},` +
+				`"error":"met non-digit while reading number","code":"LEX0002"},` +
+				`{"type":"SYMBOL","literal":"a","start":{"line":1,"column":1,"offset":1},` +
+				`"end":{"line":1,"column":2,"offset":2}},` +
+				`{"type":"INVALID","literal":"$","start":{"line":2,"column":0,"offset":3},` +
+				`"end":{"line":2,"column":1,"offset":4},` +
+				`"error":"met character that is not a valid token start: string($) hex(24)","code":"LEX0006"},` +
+				`{"type":"EOF","literal":"","start":{"line":2,"column":1,"offset":4},` +
+				`"end":{"line":2,"column":1,"offset":4}}]`,
+		},
+		{
+			name:  "Multi-line raw string",
+			input: "#\"a\nbc\"#",
+			expected: `[{"type":"RAWSTRING","literal":"#\"a\nbc\"#","start":{"line":1,"column":0,"offset":0},` +
+				`"end":{"line":2,"column":4,"offset":8}},` +
+				`{"type":"EOF","literal":"","start":{"line":2,"column":4,"offset":8},` +
+				`"end":{"line":2,"column":4,"offset":8}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalTokens(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if string(got) != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMarshalTokenLines(t *testing.T) {
+	got, err := MarshalTokenLines("x\n$")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `{"type":"SYMBOL","literal":"x","start":{"line":1,"column":0,"offset":0},` +
+		`"end":{"line":1,"column":1,"offset":1}}` + "\n" +
+		`{"type":"INVALID","literal":"$","start":{"line":2,"column":0,"offset":2},` +
+		`"end":{"line":2,"column":1,"offset":3},` +
+		`"error":"met character that is not a valid token start: string($) hex(24)","code":"LEX0006"}` + "\n" +
+		`{"type":"EOF","literal":"","start":{"line":2,"column":1,"offset":3},` +
+		`"end":{"line":2,"column":1,"offset":3}}` + "\n"
+	if string(got) != expected {
+		t.Errorf("expected:\n> %s\ngot:\n> %s", expected, got)
+	}
+}
@@ -2,6 +2,7 @@ package lex
 
 import (
 	"fmt"
+	"iter"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -20,17 +21,43 @@ type LexicalError struct {
 }
 
 func (le LexicalError) Error() string {
-	return fmt.Sprintf(
-		"lexical error at line %d column %d: %s",
-		le.Line, le.Column, le.Reason,
-	)
+	return fmt.Sprintf("lexical error %s: %s", le.Location(), le.Reason)
+}
+
+// Unwrap exposes the reason of the error, so that errors.Is(err, lex.EofInString) holds for any
+// lexical error caused by an unterminated string, however deeply err wraps it.
+func (le LexicalError) Unwrap() error {
+	return le.Reason
 }
 
 // LexicalFailure describes what caused the lexer to fail.
 // It can be followed by additional information specified after `: `.
-// When testing if two lexical failures are of the same kind, use the `Same` method.
+// When testing if two lexical failures are of the same kind, use the `Same` method or errors.Is.
 type LexicalFailure string
 
+func (lf LexicalFailure) Error() string {
+	return string(lf)
+}
+
+// Is makes errors.Is compare lexical failures by kind, ignoring the additional information.
+func (lf LexicalFailure) Is(target error) bool {
+	other, ok := target.(LexicalFailure)
+	return ok && lf.Same(other)
+}
+
+// Code returns the stable identifier of the kind of failure (e.g. LEX0003), or an empty string
+// for an unknown failure.
+func (lf LexicalFailure) Code() string {
+	return lexicalCodes[LexicalFailure(lf.Cause())]
+}
+
+// Detail returns the additional information following the cause, or an empty string when there is
+// none.
+func (lf LexicalFailure) Detail() string {
+	_, detail, _ := strings.Cut(string(lf), ": ")
+	return detail
+}
+
 func (lf LexicalFailure) Cause() string {
 	colon := strings.Index(string(lf), ": ")
 	if colon < 0 {
@@ -57,12 +84,45 @@ const (
 	NewlineInString    LexicalFailure = "met unescaped newline while reading string"
 	InvalidAfterSymbol LexicalFailure = "met invalid character after reading a symbol"
 	InvalidStart       LexicalFailure = "met character that is not a valid token start"
+	DigitInKeyword     LexicalFailure = "met digit at the start of a keyword"
+	EmptyRadixNumber   LexicalFailure = "met base prefix without digits"
+	InvalidRadixDigit  LexicalFailure = "met character that is not a digit of the base of the number"
+	EmptyExponent      LexicalFailure = "met exponent without digits"
 )
 
+// lexicalCodes identifies the kinds of lexical failures independently of their messages.
+// Codes are part of the interface of the lexer: new failures must get new codes and the codes of
+// removed failures must not be reused.
+var lexicalCodes = map[LexicalFailure]string{
+	TwoDotsInFloat:      "LEX0001",
+	NonDigitInNumber:    "LEX0002",
+	EofInString:         "LEX0003",
+	NewlineInString:     "LEX0004",
+	InvalidAfterSymbol:  "LEX0005",
+	InvalidStart:        "LEX0006",
+	DigitInKeyword:      "LEX0007",
+	EmptyRadixNumber:    "LEX0008",
+	InvalidRadixDigit:   "LEX0009",
+	EmptyExponent:       "LEX0010",
+	UnquotedString:      "LEX0011",
+	UnknownEscape:       "LEX0012",
+	IncompleteEscape:    "LEX0013",
+	InvalidCodepoint:    "LEX0014",
+	EmptyChar:           "LEX0015",
+	MultiRuneChar:       "LEX0016",
+	EmptyHeredocTag:     "LEX0017",
+	TextAfterHeredocTag: "LEX0018",
+	EofInComment:        "LEX0019",
+}
+
 ///////////
 // Lexer //
 ///////////
 
+// eof is the current rune of a lexer that has read its whole input.
+// It is not a valid rune, so that NUL characters in the input are not mistaken for the end of it.
+const eof rune = -1
+
 // Lexer performs lexical analysis for Harp source code, that is to say it turns input text into tokens.
 type Lexer struct {
 	// input is the source code being lexically analyzed.
@@ -71,7 +131,7 @@ type Lexer struct {
 	// currentPosition is the position of the current character.
 	currentPosition int
 
-	// current is the character under examination.
+	// current is the character under examination, eof when the whole input has been read.
 	current rune
 
 	// currentWidth is the currentWidth of the current rune (the number of bytes used to represent it).
@@ -82,15 +142,46 @@ type Lexer struct {
 
 	// column is the current column number in the input.
 	column int
+
+	// source is the named input being analyzed, if any.
+	source *Source
+
+	// commasAsWhitespace is true when commas are skipped like spaces instead of being unquotes.
+	commasAsWhitespace bool
+
+	// skipComments is true when comments are read but not returned as tokens.
+	skipComments bool
+
+	// tabWidth is the number of columns a tab advances by.
+	tabWidth int
+
+	// skipBOM is true when a byte order mark at the start of the input is ignored.
+	skipBOM bool
+
+	// contentStart is the position of the first character of the input, after the skipped byte
+	// order mark if any.
+	contentStart int
+
+	// crNewlines is true when a carriage return that is not followed by a line feed ends a line.
+	crNewlines bool
 }
 
-func NewLexer(input string) *Lexer {
-	if len(input) == 0 {
-		return &Lexer{line: 1}
+// NewLexer returns a lexer of input, configured by the given options.
+func NewLexer(input string, options ...Option) *Lexer {
+	l := &Lexer{input: input, line: 1, tabWidth: 1, current: eof}
+	for _, option := range options {
+		option(l)
 	}
 
-	l := &Lexer{input: input, line: 1, column: -1} // -1 to ensure first column is 0.
-	l.forward()
+	if len(input) > 0 {
+		l.column = -1 // -1 to ensure first column is 0.
+		l.forward()
+		if l.skipBOM && l.current == '\uFEFF' {
+			l.forward()
+			l.column = 0
+			l.contentStart = l.currentPosition
+		}
+	}
 	return l
 }
 
@@ -101,9 +192,13 @@ func (lex *Lexer) forward() {
 	}
 
 	lex.currentPosition += lex.currentWidth
-	lex.column += 1
+	if lex.current == '\t' {
+		lex.column += lex.tabWidth
+	} else {
+		lex.column += 1
+	}
 	if lex.currentPosition >= len(lex.input) { // Reached EOF.
-		lex.current = 0
+		lex.current = eof
 		return
 	}
 
@@ -116,6 +211,11 @@ func (lex *Lexer) nextLine() {
 	lex.column = -1 // -1 to ensure first column is 0.
 }
 
+// atNewline returns true when the current character ends a line.
+func (lex *Lexer) atNewline() bool {
+	return lex.current == '\n' || (lex.crNewlines && lex.current == '\r' && lex.peekChar() != '\n')
+}
+
 // peekChar return the rune of *the next byte* (not exactly the next rune).
 func (lex *Lexer) peekChar() rune {
 	npos := lex.currentPosition + lex.currentWidth
@@ -126,22 +226,68 @@ func (lex *Lexer) peekChar() rune {
 	return rune(lex.input[npos])
 }
 
+// Tokens returns an iterator over the remaining tokens, EOF included:
+//
+//	for tok, err := range lexer.Tokens() {
+//
+// Lexical errors do not stop the iteration: an error is yielded along with its own token.
+func (lex *Lexer) Tokens() iter.Seq2[Token, *LexicalError] {
+	return func(yield func(Token, *LexicalError) bool) {
+		for {
+			tok, err := lex.NextToken()
+			if err != nil {
+				tok = err.Token
+			}
+
+			if !yield(tok, err) || tok.Type == TOKEN_EOF {
+				return
+			}
+		}
+	}
+}
+
+// Tokenize reads all the remaining tokens, EOF included.
+// Lexical errors do not stop the process: the tokens of the errors are part of the returned tokens
+// and the errors are returned in order, so that every problem of an input can be reported at once.
+func (lex *Lexer) Tokenize() ([]Token, []LexicalError) {
+	tokens, errs := []Token{}, []LexicalError{}
+	for tok, err := range lex.Tokens() {
+		if err != nil {
+			errs = append(errs, *err)
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, errs
+}
+
 // NextToken produces the next token by moving the lexer forward.
 func (lex *Lexer) NextToken() (Token, *LexicalError) {
 	// mono is a shortcut for a trivial token made of exactly one valid rune.
 	mono := func(typ TokenType) (Token, *LexicalError) {
 		res := Token{
-			Type:    typ,
-			Literal: string(lex.current),
-			Line:    lex.line,
-			Column:  lex.column,
+			Type:     typ,
+			Line:     lex.line,
+			Column:   lex.column,
+			Position: Position{Offset: lex.currentPosition, Source: lex.source},
 		}
 
 		// The current character is a part of the returned token, so it must be skipped.
 		lex.forward()
+		res.Length = lex.currentPosition - res.Offset
+		// Slicing the input instead of converting the rune avoids an allocation per token.
+		res.Literal = lex.input[res.Offset:lex.currentPosition]
 		return res, nil
 	}
 
+	// comment reads a comment and skips it when comments are discarded.
+	comment := func(fun reader) (Token, *LexicalError) {
+		tok, err := lex.read(fun, TOKEN_COMMENT)
+		if err == nil && lex.skipComments {
+			return lex.NextToken()
+		}
+		return tok, err
+	}
+
 	lex.skipWhitespace()
 
 	// Dispatch prefix.
@@ -167,10 +313,20 @@ func (lex *Lexer) NextToken() (Token, *LexicalError) {
 		// In theory dot must have a symbol after, but lexically this is correct.
 		return mono(TOKEN_DOT)
 	case ':':
-		// In theory colon must have a symbol after, but lexically this is correct.
+		peek := lex.peekChar()
+		if canStartSymbol(peek) || isDigit(peek) {
+			return lex.read(readKeyword, TOKEN_KEYWORD)
+		}
+
 		return mono(TOKEN_COLON)
 	case '|':
 		return mono(TOKEN_PIPE)
+	case '&':
+		return mono(TOKEN_AMPERSAND)
+	case '@': // Only reached when not preceded by a comma, which makes it a splice.
+		return mono(TOKEN_AT)
+	case '^':
+		return mono(TOKEN_CARET)
 	case '\'':
 		return mono(TOKEN_QUOTE)
 	case '_':
@@ -182,9 +338,36 @@ func (lex *Lexer) NextToken() (Token, *LexicalError) {
 		return mono(TOKEN_UNDERSCORE)
 	case '"':
 		return lex.read(readString, TOKEN_DQSTRING)
+	case '#':
+		if lex.peekChar() == '!' && lex.currentPosition == lex.contentStart {
+			// Shebang line of an executable script, a comment for all intents and purposes.
+			return comment(readComment)
+		}
+		if entry, ok := dispatch[lex.peekChar()]; ok {
+			if entry.typ == TOKEN_COMMENT {
+				return comment(entry.read)
+			}
+			return lex.read(entry.read, entry.typ)
+		}
+	case '<':
+		if lex.peekChar() == '<' {
+			return lex.read(readHeredoc, TOKEN_HEREDOC)
+		}
+
+		return lex.read(readSymbol, TOKEN_SYMBOL)
+	case '`':
+		return mono(TOKEN_BACKQUOTE)
+	case ',': // Only reached when commas are not whitespace.
+		if lex.peekChar() == '@' {
+			return lex.read(readPair, TOKEN_SPLICE)
+		}
+
+		return mono(TOKEN_UNQUOTE)
+	case '\\':
+		return lex.read(readChar, TOKEN_CHAR)
 	case ';':
-		return lex.read(readComment, TOKEN_COMMENT)
-	case 0:
+		return comment(readComment)
+	case eof:
 		tok, _ := mono(TOKEN_EOF)
 		tok.Literal = ""
 		return tok, nil
@@ -194,10 +377,10 @@ func (lex *Lexer) NextToken() (Token, *LexicalError) {
 		} else if isDigit(lex.current) {
 			return lex.read(readNumber, TOKEN_INT)
 		}
-
-		tok, _ := mono(TOKEN_INVALID)
-		return Token{}, &LexicalError{tok, InvalidStart.WithStrhex(tok.Literal)}
 	}
+
+	tok, _ := mono(TOKEN_INVALID)
+	return Token{}, &LexicalError{tok, InvalidStart.WithStrhex(tok.Literal)}
 }
 
 /////////////
@@ -221,14 +404,15 @@ func (lex *Lexer) read(
 	fun reader, typ TokenType,
 ) (Token, *LexicalError) {
 	tok := Token{
-		Type:   typ,
-		Line:   lex.line,
-		Column: lex.column,
+		Type:     typ,
+		Line:     lex.line,
+		Column:   lex.column,
+		Position: Position{Offset: lex.currentPosition, Source: lex.source},
 	}
-	start := lex.currentPosition
 
 	fail := fun(lex, &tok)
-	tok.Literal = lex.input[start:lex.currentPosition]
+	tok.Literal = lex.input[tok.Offset:lex.currentPosition]
+	tok.Length = len(tok.Literal)
 
 	if fail != "" {
 		return Token{}, &LexicalError{tok, fail}
@@ -238,14 +422,35 @@ func (lex *Lexer) read(
 }
 
 func readComment(lex *Lexer, tok *Token) LexicalFailure {
-	for lex.current != '\n' && lex.current != 0 {
+	for !lex.atNewline() && lex.current != eof {
 		lex.forward()
 	}
 
 	return ""
 }
 
+func readKeyword(lex *Lexer, tok *Token) LexicalFailure {
+	lex.forward() // Consume colon.
+
+	if isDigit(lex.current) {
+		// Consume the whole would-be keyword so that the error covers it.
+		for canStartSymbol(lex.current) || isDigit(lex.current) {
+			lex.forward()
+		}
+		return DigitInKeyword
+	}
+
+	return readSymbol(lex, tok)
+}
+
 func readNumber(lex *Lexer, tok *Token) LexicalFailure {
+	if lex.current == '0' {
+		switch lex.peekChar() {
+		case 'x', 'o', 'b':
+			return readRadixNumber(lex, tok)
+		}
+	}
+
 	for {
 		switch run := lex.current; {
 		case run == '.':
@@ -255,7 +460,9 @@ func readNumber(lex *Lexer, tok *Token) LexicalFailure {
 			}
 
 			tok.Type = TOKEN_FLOAT
-		case isStoprune(run):
+		case run == 'e' || run == 'E':
+			return readExponent(lex, tok)
+		case lex.isStoprune(run):
 			return ""
 		case !isDigit(run):
 			return NonDigitInNumber
@@ -265,19 +472,70 @@ func readNumber(lex *Lexer, tok *Token) LexicalFailure {
 	}
 }
 
+// readExponent reads the exponent suffix of a float, an e followed by an optionally signed integer.
+func readExponent(lex *Lexer, tok *Token) LexicalFailure {
+	tok.Type = TOKEN_FLOAT
+	lex.forward() // Consume e.
+	if lex.current == '+' || lex.current == '-' {
+		lex.forward()
+	}
+
+	if !isDigit(lex.current) {
+		return EmptyExponent
+	}
+	for isDigit(lex.current) {
+		lex.forward()
+	}
+
+	if !lex.isStoprune(lex.current) {
+		return NonDigitInNumber
+	}
+	return ""
+}
+
+// readRadixNumber reads an integer prefixed by its base (0x, 0o or 0b).
+func readRadixNumber(lex *Lexer, tok *Token) LexicalFailure {
+	lex.forward() // Consume 0.
+
+	isValid := isHexDigit
+	switch lex.current {
+	case 'x':
+		tok.Type = TOKEN_HEX
+	case 'o':
+		tok.Type, isValid = TOKEN_OCT, isOctalDigit
+	case 'b':
+		tok.Type, isValid = TOKEN_BIN, isBinaryDigit
+	}
+	lex.forward() // Consume base letter.
+
+	for digits := 0; ; digits++ {
+		if !isValid(lex.current) {
+			switch {
+			case digits == 0:
+				return EmptyRadixNumber
+			case lex.isStoprune(lex.current):
+				return ""
+			}
+			return InvalidRadixDigit.WithStrhex(string(lex.current))
+		}
+
+		lex.forward()
+	}
+}
+
 func readString(lex *Lexer, tok *Token) LexicalFailure {
 	lex.forward() // Consume opening double quote.
 
 	for {
-		switch lex.current {
-		case 0:
+		switch {
+		case lex.current == eof:
 			return EofInString
-		case '\n':
+		case lex.atNewline():
 			return NewlineInString
-		case '"':
+		case lex.current == '"':
 			lex.forward()
 			return ""
-		case '\\': // Handle escape sequences.
+		case lex.current == '\\': // Handle escape sequences.
 			lex.forward()
 		}
 
@@ -285,13 +543,44 @@ func readString(lex *Lexer, tok *Token) LexicalFailure {
 	}
 }
 
+// readRawString reads a string delimited by #" and "#, where backslashes have no special meaning
+// and newlines are allowed. A raw string cannot contain "#.
+func readRawString(lex *Lexer, tok *Token) LexicalFailure {
+	lex.forward() // Consume #.
+	lex.forward() // Consume opening double quote.
+
+	for {
+		switch {
+		case lex.current == eof:
+			return EofInString
+		case lex.atNewline():
+			lex.nextLine()
+		case lex.current == '"':
+			if lex.peekChar() == '#' {
+				lex.forward()
+				lex.forward()
+				return ""
+			}
+		}
+
+		lex.forward()
+	}
+}
+
+// readPair reads a token made of two ASCII characters, like ,@ or #{.
+func readPair(lex *Lexer, tok *Token) LexicalFailure {
+	lex.forward()
+	lex.forward()
+	return ""
+}
+
 func readSymbol(lex *Lexer, tok *Token) LexicalFailure {
-	for canStartSymbol(lex.current) || isDigit(lex.current) {
+	for canContinueSymbol(lex.current) {
 		lex.forward()
 	}
 
 	// Symbols can be followed by stoprunes or by a dot followed by a symbol.
-	if isStoprune(lex.current) || (lex.current == '.' && canStartSymbol(lex.peekChar())) {
+	if lex.isStoprune(lex.current) || (lex.current == '.' && canStartSymbol(lex.peekChar())) {
 		return ""
 	}
 
@@ -307,9 +596,11 @@ func readSymbol(lex *Lexer, tok *Token) LexicalFailure {
 // Rune predicates //
 
 // canStartSymbol returns true if the given rune can start a valid symbol
-// (unicode letter, _, -).
+// (unicode letter, _, -, +, *, /, %, ?, !, <, >, =), so that operators (<=, not=), predicates
+// (empty?) and mutators (set!) can be named like in other lisps.
+// A symbol cannot start with << though, since it starts a heredoc.
 func canStartSymbol(run rune) bool {
-	return unicode.IsLetter(run) || strings.ContainsRune("_-", run)
+	return unicode.IsLetter(run) || strings.ContainsRune("_-+*/%?!<>=", run)
 }
 
 // isDigit returns true if run is an ASCII digit.
@@ -317,11 +608,38 @@ func isDigit(run rune) bool {
 	return '0' <= run && run <= '9'
 }
 
+// canContinueSymbol returns true if the given rune can appear after the start of a symbol (rune that
+// can start a symbol or digit).
+func canContinueSymbol(run rune) bool {
+	return canStartSymbol(run) || isDigit(run)
+}
+
+// isHexDigit returns true if run is an ASCII hexadecimal digit (case insensitive).
+func isHexDigit(run rune) bool {
+	return isDigit(run) || ('a' <= run && run <= 'f') || ('A' <= run && run <= 'F')
+}
+
+// isOctalDigit returns true if run is an ASCII octal digit.
+func isOctalDigit(run rune) bool {
+	return '0' <= run && run <= '7'
+}
+
+// isBinaryDigit returns true if run is 0 or 1.
+func isBinaryDigit(run rune) bool {
+	return run == '0' || run == '1'
+}
+
 // isStoprune returns true when given a stoprune, that is to say a rune that can validly end any
 // token and can appear right next to anything.
 // For instance, `(` is a stoprune, but `:` is not (it cannot end an int).
 func isStoprune(run rune) bool {
-	return strings.ContainsRune("()[]{} \t\r\n\000", run)
+	return run == eof || strings.ContainsRune("()[]{} \t\r\n", run)
+}
+
+// isStoprune extends the isStoprune function with the runes that are whitespace because of the
+// options of the lexer.
+func (lex *Lexer) isStoprune(run rune) bool {
+	return isStoprune(run) || (run == ',' && lex.commasAsWhitespace)
 }
 
 ///////////////////////
@@ -329,12 +647,14 @@ func isStoprune(run rune) bool {
 
 func (lex *Lexer) skipWhitespace() {
 	for {
-		switch lex.current {
-		case ' ', '\t', '\r':
-			lex.forward()
-		case '\n':
+		switch {
+		case lex.atNewline():
 			lex.nextLine()
 			lex.forward()
+		case lex.current == ' ' || lex.current == '\t' || lex.current == '\r':
+			lex.forward()
+		case lex.current == ',' && lex.commasAsWhitespace:
+			lex.forward()
 		default:
 			return
 		}
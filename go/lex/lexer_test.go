@@ -1,6 +1,10 @@
 package lex
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -12,6 +16,111 @@ type expected struct {
 	Reason  LexicalFailure
 }
 
+func TestPositions(t *testing.T) {
+	lexer := NewLexer("(f\n  \"λ\" 12)")
+	expected := []Position{
+		{Offset: 0, Length: 1}, {Offset: 1, Length: 1}, {Offset: 5, Length: 4},
+		{Offset: 10, Length: 2}, {Offset: 12, Length: 1}, {Offset: 13, Length: 0},
+	}
+
+	for _, exp := range expected {
+		tok, err := lexer.NextToken()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if tok.Position != exp {
+			t.Errorf("expected %q at %+v, got %+v", tok.Literal, exp, tok.Position)
+		}
+	}
+
+	lexer = NewLexer("x $")
+	lexer.NextToken()
+	if _, err := lexer.NextToken(); err == nil || err.Position != (Position{Offset: 2, Length: 1}) {
+		t.Errorf("expected an error at offset 2, got %v", err)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, errs := NewLexer("(f 1a $\n\"x)").Tokenize()
+
+	types := []TokenType{
+		TOKEN_LPAREN, TOKEN_SYMBOL, TOKEN_INT, TOKEN_SYMBOL, TOKEN_INVALID, TOKEN_DQSTRING, TOKEN_EOF,
+	}
+	if len(tokens) != len(types) {
+		t.Fatalf("expected %d tokens, got %d: %+v", len(types), len(tokens), tokens)
+	}
+	for i, typ := range types {
+		if tokens[i].Type != typ {
+			t.Errorf("expected token %d to be %s, got %+v", i, typ, tokens[i])
+		}
+	}
+
+	reasons := []LexicalFailure{NonDigitInNumber, InvalidStart, EofInString}
+	if len(errs) != len(reasons) {
+		t.Fatalf("expected %d errors, got %d: %v", len(reasons), len(errs), errs)
+	}
+	for i, reason := range reasons {
+		if !errs[i].Reason.Same(reason) {
+			t.Errorf("expected error %d to be %s, got %s", i, reason, errs[i].Reason)
+		}
+	}
+	if errs[2].Line != 2 {
+		t.Errorf("expected the last error on line 2, got %s", errs[2])
+	}
+}
+
+func TestTokens(t *testing.T) {
+	types := []TokenType{}
+	for tok, err := range NewLexer("(f $ 1)").Tokens() {
+		if err != nil && tok != err.Token {
+			t.Errorf("expected the token of the error, got %+v", tok)
+		}
+		types = append(types, tok.Type)
+	}
+
+	expected := []TokenType{TOKEN_LPAREN, TOKEN_SYMBOL, TOKEN_INVALID, TOKEN_INT, TOKEN_RPAREN, TOKEN_EOF}
+	if !slices.Equal(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+
+	// Breaking out of the loop leaves the remaining tokens in the lexer.
+	lexer := NewLexer("a b")
+	for range lexer.Tokens() {
+		break
+	}
+	if tok, _ := lexer.NextToken(); tok.Literal != "b" {
+		t.Errorf("expected the lexer to resume at b, got %+v", tok)
+	}
+}
+
+func TestLexicalFailureCodes(t *testing.T) {
+	_, err := NewLexer("$").NextToken()
+	wrapped := fmt.Errorf("loading: %w", err)
+
+	if !errors.Is(wrapped, InvalidStart) {
+		t.Errorf("expected %q to match %q", wrapped, InvalidStart)
+	}
+	if errors.Is(wrapped, EofInString) {
+		t.Errorf("expected %q not to match %q", wrapped, EofInString)
+	}
+
+	var failure LexicalFailure
+	if !errors.As(wrapped, &failure) {
+		t.Fatalf("expected %q to contain a lexical failure", wrapped)
+	}
+	if failure.Code() != "LEX0006" || failure.Detail() != "string($) hex(24)" {
+		t.Errorf("expected code LEX0006 and detail, got %q and %q", failure.Code(), failure.Detail())
+	}
+
+	seen := map[string]LexicalFailure{}
+	for failure, code := range lexicalCodes {
+		if other, ok := seen[code]; ok {
+			t.Errorf("code %s is shared by %q and %q", code, failure, other)
+		}
+		seen[code] = failure
+	}
+}
+
 func TestLexer(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -60,18 +169,13 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			name:  "Symbols with special characters",
-			input: "a-b_c/d*e",
+			input: "a-b_c/d*e +x % %2",
 			expected: []expected{
-				{Type: TOKEN_SYMBOL, Literal: "a-b_c", Line: 1, Column: 0,
-					Reason: InvalidAfterSymbol.WithStrhex("/")},
-				{Type: TOKEN_INVALID, Literal: "/", Line: 1, Column: 5,
-					Reason: InvalidStart.WithStrhex("/")},
-				{Type: TOKEN_SYMBOL, Literal: "d", Line: 1, Column: 6,
-					Reason: InvalidAfterSymbol.WithStrhex("*")},
-				{Type: TOKEN_INVALID, Literal: "*", Line: 1, Column: 7,
-					Reason: InvalidStart.WithStrhex("*")},
-				{Type: TOKEN_SYMBOL, Literal: "e", Line: 1, Column: 8},
-				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 9},
+				{Type: TOKEN_SYMBOL, Literal: "a-b_c/d*e", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "+x", Line: 1, Column: 10},
+				{Type: TOKEN_SYMBOL, Literal: "%", Line: 1, Column: 13},
+				{Type: TOKEN_SYMBOL, Literal: "%2", Line: 1, Column: 15},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 17},
 			},
 		},
 		{
@@ -116,14 +220,16 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			name:  "Special characters",
-			input: ". : | ' _",
+			input: ". : | ' _ &rest",
 			expected: []expected{
 				{Type: TOKEN_DOT, Literal: ".", Line: 1, Column: 0},
 				{Type: TOKEN_COLON, Literal: ":", Line: 1, Column: 2},
 				{Type: TOKEN_PIPE, Literal: "|", Line: 1, Column: 4},
 				{Type: TOKEN_QUOTE, Literal: "'", Line: 1, Column: 6},
 				{Type: TOKEN_UNDERSCORE, Literal: "_", Line: 1, Column: 8},
-				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 9},
+				{Type: TOKEN_AMPERSAND, Literal: "&", Line: 1, Column: 10},
+				{Type: TOKEN_SYMBOL, Literal: "rest", Line: 1, Column: 11},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 15},
 			},
 		},
 		{
@@ -139,12 +245,12 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			name:  "Invalid characters",
-			input: "!@#",
+			input: "$~#",
 			expected: []expected{
-				{Type: TOKEN_INVALID, Literal: "!", Line: 1, Column: 0,
-					Reason: InvalidStart.WithStrhex("!")},
-				{Type: TOKEN_INVALID, Literal: "@", Line: 1, Column: 1,
-					Reason: InvalidStart.WithStrhex("@")},
+				{Type: TOKEN_INVALID, Literal: "$", Line: 1, Column: 0,
+					Reason: InvalidStart.WithStrhex("$")},
+				{Type: TOKEN_INVALID, Literal: "~", Line: 1, Column: 1,
+					Reason: InvalidStart.WithStrhex("~")},
 				{Type: TOKEN_INVALID, Literal: "#", Line: 1, Column: 2,
 					Reason: InvalidStart.WithStrhex("#")},
 				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 3},
@@ -184,6 +290,239 @@ func TestLexer(t *testing.T) {
 				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 1},
 			},
 		},
+		{
+			name:  "Symbols with ? ! and =",
+			input: "(empty? x) set! a=b ok?! :valid? x?.y",
+			expected: []expected{
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "empty?", Line: 1, Column: 1},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 8},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 9},
+				{Type: TOKEN_SYMBOL, Literal: "set!", Line: 1, Column: 11},
+				{Type: TOKEN_SYMBOL, Literal: "a=b", Line: 1, Column: 16},
+				{Type: TOKEN_SYMBOL, Literal: "ok?!", Line: 1, Column: 20},
+				{Type: TOKEN_KEYWORD, Literal: ":valid?", Line: 1, Column: 25},
+				{Type: TOKEN_SYMBOL, Literal: "x?", Line: 1, Column: 33},
+				{Type: TOKEN_DOT, Literal: ".", Line: 1, Column: 35},
+				{Type: TOKEN_SYMBOL, Literal: "y", Line: 1, Column: 36},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 37},
+			},
+		},
+		{
+			name:  "Operators as symbols",
+			input: "(<= a b) (not= 1 2) ?a !b = > <x>",
+			expected: []expected{
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "<=", Line: 1, Column: 1},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 4},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 6},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 7},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 9},
+				{Type: TOKEN_SYMBOL, Literal: "not=", Line: 1, Column: 10},
+				{Type: TOKEN_INT, Literal: "1", Line: 1, Column: 15},
+				{Type: TOKEN_INT, Literal: "2", Line: 1, Column: 17},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 18},
+				{Type: TOKEN_SYMBOL, Literal: "?a", Line: 1, Column: 20},
+				{Type: TOKEN_SYMBOL, Literal: "!b", Line: 1, Column: 23},
+				{Type: TOKEN_SYMBOL, Literal: "=", Line: 1, Column: 26},
+				{Type: TOKEN_SYMBOL, Literal: ">", Line: 1, Column: 28},
+				{Type: TOKEN_SYMBOL, Literal: "<x>", Line: 1, Column: 30},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 33},
+			},
+		},
+		{
+			name:  "Symbols cannot start with <<",
+			input: "<<= x",
+			expected: []expected{
+				{Type: TOKEN_HEREDOC, Literal: "<<", Line: 1, Column: 0, Reason: EmptyHeredocTag},
+				{Type: TOKEN_SYMBOL, Literal: "=", Line: 1, Column: 2},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 4},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 5},
+			},
+		},
+		{
+			name:  "Shebang",
+			input: "#!/usr/bin/env harp\nx",
+			expected: []expected{
+				{Type: TOKEN_COMMENT, Literal: "#!/usr/bin/env harp", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 2, Column: 0},
+				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 1},
+			},
+		},
+		{
+			name:  "Shebang after the start",
+			input: " #!",
+			expected: []expected{
+				{Type: TOKEN_INVALID, Literal: "#", Line: 1, Column: 1, Reason: InvalidStart.WithStrhex("#")},
+				{Type: TOKEN_SYMBOL, Literal: "!", Line: 1, Column: 2},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 3},
+			},
+		},
+		{
+			name:  "Metadata",
+			input: "^:a ^{:b 1}",
+			expected: []expected{
+				{Type: TOKEN_CARET, Literal: "^", Line: 1, Column: 0},
+				{Type: TOKEN_KEYWORD, Literal: ":a", Line: 1, Column: 1},
+				{Type: TOKEN_CARET, Literal: "^", Line: 1, Column: 4},
+				{Type: TOKEN_LBRACE, Literal: "{", Line: 1, Column: 5},
+				{Type: TOKEN_KEYWORD, Literal: ":b", Line: 1, Column: 6},
+				{Type: TOKEN_INT, Literal: "1", Line: 1, Column: 9},
+				{Type: TOKEN_RBRACE, Literal: "}", Line: 1, Column: 10},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 11},
+			},
+		},
+		{
+			name:  "Hash dispatch",
+			input: "#{a} #_b #?(:harp c) #| x #| (\n |# |# #\"d\"#",
+			expected: []expected{
+				{Type: TOKEN_SET, Literal: "#{", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 2},
+				{Type: TOKEN_RBRACE, Literal: "}", Line: 1, Column: 3},
+				{Type: TOKEN_DISCARD, Literal: "#_", Line: 1, Column: 5},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 7},
+				{Type: TOKEN_CONDITIONAL, Literal: "#?", Line: 1, Column: 9},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 11},
+				{Type: TOKEN_KEYWORD, Literal: ":harp", Line: 1, Column: 12},
+				{Type: TOKEN_SYMBOL, Literal: "c", Line: 1, Column: 18},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 19},
+				{Type: TOKEN_COMMENT, Literal: "#| x #| (\n |# |#", Line: 1, Column: 21},
+				{Type: TOKEN_RAWSTRING, Literal: `#"d"#`, Line: 2, Column: 7},
+				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 12},
+			},
+		},
+		{
+			name:  "Unterminated block comment",
+			input: "#| a #| b |#\n",
+			expected: []expected{
+				{Type: TOKEN_COMMENT, Literal: "#| a #| b |#\n", Line: 1, Column: 0, Reason: EofInComment},
+				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 0},
+			},
+		},
+		{
+			name:  "Unknown dispatch",
+			input: "#a",
+			expected: []expected{
+				{Type: TOKEN_INVALID, Literal: "#", Line: 1, Column: 0, Reason: InvalidStart.WithStrhex("#")},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 1},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 2},
+			},
+		},
+		{
+			name:  "Quasiquotation",
+			input: "`(a ,b ,@c) ',d",
+			expected: []expected{
+				{Type: TOKEN_BACKQUOTE, Literal: "`", Line: 1, Column: 0},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 1},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 2},
+				{Type: TOKEN_UNQUOTE, Literal: ",", Line: 1, Column: 4},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 5},
+				{Type: TOKEN_SPLICE, Literal: ",@", Line: 1, Column: 7},
+				{Type: TOKEN_SYMBOL, Literal: "c", Line: 1, Column: 9},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 10},
+				{Type: TOKEN_QUOTE, Literal: "'", Line: 1, Column: 12},
+				{Type: TOKEN_UNQUOTE, Literal: ",", Line: 1, Column: 13},
+				{Type: TOKEN_SYMBOL, Literal: "d", Line: 1, Column: 14},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 15},
+			},
+		},
+		{
+			name:  "Deref",
+			input: "@a , @b @@c",
+			expected: []expected{
+				{Type: TOKEN_AT, Literal: "@", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 1},
+				{Type: TOKEN_UNQUOTE, Literal: ",", Line: 1, Column: 3},
+				{Type: TOKEN_AT, Literal: "@", Line: 1, Column: 5},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 6},
+				{Type: TOKEN_AT, Literal: "@", Line: 1, Column: 8},
+				{Type: TOKEN_AT, Literal: "@", Line: 1, Column: 9},
+				{Type: TOKEN_SYMBOL, Literal: "c", Line: 1, Column: 10},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 11},
+			},
+		},
+		{
+			name:  "Raw strings",
+			input: `#"C:\dir\"# #"a` + "\n" + `  "b""# x`,
+			expected: []expected{
+				{Type: TOKEN_RAWSTRING, Literal: `#"C:\dir\"#`, Line: 1, Column: 0},
+				{Type: TOKEN_RAWSTRING, Literal: `#"a` + "\n" + `  "b""#`, Line: 1, Column: 12},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 2, Column: 8},
+				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 9},
+			},
+		},
+		{
+			name:  "Heredocs",
+			input: "(f <<SQL \n  SQLite\nENDSQL\n  SQL) <<~E\nEND\nE",
+			expected: []expected{
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "f", Line: 1, Column: 1},
+				{Type: TOKEN_HEREDOC, Literal: "<<SQL \n  SQLite\nENDSQL\n  SQL", Line: 1, Column: 3},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 4, Column: 5},
+				{Type: TOKEN_HEREDOC, Literal: "<<~E\nEND\nE", Line: 4, Column: 7},
+				{Type: TOKEN_EOF, Literal: "", Line: 6, Column: 1},
+			},
+		},
+		{
+			name:  "CRLF heredoc",
+			input: "<<SQL\r\nx\r\nSQL\r\ny",
+			expected: []expected{
+				{Type: TOKEN_HEREDOC, Literal: "<<SQL\r\nx\r\nSQL", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "y", Line: 4, Column: 0},
+				{Type: TOKEN_EOF, Literal: "", Line: 4, Column: 1},
+			},
+		},
+		{
+			name:  "Malformed heredocs",
+			input: "<< x\n<<A b\n<<A\nB",
+			expected: []expected{
+				{Type: TOKEN_HEREDOC, Literal: "<<", Line: 1, Column: 0, Reason: EmptyHeredocTag},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 3},
+				{Type: TOKEN_HEREDOC, Literal: "<<A ", Line: 2, Column: 0, Reason: TextAfterHeredocTag},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 2, Column: 4},
+				{Type: TOKEN_HEREDOC, Literal: "<<A\nB", Line: 3, Column: 0, Reason: EofInString},
+				{Type: TOKEN_EOF, Literal: "", Line: 4, Column: 1},
+			},
+		},
+		{
+			name:  "Unterminated raw string",
+			input: "(f #\"a\"\nb",
+			expected: []expected{
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "f", Line: 1, Column: 1},
+				{Type: TOKEN_RAWSTRING, Literal: "#\"a\"\nb", Line: 1, Column: 3, Reason: EofInString},
+				{Type: TOKEN_EOF, Literal: "", Line: 2, Column: 1},
+			},
+		},
+		{
+			name:  "Characters",
+			input: "\\a \\newline \\u03BB \\λ (\\() \\[]",
+			expected: []expected{
+				{Type: TOKEN_CHAR, Literal: "\\a", Line: 1, Column: 0},
+				{Type: TOKEN_CHAR, Literal: "\\newline", Line: 1, Column: 3},
+				{Type: TOKEN_CHAR, Literal: "\\u03BB", Line: 1, Column: 12},
+				{Type: TOKEN_CHAR, Literal: "\\λ", Line: 1, Column: 19},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 22},
+				{Type: TOKEN_CHAR, Literal: "\\(", Line: 1, Column: 23},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 25},
+				{Type: TOKEN_CHAR, Literal: "\\[", Line: 1, Column: 27},
+				{Type: TOKEN_RBRACKET, Literal: "]", Line: 1, Column: 29},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 30},
+			},
+		},
+		{
+			name:  "Invalid characters literals",
+			input: "\\ \\ab \\uD800 \\",
+			expected: []expected{
+				{Type: TOKEN_CHAR, Literal: "\\", Line: 1, Column: 0, Reason: EmptyChar},
+				{Type: TOKEN_CHAR, Literal: "\\ab", Line: 1, Column: 2,
+					Reason: MultiRuneChar.WithStrhex("ab")},
+				{Type: TOKEN_CHAR, Literal: "\\uD800", Line: 1, Column: 6,
+					Reason: InvalidCodepoint.WithStrhex("uD800")},
+				{Type: TOKEN_CHAR, Literal: "\\", Line: 1, Column: 13, Reason: EmptyChar},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 14},
+			},
+		},
 		{
 			name:  "String with escaped characters",
 			input: `"hello\nworld\t\"quoted\"\\escaped\\"`,
@@ -318,7 +657,7 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			name:  "More invalid characters",
-			input: "§±~`°•",
+			input: "§±~°•",
 			expected: []expected{
 				{Type: TOKEN_INVALID, Literal: "§", Line: 1, Column: 0,
 					Reason: InvalidStart.WithStrhex("§")},
@@ -326,13 +665,11 @@ func TestLexer(t *testing.T) {
 					Reason: InvalidStart.WithStrhex("±")},
 				{Type: TOKEN_INVALID, Literal: "~", Line: 1, Column: 2,
 					Reason: InvalidStart.WithStrhex("~")},
-				{Type: TOKEN_INVALID, Literal: "`", Line: 1, Column: 3,
-					Reason: InvalidStart.WithStrhex("`")},
-				{Type: TOKEN_INVALID, Literal: "°", Line: 1, Column: 4,
+				{Type: TOKEN_INVALID, Literal: "°", Line: 1, Column: 3,
 					Reason: InvalidStart.WithStrhex("°")},
-				{Type: TOKEN_INVALID, Literal: "•", Line: 1, Column: 5,
+				{Type: TOKEN_INVALID, Literal: "•", Line: 1, Column: 4,
 					Reason: InvalidStart.WithStrhex("•")},
-				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 6},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 5},
 			},
 		},
 		{
@@ -409,6 +746,140 @@ func TestLexer(t *testing.T) {
 				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 1},
 			},
 		},
+		{
+			name:  "Keywords",
+			input: "{:x 1 :long-name_2 :_}",
+			expected: []expected{
+				{Type: TOKEN_LBRACE, Literal: "{", Line: 1, Column: 0},
+				{Type: TOKEN_KEYWORD, Literal: ":x", Line: 1, Column: 1},
+				{Type: TOKEN_INT, Literal: "1", Line: 1, Column: 4},
+				{Type: TOKEN_KEYWORD, Literal: ":long-name_2", Line: 1, Column: 6},
+				{Type: TOKEN_KEYWORD, Literal: ":_", Line: 1, Column: 19},
+				{Type: TOKEN_RBRACE, Literal: "}", Line: 1, Column: 21},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 22},
+			},
+		},
+		{
+			name:  "Unicode keyword",
+			input: ":été",
+			expected: []expected{
+				{Type: TOKEN_KEYWORD, Literal: ":été", Line: 1, Column: 0},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 4},
+			},
+		},
+		{
+			name:  "Colon not followed by a symbol",
+			input: ": x :(",
+			expected: []expected{
+				{Type: TOKEN_COLON, Literal: ":", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 2},
+				{Type: TOKEN_COLON, Literal: ":", Line: 1, Column: 4},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 5},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 6},
+			},
+		},
+		{
+			name:  "Keyword starting with a digit",
+			input: ":123 :1a b",
+			expected: []expected{
+				{Type: TOKEN_KEYWORD, Literal: ":123", Line: 1, Column: 0, Reason: DigitInKeyword},
+				{Type: TOKEN_KEYWORD, Literal: ":1a", Line: 1, Column: 5, Reason: DigitInKeyword},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 9},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 10},
+			},
+		},
+		{
+			name:  "Keyword followed by invalid",
+			input: ":a|",
+			expected: []expected{
+				{Type: TOKEN_KEYWORD, Literal: ":a", Line: 1, Column: 0,
+					Reason: InvalidAfterSymbol.WithStrhex("|")},
+				{Type: TOKEN_PIPE, Literal: "|", Line: 1, Column: 2},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 3},
+			},
+		},
+		{
+			name:  "Integers with a base prefix",
+			input: "0xFF 0xdead_ 0o755 0b1010 0x0",
+			expected: []expected{
+				{Type: TOKEN_HEX, Literal: "0xFF", Line: 1, Column: 0},
+				{Type: TOKEN_HEX, Literal: "0xdead", Line: 1, Column: 5,
+					Reason: InvalidRadixDigit.WithStrhex("_")},
+				{Type: TOKEN_UNDERSCORE, Literal: "_", Line: 1, Column: 11},
+				{Type: TOKEN_OCT, Literal: "0o755", Line: 1, Column: 13},
+				{Type: TOKEN_BIN, Literal: "0b1010", Line: 1, Column: 19},
+				{Type: TOKEN_HEX, Literal: "0x0", Line: 1, Column: 26},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 29},
+			},
+		},
+		{
+			name:  "Base prefix without digits",
+			input: "0x (0b) 0og",
+			expected: []expected{
+				{Type: TOKEN_HEX, Literal: "0x", Line: 1, Column: 0, Reason: EmptyRadixNumber},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 3},
+				{Type: TOKEN_BIN, Literal: "0b", Line: 1, Column: 4, Reason: EmptyRadixNumber},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 6},
+				{Type: TOKEN_OCT, Literal: "0o", Line: 1, Column: 8, Reason: EmptyRadixNumber},
+				{Type: TOKEN_SYMBOL, Literal: "g", Line: 1, Column: 10},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 11},
+			},
+		},
+		{
+			name:  "Digits outside of the base",
+			input: "0b102 0o8 0x1.5",
+			expected: []expected{
+				{Type: TOKEN_BIN, Literal: "0b10", Line: 1, Column: 0,
+					Reason: InvalidRadixDigit.WithStrhex("2")},
+				{Type: TOKEN_INT, Literal: "2", Line: 1, Column: 4},
+				{Type: TOKEN_OCT, Literal: "0o", Line: 1, Column: 6, Reason: EmptyRadixNumber},
+				{Type: TOKEN_INT, Literal: "8", Line: 1, Column: 8},
+				{Type: TOKEN_HEX, Literal: "0x1", Line: 1, Column: 10,
+					Reason: InvalidRadixDigit.WithStrhex(".")},
+				{Type: TOKEN_FLOAT, Literal: ".5", Line: 1, Column: 13},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 15},
+			},
+		},
+		{
+			name:  "Other numbers starting with zero",
+			input: "0 07 0.5 0a",
+			expected: []expected{
+				{Type: TOKEN_INT, Literal: "0", Line: 1, Column: 0},
+				{Type: TOKEN_INT, Literal: "07", Line: 1, Column: 2},
+				{Type: TOKEN_FLOAT, Literal: "0.5", Line: 1, Column: 5},
+				{Type: TOKEN_INT, Literal: "0", Line: 1, Column: 9, Reason: NonDigitInNumber},
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 10},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 11},
+			},
+		},
+		{
+			name:  "Floats with an exponent",
+			input: "1e10 6.02e23 1.5e-3 2E+2 .5e1",
+			expected: []expected{
+				{Type: TOKEN_FLOAT, Literal: "1e10", Line: 1, Column: 0},
+				{Type: TOKEN_FLOAT, Literal: "6.02e23", Line: 1, Column: 5},
+				{Type: TOKEN_FLOAT, Literal: "1.5e-3", Line: 1, Column: 13},
+				{Type: TOKEN_FLOAT, Literal: "2E+2", Line: 1, Column: 20},
+				{Type: TOKEN_FLOAT, Literal: ".5e1", Line: 1, Column: 25},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 29},
+			},
+		},
+		{
+			name:  "Malformed exponents",
+			input: "1e 1e+ (1e-) 1e2.5 1ex",
+			expected: []expected{
+				{Type: TOKEN_FLOAT, Literal: "1e", Line: 1, Column: 0, Reason: EmptyExponent},
+				{Type: TOKEN_FLOAT, Literal: "1e+", Line: 1, Column: 3, Reason: EmptyExponent},
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 7},
+				{Type: TOKEN_FLOAT, Literal: "1e-", Line: 1, Column: 8, Reason: EmptyExponent},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 11},
+				{Type: TOKEN_FLOAT, Literal: "1e2", Line: 1, Column: 13, Reason: NonDigitInNumber},
+				{Type: TOKEN_FLOAT, Literal: ".5", Line: 1, Column: 16},
+				{Type: TOKEN_FLOAT, Literal: "1e", Line: 1, Column: 19, Reason: EmptyExponent},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 21},
+				{Type: TOKEN_EOF, Literal: "", Line: 1, Column: 22},
+			},
+		},
 		{
 			name:  "Number followed by invalid",
 			input: "123§",
@@ -425,7 +896,7 @@ func TestLexer(t *testing.T) {
 			lexer := NewLexer(tt.input)
 
 			for _, exp := range tt.expected {
-				expTok := Token{exp.Type, exp.Literal, exp.Line, exp.Column}
+				expTok := Token{Type: exp.Type, Literal: exp.Literal, Line: exp.Line, Column: exp.Column}
 				expFail := exp.Reason
 				gotFail := LexicalFailure("")
 				gotTok, err := lexer.NextToken()
@@ -443,6 +914,11 @@ func TestLexer(t *testing.T) {
 				if expFail != gotFail {
 					t.Errorf("expected failure:\n> %s\ngot:\n> %s", expFail, gotFail)
 				}
+				// The position must slice the literal out of the input.
+				if gotTok.End() > len(tt.input) || tt.input[gotTok.Offset:gotTok.End()] != gotTok.Literal {
+					t.Errorf("position %+v does not match literal %q", gotTok.Position, gotTok.Literal)
+				}
+				gotTok.Position = Position{}
 				if expTok != gotTok {
 					t.Errorf("expected %+v, got: %+v", expTok, gotTok)
 				}
@@ -456,3 +932,36 @@ func TestLexer(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkInput is a large input mixing every kind of token.
+var benchmarkInput = strings.Repeat(`; Compute the sum of a collection.
+(fun sum [xs]
+  (let [total 0.5e1 mask 0xff]
+    (when [(empty? xs) :none]
+      [else (reduce add total "a \"string\"" #"raw"# \newline xs)])))
+`, 1000)
+
+func TestLexerAllocations(t *testing.T) {
+	lexer := NewLexer(benchmarkInput)
+	allocs := testing.AllocsPerRun(1000, func() {
+		lexer.NextToken()
+	})
+
+	// Valid tokens are values whose literals are slices of the input.
+	if allocs != 0 {
+		t.Errorf("expected no allocation per token, got %.2f", allocs)
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	for range b.N {
+		lexer := NewLexer(benchmarkInput)
+		for {
+			tok, _ := lexer.NextToken()
+			if tok.Type == TOKEN_EOF {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package lex
+
+// Option configures a lexer when passed to NewLexer or NewSourceLexer.
+type Option func(*Lexer)
+
+// WithCommasAsWhitespace makes the lexer treat commas as whitespace, like Clojure does, so that
+// data pasted from JSON-like notations can be read as is (e.g. `[1, 2, 3,]`).
+// Unquote and unquote splicing are not available in this mode.
+func WithCommasAsWhitespace(enabled bool) Option {
+	return func(lex *Lexer) {
+		lex.commasAsWhitespace = enabled
+	}
+}
+
+// WithComments controls whether comments are returned as TOKEN_COMMENT tokens, which is the default,
+// or silently skipped. Formatters and documentation extractors need them, most other tools do not.
+func WithComments(keep bool) Option {
+	return func(lex *Lexer) {
+		lex.skipComments = !keep
+	}
+}
+
+// WithTabWidth makes a tab advance the column by width instead of 1, so that columns match the
+// display of an editor using tabs of that width.
+func WithTabWidth(width int) Option {
+	return func(lex *Lexer) {
+		lex.tabWidth = width
+	}
+}
+
+// WithSkipBOM controls whether a byte order mark at the start of the input is ignored, instead of
+// being an invalid token start. Offsets still count its bytes, but columns do not.
+func WithSkipBOM(skip bool) Option {
+	return func(lex *Lexer) {
+		lex.skipBOM = skip
+	}
+}
+
+// WithCRNewlines controls whether a carriage return that is not followed by a line feed ends a line,
+// as in files written with old Mac OS line endings. By default, only line feeds end lines and
+// carriage returns are whitespace.
+func WithCRNewlines(enabled bool) Option {
+	return func(lex *Lexer) {
+		lex.crNewlines = enabled
+	}
+}
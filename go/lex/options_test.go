@@ -0,0 +1,151 @@
+package lex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWithCommasAsWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		enabled  bool
+		expected []string // Literals of the tokens, EOF excluded.
+	}{
+		{"Numbers and strings", `[1, 2.5,"a",]`, true, []string{"[", "1", "2.5", `"a"`, "]"}},
+		{"Symbols and keywords", "{a 1,:b x,}", true, []string{"{", "a", "1", ":b", "x", "}"}},
+		{"Comma character", `\,,\a,`, true, []string{`\,`, `\a`}},
+		{"Unquote when disabled", "`(f ,a ,@b)", false, []string{"`", "(", "f", ",", "a", ",@", "b", ")"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, errs := NewLexer(tt.input, WithCommasAsWhitespace(tt.enabled)).Tokenize()
+			if len(errs) > 0 {
+				t.Fatalf("unexpected error: %s", errs[0])
+			}
+
+			got := []string{}
+			for _, tok := range tokens[:len(tokens)-1] {
+				got = append(got, tok.Literal)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithComments(t *testing.T) {
+	input := "#!/usr/bin/env harp\n; Comment.\n(f #| Argument. |#\n 1)"
+	for _, keep := range []bool{true, false} {
+		tokens, _ := NewLexer(input, WithComments(keep)).Tokenize()
+		comments := 0
+		for _, tok := range tokens {
+			if tok.Type == TOKEN_COMMENT {
+				comments++
+			}
+		}
+
+		if expected := map[bool]int{true: 3, false: 0}[keep]; comments != expected {
+			t.Errorf("expected %d comments when keeping them is %t, got %d", expected, keep, comments)
+		}
+		if len(tokens)-comments != 5 {
+			t.Errorf("expected 5 other tokens when keeping comments is %t, got %v", keep, tokens)
+		}
+	}
+}
+
+func TestPositionOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []Option
+		expected []expected
+	}{
+		{
+			name:    "Tab width",
+			input:   "\t(f\t1)",
+			options: []Option{WithTabWidth(4)},
+			expected: []expected{
+				{Type: TOKEN_LPAREN, Literal: "(", Line: 1, Column: 4},
+				{Type: TOKEN_SYMBOL, Literal: "f", Line: 1, Column: 5},
+				{Type: TOKEN_INT, Literal: "1", Line: 1, Column: 10},
+				{Type: TOKEN_RPAREN, Literal: ")", Line: 1, Column: 11},
+			},
+		},
+		{
+			name:    "Skipped BOM",
+			input:   "\uFEFFx y",
+			options: []Option{WithSkipBOM(true)},
+			expected: []expected{
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "y", Line: 1, Column: 2},
+			},
+		},
+		{
+			name:    "Skipped BOM before a shebang",
+			input:   "\uFEFF#!/usr/bin/env harp\nx",
+			options: []Option{WithSkipBOM(true)},
+			expected: []expected{
+				{Type: TOKEN_COMMENT, Literal: "#!/usr/bin/env harp", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 2, Column: 0},
+			},
+		},
+		{
+			name:  "Kept BOM",
+			input: "\uFEFFx",
+			expected: []expected{
+				{Type: TOKEN_INVALID, Literal: "\uFEFF", Line: 1, Column: 0, Reason: InvalidStart},
+				{Type: TOKEN_SYMBOL, Literal: "x", Line: 1, Column: 1},
+			},
+		},
+		{
+			name:    "Carriage return newlines",
+			input:   "a\rb ; c\r#\"d\re\"# \r\nf",
+			options: []Option{WithCRNewlines(true)},
+			expected: []expected{
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 2, Column: 0},
+				{Type: TOKEN_COMMENT, Literal: "; c", Line: 2, Column: 2},
+				{Type: TOKEN_RAWSTRING, Literal: "#\"d\re\"#", Line: 3, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "f", Line: 5, Column: 0},
+			},
+		},
+		{
+			name:    "Carriage return heredoc",
+			input:   "<<E\rx\rE\ry",
+			options: []Option{WithCRNewlines(true)},
+			expected: []expected{
+				{Type: TOKEN_HEREDOC, Literal: "<<E\rx\rE", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "y", Line: 4, Column: 0},
+			},
+		},
+		{
+			name:  "Carriage returns as whitespace",
+			input: "a\rb",
+			expected: []expected{
+				{Type: TOKEN_SYMBOL, Literal: "a", Line: 1, Column: 0},
+				{Type: TOKEN_SYMBOL, Literal: "b", Line: 1, Column: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := NewLexer(tt.input, tt.options...)
+			for _, exp := range tt.expected {
+				tok, err := lexer.NextToken()
+				reason := LexicalFailure("")
+				if err != nil {
+					tok, reason = err.Token, LexicalFailure(err.Reason.Cause())
+				}
+
+				got := expected{tok.Type, tok.Literal, tok.Line, tok.Column, reason}
+				if got != exp {
+					t.Errorf("expected %+v, got %+v", exp, got)
+				}
+			}
+		})
+	}
+}
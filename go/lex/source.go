@@ -0,0 +1,42 @@
+package lex
+
+import (
+	"fmt"
+	"mooss/harp/position"
+	"sync"
+)
+
+// Source is a named input, usually a file, so that diagnostics can tell where they come from.
+type Source struct {
+	// Name identifies the source in diagnostics, typically the path of the file.
+	Name    string
+	Content string
+
+	// index maps the offsets of tokens to lines, built on first use since most sources are read
+	// without an error to report.
+	index     *position.LineIndex
+	indexOnce sync.Once
+}
+
+// Index returns the line index of the content, which must not change once indexed.
+func (src *Source) Index() *position.LineIndex {
+	src.indexOnce.Do(func() { src.index = position.NewLineIndex(src.Content) })
+	return src.index
+}
+
+// NewSourceLexer returns a lexer of the content of src, whose tokens refer to src.
+func NewSourceLexer(src *Source, options ...Option) *Lexer {
+	lex := NewLexer(src.Content, options...)
+	lex.source = src
+	return lex
+}
+
+// Location describes where the token is for error messages, e.g. "at line 3 column 7", or
+// "in main.harp at line 3 column 7" when the token comes from a named source.
+func (tok Token) Location() string {
+	res := fmt.Sprintf("at line %d column %d", tok.Line, tok.Column)
+	if tok.Source != nil && tok.Source.Name != "" {
+		res = fmt.Sprintf("in %s %s", tok.Source.Name, res)
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestSourceInTokens(t *testing.T) {
+	src := &Source{Name: "main.harp", Content: "(f 1)"}
+	for tok, err := range NewSourceLexer(src).Tokens() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if tok.Source != src {
+			t.Errorf("expected %q to refer to its source", tok.Literal)
+		}
+	}
+}
+
+func TestSourceIndex(t *testing.T) {
+	src := &Source{Content: "(f 1\n\n  [:a \"b\"])"}
+	if src.Index() != src.Index() {
+		t.Error("expected the index to be built once")
+	}
+
+	// The content is ASCII, so byte columns are the columns of tokens.
+	for tok, err := range NewSourceLexer(src).Tokens() {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		pos, err := src.Index().Position(tok.Offset)
+		if err != nil || pos.Line != tok.Line || pos.Column != tok.Column {
+			t.Errorf("expected %q at %d:%d, got %v (%v)", tok.Literal, tok.Line, tok.Column, pos, err)
+		}
+	}
+}
+
+func TestSourceInErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lexer    *Lexer
+		expected string
+	}{
+		{
+			"Named source",
+			NewSourceLexer(&Source{Name: "main.harp", Content: "\n  $"}),
+			"lexical error in main.harp at line 2 column 2: " + string(InvalidStart.WithStrhex("$")),
+		},
+		{
+			"Anonymous input",
+			NewLexer("$"),
+			"lexical error at line 1 column 0: " + string(InvalidStart.WithStrhex("$")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.lexer.NextToken()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected:\n> %s\ngot:\n> %s", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package lex
+
+// TokenStream wraps a lexer to look ahead and backtrack over its tokens, skipping comments.
+//
+// Tokens are buffered from the oldest active mark, or from the next token when there is no mark, so
+// the memory used is bounded by the lookahead and the backtracking distance.
+type TokenStream struct {
+	lexer *Lexer
+
+	// buffer holds the tokens read from the lexer and not discarded yet.
+	buffer []streamed
+
+	// next is the index in buffer of the next token to consume.
+	next int
+
+	// base is the number of tokens discarded from the start of buffer, so that marks stay valid
+	// when the buffer is compacted.
+	base int
+
+	// marks is the number of marks that were neither reset nor released.
+	marks int
+}
+
+// streamed is a token along with the error that occured while reading it, if any.
+type streamed struct {
+	tok Token
+	err *LexicalError
+}
+
+// Mark is a position in a token stream, to go back to with Reset.
+type Mark int
+
+func NewTokenStream(lexer *Lexer) *TokenStream {
+	return &TokenStream{lexer: lexer}
+}
+
+// fill ensures that the buffer holds at least n tokens after next.
+func (ts *TokenStream) fill(n int) {
+	for len(ts.buffer)-ts.next < n {
+		tok, err := ts.lexer.NextToken()
+		if err != nil {
+			tok = err.Token
+		}
+		if tok.Type != TOKEN_COMMENT || err != nil { // Unterminated comments are errors like any other.
+			ts.buffer = append(ts.buffer, streamed{tok, err})
+		}
+	}
+}
+
+// Peek returns the next token without consuming it.
+// Like NextToken, the error is not nil when the token could not be lexed, in which case the token is
+// the token of the error.
+func (ts *TokenStream) Peek() (Token, *LexicalError) {
+	return ts.PeekN(0)
+}
+
+// PeekN returns the token coming n tokens after the next one without consuming anything, PeekN(0)
+// being Peek. Peeking past the end of the input returns EOF.
+func (ts *TokenStream) PeekN(n int) (Token, *LexicalError) {
+	ts.fill(n + 1)
+	res := ts.buffer[ts.next+n]
+	return res.tok, res.err
+}
+
+// Next consumes and returns the next token.
+func (ts *TokenStream) Next() (Token, *LexicalError) {
+	tok, err := ts.Peek()
+	ts.next++
+
+	// Without marks, consumed tokens can never be read again.
+	if ts.marks == 0 {
+		ts.base += ts.next
+		ts.buffer, ts.next = ts.buffer[ts.next:], 0
+	}
+	return tok, err
+}
+
+// Mark records the current position so that the tokens consumed afterwards can be read again by
+// calling Reset. Every mark must end with either Reset or Release.
+func (ts *TokenStream) Mark() Mark {
+	ts.marks++
+	return Mark(ts.base + ts.next)
+}
+
+// Reset moves the stream to a mark and ends it. The mark is usually before the current position,
+// but it can be after it, to skip tokens read again since the mark.
+func (ts *TokenStream) Reset(mark Mark) {
+	ts.next = int(mark) - ts.base
+	ts.Release(mark)
+}
+
+// Release ends a mark without moving the stream, when backtracking is not needed anymore.
+func (ts *TokenStream) Release(mark Mark) {
+	ts.marks--
+}
@@ -0,0 +1,47 @@
+package lex
+
+import (
+	"testing"
+)
+
+func TestTokenStream(t *testing.T) {
+	ts := NewTokenStream(NewLexer("(f ; Comment.\n a $ b)"))
+	literal := func(tok Token, _ *LexicalError) string { return tok.Literal }
+	check := func(what string, got string, expected string) {
+		t.Helper()
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", what, expected, got)
+		}
+	}
+
+	check("peek", literal(ts.Peek()), "(")
+	check("peek 2 ahead, skipping the comment", literal(ts.PeekN(2)), "a")
+	check("next", literal(ts.Next()), "(")
+
+	mark := ts.Mark()
+	check("next after mark", literal(ts.Next()), "f")
+	check("next", literal(ts.Next()), "a")
+
+	_, err := ts.Next()
+	if err == nil || !err.Reason.Same(InvalidStart) {
+		t.Errorf("expected an invalid start error, got %v", err)
+	}
+
+	ts.Reset(mark)
+	check("next after reset", literal(ts.Next()), "f")
+
+	mark = ts.Mark()
+	check("next after second mark", literal(ts.Next()), "a")
+	ts.Release(mark)
+	check("next after release", literal(ts.Next()), "$")
+	check("next", literal(ts.Next()), "b")
+	check("next", literal(ts.Next()), ")")
+
+	tok, _ := ts.PeekN(3)
+	if tok.Type != TOKEN_EOF {
+		t.Errorf("expected EOF when peeking past the end, got %+v", tok)
+	}
+	if len(ts.buffer) > 4 {
+		t.Errorf("expected consumed tokens to be discarded, %d are buffered", len(ts.buffer))
+	}
+}
@@ -1,29 +1,50 @@
 package lex
 
-type TokenType string
+import (
+	"fmt"
+)
+
+// TokenType is the kind of a token.
+// It is a small integer so that tokens stay small and comparing kinds is cheap, use String to
+// display it.
+type TokenType uint8
 
 const (
 	/////////////
 	// Special //
 
 	// End of file.
-	TOKEN_EOF TokenType = "EOF"
+	TOKEN_EOF TokenType = iota
 	// Invalid rune identified at the start of a token.
-	TOKEN_INVALID TokenType = "INVALID"
+	TOKEN_INVALID
 	// Comment that stretches to the end of the line (semicolon).
-	TOKEN_COMMENT TokenType = "COMMENT" // ;
+	TOKEN_COMMENT // ;
 
 	///////////
 	// Atoms //
 
 	// Identifier mapped to a value.
-	TOKEN_SYMBOL TokenType = "SYMBOL"
-	// Integer.
-	TOKEN_INT TokenType = "INT"
+	TOKEN_SYMBOL
+	// Decimal integer.
+	TOKEN_INT
+	// Hexadecimal integer.
+	TOKEN_HEX // 0x
+	// Octal integer.
+	TOKEN_OCT // 0o
+	// Binary integer.
+	TOKEN_BIN // 0b
 	// Floating point number.
-	TOKEN_FLOAT TokenType = "FLOAT"
+	TOKEN_FLOAT
 	// Double quoted string.
-	TOKEN_DQSTRING TokenType = "STRING"
+	TOKEN_DQSTRING
+	// Raw string, spanning lines and without escape sequences.
+	TOKEN_RAWSTRING
+	// Heredoc, spanning lines up to a closing tag.
+	TOKEN_HEREDOC // <<TAG or <<~TAG
+	// Character.
+	TOKEN_CHAR // \a, \newline or \u03BB
+	// Symbol prefixed by a colon, evaluating to itself.
+	TOKEN_KEYWORD // :name
 
 	///////////////
 	// Stoprunes //
@@ -31,36 +52,129 @@ const (
 	// Whitespace is also a stoprune but is only a delimiter, so it's not represented here.
 
 	// Opening parenthesis.
-	TOKEN_LPAREN TokenType = "LPAREN" // (
+	TOKEN_LPAREN // (
 	// Closing parenthesis.
-	TOKEN_RPAREN TokenType = "RPAREN" // )
+	TOKEN_RPAREN // )
 	// Opening curly brace.
-	TOKEN_LBRACE TokenType = "LBRACE" // {
+	TOKEN_LBRACE // {
 	// Closing curly brace.
-	TOKEN_RBRACE TokenType = "RBRACE" // }
+	TOKEN_RBRACE // }
 	// Opening square bracket.
-	TOKEN_LBRACKET TokenType = "LBRACKET" // [
+	TOKEN_LBRACKET // [
 	// Closing square bracket.
-	TOKEN_RBRACKET TokenType = "RBRACKET" // ]
+	TOKEN_RBRACKET // ]
 
 	/////////////////
 	// Other runes //
 
 	// Dot, meant to be followed by a symbol (method call or field access).
-	TOKEN_DOT TokenType = "DOT"
-	// Colon, meant to be followed by a symbol (method of a type).
-	TOKEN_COLON TokenType = "COLON"
+	TOKEN_DOT
+	// Colon not immediately followed by a symbol (a colon followed by a symbol is a keyword).
+	TOKEN_COLON
 	// Single quote.
-	TOKEN_QUOTE TokenType = "QUOTE" // '
+	TOKEN_QUOTE // '
+	// Backquote (quasiquote).
+	TOKEN_BACKQUOTE // `
+	// Comma (unquote).
+	TOKEN_UNQUOTE // ,
+	// Comma followed by at (unquote splicing).
+	TOKEN_SPLICE // ,@
 	// Underscore.
-	TOKEN_UNDERSCORE TokenType = "UNDER" // _
+	TOKEN_UNDERSCORE // _
 	// Pipe.
-	TOKEN_PIPE TokenType = "PIPE" // |
+	TOKEN_PIPE // |
+	// Ampersand, introducing the rest parameter of a function.
+	TOKEN_AMPERSAND // &
+	// At, dereferencing the form that follows.
+	TOKEN_AT // @
+	// Caret, attaching the metadata that follows to the next form.
+	TOKEN_CARET // ^
+
+	///////////////////
+	// Hash dispatch //
+
+	// Opening curly brace of a set literal, closed by TOKEN_RBRACE.
+	TOKEN_SET // #{
+	// Discard marker, the form that follows is ignored.
+	TOKEN_DISCARD // #_
+	// Reader conditional marker, followed by a list of features and forms.
+	TOKEN_CONDITIONAL // #?
 )
 
+// tokenNames are the names of the token types, as displayed and used in JSON.
+var tokenNames = [...]string{
+	TOKEN_EOF:         "EOF",
+	TOKEN_INVALID:     "INVALID",
+	TOKEN_COMMENT:     "COMMENT",
+	TOKEN_SYMBOL:      "SYMBOL",
+	TOKEN_INT:         "INT",
+	TOKEN_HEX:         "HEX",
+	TOKEN_OCT:         "OCT",
+	TOKEN_BIN:         "BIN",
+	TOKEN_FLOAT:       "FLOAT",
+	TOKEN_DQSTRING:    "STRING",
+	TOKEN_RAWSTRING:   "RAWSTRING",
+	TOKEN_HEREDOC:     "HEREDOC",
+	TOKEN_CHAR:        "CHAR",
+	TOKEN_KEYWORD:     "KEYWORD",
+	TOKEN_LPAREN:      "LPAREN",
+	TOKEN_RPAREN:      "RPAREN",
+	TOKEN_LBRACE:      "LBRACE",
+	TOKEN_RBRACE:      "RBRACE",
+	TOKEN_LBRACKET:    "LBRACKET",
+	TOKEN_RBRACKET:    "RBRACKET",
+	TOKEN_DOT:         "DOT",
+	TOKEN_COLON:       "COLON",
+	TOKEN_QUOTE:       "QUOTE",
+	TOKEN_BACKQUOTE:   "BACKQUOTE",
+	TOKEN_UNQUOTE:     "UNQUOTE",
+	TOKEN_SPLICE:      "SPLICE",
+	TOKEN_UNDERSCORE:  "UNDER",
+	TOKEN_PIPE:        "PIPE",
+	TOKEN_AMPERSAND:   "AMPERSAND",
+	TOKEN_AT:          "AT",
+	TOKEN_CARET:       "CARET",
+	TOKEN_SET:         "SET",
+	TOKEN_DISCARD:     "DISCARD",
+	TOKEN_CONDITIONAL: "CONDITIONAL",
+}
+
+func (typ TokenType) String() string {
+	if int(typ) < len(tokenNames) {
+		return tokenNames[typ]
+	}
+	return fmt.Sprintf("TokenType(%d)", typ)
+}
+
+// MarshalText represents token types by their names in JSON.
+func (typ TokenType) MarshalText() ([]byte, error) {
+	return []byte(typ.String()), nil
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
 	Line    int
 	Column  int
+	Position
+
+	// Trivia holds the comments and blank lines around the token once attached by AttachTrivia, and
+	// is nil otherwise.
+	Trivia *Trivia
+}
+
+// Position locates the bytes of a token in the input, so that the source can be sliced with
+// input[Offset:Offset+Length] without going through lines and columns.
+type Position struct {
+	// Offset is the index of the first byte of the token.
+	Offset int
+	// Length is the number of bytes of the token (0 for EOF).
+	Length int
+	// Source is the input of the token, nil when the lexer was not created from a Source.
+	Source *Source
+}
+
+// End returns the offset of the byte following the token.
+func (pos Position) End() int {
+	return pos.Offset + pos.Length
 }
@@ -0,0 +1,49 @@
+package lex
+
+import "strings"
+
+// Trivia is what surrounds a token without being a part of the program: comments and blank lines.
+// Tools that reprint source code need it to keep what the parser ignores.
+type Trivia struct {
+	// Leading are the comments between the previous token (or its trailing comments) and the token.
+	Leading []Comment
+
+	// Blank is the number of blank lines right before the token, after its leading comments.
+	Blank int
+
+	// Trailing are the comments starting on the line where the token ends, e.g. `; one` in
+	// `(f 1 ; one`.
+	Trailing []Token
+}
+
+// Comment is a leading comment along with the number of blank lines right before it.
+type Comment struct {
+	Token
+	Blank int
+}
+
+// AttachTrivia returns tokens without their comments, every remaining token having its Trivia.
+// Comments after the last token are the leading trivia of EOF.
+func AttachTrivia(tokens []Token) []Token {
+	res := []Token{}
+	trivia := &Trivia{}
+
+	// end is the line where the previous token or comment ends, 0 before the first one.
+	end := 0
+	for _, tok := range tokens {
+		blank := max(0, tok.Line-end-1)
+		if tok.Type != TOKEN_COMMENT {
+			trivia.Blank = blank
+			tok.Trivia = trivia
+			res = append(res, tok)
+			trivia = &Trivia{}
+		} else if len(res) > 0 && len(trivia.Leading) == 0 && tok.Line == end {
+			previous := res[len(res)-1].Trivia
+			previous.Trailing = append(previous.Trailing, tok)
+		} else {
+			trivia.Leading = append(trivia.Leading, Comment{Token: tok, Blank: blank})
+		}
+		end = tok.Line + strings.Count(tok.Literal, "\n")
+	}
+	return res
+}
@@ -0,0 +1,41 @@
+package lex
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAttachTrivia(t *testing.T) {
+	input := "#!/usr/bin/env harp\n; Header.\n\n\n(f 1 ; One.\n #| A. |# #| B. |#\n\n 2) #| C. |#\n; End.\n"
+	tokens, _ := NewLexer(input).Tokenize()
+
+	// Every token is described as "leading comments (with their blank lines) / blank lines / literal
+	// / trailing comments".
+	got := []string{}
+	for _, tok := range AttachTrivia(tokens) {
+		leading := []string{}
+		for _, c := range tok.Trivia.Leading {
+			leading = append(leading, fmt.Sprintf("%d%q", c.Blank, c.Literal))
+		}
+		trailing := []string{}
+		for _, c := range tok.Trivia.Trailing {
+			trailing = append(trailing, c.Literal)
+		}
+		got = append(got, fmt.Sprintf("%s/%d/%s/%s",
+			strings.Join(leading, " "), tok.Trivia.Blank, tok.Literal, strings.Join(trailing, " ")))
+	}
+
+	expected := []string{
+		`0"#!/usr/bin/env harp" 0"; Header."/2/(/`,
+		"/0/f/",
+		"/0/1/; One.",
+		`0"#| A. |#" 0"#| B. |#"/1/2/`,
+		"/0/)/#| C. |#",
+		`0"; End."/0//`,
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,1278 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+////////////
+// Errors //
+////////////
+
+type ParseError struct {
+	// Token is the token that was being parsed when the error occured.
+	lex.Token
+
+	// Reason explains what triggered the error.
+	Reason ParseFailure
+}
+
+func (pe ParseError) Error() string {
+	return fmt.Sprintf("parse error %s: %s", pe.Location(), pe.Reason)
+}
+
+// Unwrap exposes the reason of the error, so that errors.Is(err, parse.EofInForm) holds for any
+// parse error caused by an unterminated form.
+func (pe ParseError) Unwrap() error {
+	return pe.Reason
+}
+
+// ParseFailure describes what caused the parser to fail.
+// It can be followed by additional information specified after `: `.
+// When testing if two parse failures are of the same kind, use the `Same` method or errors.Is.
+type ParseFailure string
+
+func (pf ParseFailure) Error() string {
+	return string(pf)
+}
+
+// Is makes errors.Is compare parse failures by kind, ignoring the additional information.
+func (pf ParseFailure) Is(target error) bool {
+	other, ok := target.(ParseFailure)
+	return ok && pf.Same(other)
+}
+
+// Code returns the stable identifier of the kind of failure (e.g. PAR0001), or an empty string for
+// an unknown failure.
+func (pf ParseFailure) Code() string {
+	return parseCodes[ParseFailure(pf.Cause())]
+}
+
+// Detail returns the additional information following the cause, or an empty string when there is
+// none.
+func (pf ParseFailure) Detail() string {
+	_, detail, _ := strings.Cut(string(pf), ": ")
+	return detail
+}
+
+func (pf ParseFailure) Cause() string {
+	colon := strings.Index(string(pf), ": ")
+	if colon < 0 {
+		return string(pf)
+	}
+
+	return string(pf[:colon])
+}
+
+func (pf ParseFailure) Same(other ParseFailure) bool {
+	return pf.Cause() == other.Cause()
+}
+
+// WithLiteral builds a new ParseFailure by adding the given literal at the end.
+func (pf ParseFailure) WithLiteral(literal string) ParseFailure {
+	return ParseFailure(fmt.Sprintf("%s: %q", pf, literal))
+}
+
+const (
+	EofInForm          ParseFailure = "met EOF before the end of the form"
+	UnexpectedCloser   ParseFailure = "met closing delimiter without matching opening delimiter"
+	MismatchedCloser   ParseFailure = "met closing delimiter that does not match the opening delimiter"
+	UnsupportedToken   ParseFailure = "met token that cannot start a form"
+	EmptyCall          ParseFailure = "met empty parentheses"
+	InvalidFloat       ParseFailure = "met invalid floating point number"
+	InvalidString      ParseFailure = "met invalid escape sequence in string"
+	ExpectedSymbol     ParseFailure = "expected a symbol"
+	ExpectedVector     ParseFailure = "expected a vector"
+	MissingForm        ParseFailure = "met end of special form before a required form"
+	TooManyForms       ParseFailure = "met unexpected form at the end of special form"
+	OddBindings        ParseFailure = "met binding vector with an odd number of forms"
+	OddMap             ParseFailure = "met map literal with an odd number of forms"
+	NonAtomKey         ParseFailure = "met map key that is not an atom"
+	DuplicateKey       ParseFailure = "met duplicate map key"
+	EmptyClause        ParseFailure = "met empty when clause"
+	MisplacedElse      ParseFailure = "met when clause after else clause"
+	ParameterAfterRest ParseFailure = "met parameter after the rest parameter"
+	ExpectedList       ParseFailure = "expected a list"
+	NonKeywordFeature  ParseFailure = "met reader conditional feature that is not a keyword"
+	OddConditional     ParseFailure = "met reader conditional with an odd number of forms"
+	NonAtomElement     ParseFailure = "met set element that is not an atom"
+	DuplicateElement   ParseFailure = "met duplicate set element"
+	ExpectedMetadata   ParseFailure = "expected a map or a keyword as metadata"
+	ExpectedField      ParseFailure = "expected a field name right after the dot"
+	ConditionalSyntax  ParseFailure = "met reader conditional in place of the syntax of a special form"
+)
+
+// parseCodes identifies the kinds of parse failures independently of their messages.
+// Codes are part of the interface of the parser: new failures must get new codes and the codes of
+// removed failures must not be reused: PAR0006 was for integers that did not fit in 64 bits.
+var parseCodes = map[ParseFailure]string{
+	EofInForm:          "PAR0001",
+	UnexpectedCloser:   "PAR0002",
+	MismatchedCloser:   "PAR0003",
+	UnsupportedToken:   "PAR0004",
+	EmptyCall:          "PAR0005",
+	InvalidFloat:       "PAR0007",
+	InvalidString:      "PAR0008",
+	ExpectedSymbol:     "PAR0009",
+	ExpectedVector:     "PAR0010",
+	MissingForm:        "PAR0011",
+	TooManyForms:       "PAR0012",
+	OddBindings:        "PAR0013",
+	OddMap:             "PAR0014",
+	NonAtomKey:         "PAR0015",
+	DuplicateKey:       "PAR0016",
+	EmptyClause:        "PAR0017",
+	MisplacedElse:      "PAR0018",
+	ParameterAfterRest: "PAR0019",
+	ExpectedList:       "PAR0020",
+	NonKeywordFeature:  "PAR0021",
+	OddConditional:     "PAR0022",
+	NonAtomElement:     "PAR0023",
+	DuplicateElement:   "PAR0024",
+	ExpectedMetadata:   "PAR0025",
+	ExpectedField:      "PAR0026",
+	ConditionalSyntax:  "PAR0027",
+}
+
+////////////
+// Parser //
+////////////
+
+// Parser performs syntactic analysis for Harp source code, that is to say it turns the tokens
+// produced by the lexer into AST nodes.
+//
+// Comments are skipped and lexical errors are returned as is.
+type Parser struct {
+	// tokens are the tokens being parsed, without comments.
+	tokens *lex.TokenStream
+
+	// last is the last token consumed, whose end is the end of the form being completed.
+	last lex.Token
+
+	// ids is the number of identifiers assigned to nodes.
+	ids ast.NodeID
+
+	// selected is the form of the reader conditional at the next token, parsed by skip and
+	// returned by primary, nil when there is none.
+	selected *selection
+}
+
+// selection is the form selected by a reader conditional, with the state of the parser after it.
+type selection struct {
+	form ast.Expr
+	// open is the #? token of the conditional.
+	open lex.Token
+	// end marks the token following the conditional.
+	end lex.Mark
+	// last is the closing parenthesis of the conditional.
+	last lex.Token
+}
+
+func NewParser(lexer *lex.Lexer) *Parser {
+	return &Parser{tokens: lex.NewTokenStream(lexer)}
+}
+
+// Parse parses all the remaining forms until EOF.
+func (p *Parser) Parse() ([]ast.Expr, error) {
+	forms := []ast.Expr{}
+	for {
+		form, err := p.ParseForm()
+		if err == io.EOF {
+			return forms, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		forms = append(forms, form)
+	}
+}
+
+// ParseForm parses the next top-level form, returning io.EOF when there is none left.
+func (p *Parser) ParseForm() (ast.Expr, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Type == lex.TOKEN_EOF {
+		return nil, io.EOF
+	}
+
+	return p.form()
+}
+
+////////////
+// Tokens //
+
+// peek returns the next token without consuming it.
+func (p *Parser) peek() (lex.Token, error) {
+	if err := p.skip(); err != nil {
+		return lex.Token{}, err
+	}
+
+	tok, err := p.tokens.Peek()
+	if err != nil { // Checked to avoid wrapping a nil *LexicalError in a non-nil error.
+		return tok, err
+	}
+	return tok, nil
+}
+
+// next consumes and returns the next token.
+func (p *Parser) next() (lex.Token, error) {
+	if err := p.skip(); err != nil {
+		return lex.Token{}, err
+	}
+
+	tok, err := p.tokens.Next()
+	if err != nil {
+		return tok, err
+	}
+	p.last = tok
+	return tok, nil
+}
+
+// skip consumes the forms that read as nothing, so that peek and next see the token after them:
+// discarded forms (#_ form) and reader conditionals selecting no form.
+func (p *Parser) skip() error {
+	for {
+		tok, err := p.tokens.Peek()
+		if err != nil {
+			return err
+		}
+
+		switch tok.Type {
+		case lex.TOKEN_DISCARD:
+			p.tokens.Next()
+			if _, err := p.prefixed(tok); err != nil {
+				return err
+			}
+		case lex.TOKEN_CONDITIONAL:
+			if p.selected != nil && p.selected.open == tok {
+				return nil
+			}
+
+			// The conditional is parsed once. When it selects a form, the stream goes back to #? so
+			// that the form is seen as starting there, and primary returns it.
+			mark, last := p.tokens.Mark(), p.last
+			p.tokens.Next()
+			form, ok, err := p.conditional(tok)
+			if err != nil {
+				p.tokens.Release(mark)
+				return err
+			}
+			if ok {
+				p.selected = &selection{form: form, open: tok, end: p.tokens.Mark(), last: p.last}
+				p.tokens.Reset(mark)
+				p.last = last
+				return nil
+			}
+			p.tokens.Release(mark)
+		default:
+			return nil
+		}
+	}
+}
+
+// expect consumes the next token and fails if it is not of the given type.
+// open is the token opening the form being parsed, used to report EOF.
+func (p *Parser) expect(typ lex.TokenType, open lex.Token, fail ParseFailure) (lex.Token, error) {
+	tok, err := p.next()
+	if err != nil {
+		return tok, err
+	}
+
+	switch tok.Type {
+	case typ:
+		return tok, nil
+	case lex.TOKEN_EOF:
+		return tok, &ParseError{open, EofInForm}
+	case lex.TOKEN_CONDITIONAL:
+		// Reported as such since the conditional could select the expected syntax, see conditional.
+		if typ != lex.TOKEN_RPAREN && typ != lex.TOKEN_RBRACKET {
+			return tok, &ParseError{tok, ConditionalSyntax}
+		}
+	}
+
+	return tok, &ParseError{tok, fail.WithLiteral(tok.Literal)}
+}
+
+///////////////
+// Positions //
+
+// start returns the position of the first character of a token.
+func start(tok lex.Token) ast.Pos {
+	return ast.Pos{Line: tok.Line, Column: tok.Column, Offset: tok.Offset}
+}
+
+// stop returns the position right after the last character of a token.
+func stop(tok lex.Token) ast.Pos {
+	res := ast.Pos{Line: tok.Line, Column: tok.Column, Offset: tok.End()}
+	if last := strings.LastIndexByte(tok.Literal, '\n'); last >= 0 {
+		res.Line += strings.Count(tok.Literal, "\n")
+		res.Column = utf8.RuneCountInString(tok.Literal[last+1:])
+	} else {
+		res.Column += utf8.RuneCountInString(tok.Literal)
+	}
+	return res
+}
+
+// node returns the span of a new node, identified by the next identifier.
+func (p *Parser) node(start, stop ast.Pos) ast.Span {
+	p.ids++
+	return ast.Span{Start: start, Stop: stop, ID: p.ids}
+}
+
+// span returns the span of a form going from open to the last token consumed.
+func (p *Parser) span(open lex.Token) ast.Span {
+	return p.node(start(open), stop(p.last))
+}
+
+// spanTo returns the span of a form going from open to the end of a form.
+func (p *Parser) spanTo(open lex.Token, form ast.Expr) ast.Span {
+	return p.node(start(open), form.End())
+}
+
+///////////
+// Forms //
+
+// bases maps the token types of prefixed integers to their base.
+var bases = map[lex.TokenType]int{lex.TOKEN_HEX: 16, lex.TOKEN_OCT: 8, lex.TOKEN_BIN: 2}
+
+// form parses the next form, which must exist, along with the fields accessed on it.
+func (p *Parser) form() (ast.Expr, error) {
+	form, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	return p.accesses(form)
+}
+
+// accesses parses the fields accessed on a form, as in form.field.subfield. The dot must be right
+// after the form, so that a misplaced dot (a . b) is not mistaken for a field.
+func (p *Parser) accesses(form ast.Expr) (ast.Expr, error) {
+	for {
+		dot, lexErr := p.tokens.Peek()
+		if lexErr != nil {
+			return nil, lexErr
+		}
+		// The last token rather than the end of the form, which is inside a reader conditional
+		// selecting it.
+		if dot.Type != lex.TOKEN_DOT || dot.Offset != p.last.End() {
+			return form, nil
+		}
+		p.next()
+
+		field, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if field.Type != lex.TOKEN_SYMBOL || field.Offset != dot.End() {
+			return nil, &ParseError{dot, ExpectedField}
+		}
+		name := ast.Symbol{Name: field.Literal, Span: p.node(start(field), stop(field))}
+		form = ast.Access{Target: form, Field: name, Span: p.node(form.Pos(), stop(field))}
+	}
+}
+
+// primary parses the next form, which must exist, without the fields accessed on it.
+func (p *Parser) primary() (ast.Expr, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	// Only atoms are identified here, so that compound forms are identified after their children.
+	span := func() ast.Span { return p.node(start(tok), stop(tok)) }
+
+	switch tok.Type {
+	case lex.TOKEN_INT, lex.TOKEN_HEX, lex.TOKEN_OCT, lex.TOKEN_BIN:
+		digits, base := tok.Literal, 10
+		if tok.Type != lex.TOKEN_INT {
+			digits, base = tok.Literal[2:], bases[tok.Type] // Skip base prefix.
+		}
+		// The lexer only accepts valid digits, so parsing can only fail when the value is too large.
+		if value, err := strconv.ParseInt(digits, base, 64); err == nil {
+			return ast.Int64{Value: value, Span: span()}, nil
+		}
+		value, _ := new(big.Int).SetString(digits, base)
+		return ast.BigInt{Value: value, Span: span()}, nil
+	case lex.TOKEN_FLOAT:
+		value, err := strconv.ParseFloat(tok.Literal, 64)
+		if err != nil {
+			return nil, &ParseError{tok, InvalidFloat.WithLiteral(tok.Literal)}
+		}
+		return ast.Float64{Value: value, Span: span()}, nil
+	case lex.TOKEN_DQSTRING:
+		value, err := lex.DecodeString(tok.Literal)
+		if err, ok := err.(*lex.EscapeError); ok {
+			// Point to the escape sequence rather than to the start of the string.
+			at := lex.Token{
+				Type:    tok.Type,
+				Literal: err.Sequence,
+				Line:    tok.Line,
+				Column:  tok.Column + err.Column,
+				Position: lex.Position{
+					Offset: tok.Offset + err.Offset,
+					Length: len(err.Sequence),
+					Source: tok.Source,
+				},
+			}
+			return nil, &ParseError{at, InvalidString.WithLiteral(err.Sequence)}
+		}
+		return ast.String{Value: value, Span: span()}, nil
+	case lex.TOKEN_RAWSTRING:
+		return ast.String{Value: tok.Literal[2 : len(tok.Literal)-2], Span: span()}, nil // Remove #" and "#.
+	case lex.TOKEN_HEREDOC:
+		return ast.String{Value: lex.HeredocValue(tok.Literal), Span: span()}, nil
+	case lex.TOKEN_CHAR:
+		value, _ := lex.DecodeChar(tok.Literal) // Validated by the lexer.
+		return ast.Rune{Value: value, Span: span()}, nil
+	case lex.TOKEN_SYMBOL:
+		switch tok.Literal {
+		case "true":
+			return ast.Bool{Value: true, Span: span()}, nil
+		case "false":
+			return ast.Bool{Value: false, Span: span()}, nil
+		}
+		return ast.Symbol{Name: tok.Literal, Span: span()}, nil
+	case lex.TOKEN_KEYWORD:
+		return ast.Keyword{Name: tok.Literal[1:], Span: span()}, nil
+	case lex.TOKEN_LPAREN:
+		return p.list(tok)
+	case lex.TOKEN_LBRACKET:
+		forms, err := p.formsUntil(lex.TOKEN_RBRACKET, tok)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Array{Elements: forms, Span: p.span(tok)}, nil
+	case lex.TOKEN_LBRACE:
+		return p.mapLiteral(tok)
+	case lex.TOKEN_SET:
+		return p.setLiteral(tok)
+	case lex.TOKEN_CONDITIONAL:
+		// Only reached when a form is selected, see skip.
+		selected := p.selected
+		p.selected = nil
+		p.tokens.Reset(selected.end)
+		p.last = selected.last
+		return selected.form, nil
+	case lex.TOKEN_CARET:
+		return p.withMetadata(tok)
+	case lex.TOKEN_QUOTE, lex.TOKEN_BACKQUOTE, lex.TOKEN_UNQUOTE, lex.TOKEN_SPLICE, lex.TOKEN_AT:
+		return p.quotation(tok)
+	case lex.TOKEN_RPAREN, lex.TOKEN_RBRACKET, lex.TOKEN_RBRACE:
+		return nil, &ParseError{tok, UnexpectedCloser}
+	case lex.TOKEN_EOF:
+		return nil, &ParseError{tok, EofInForm}
+	}
+
+	return nil, &ParseError{tok, UnsupportedToken.WithLiteral(tok.Literal)}
+}
+
+// prefixed parses the form following a prefix, reporting EOF at the prefix.
+func (p *Parser) prefixed(prefix lex.Token) (ast.Expr, error) {
+	next, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if next.Type == lex.TOKEN_EOF {
+		return nil, &ParseError{prefix, EofInForm}
+	}
+
+	return p.form()
+}
+
+// quotation parses the form following a prefix (quotation or deref) and wraps it in the matching node.
+func (p *Parser) quotation(prefix lex.Token) (ast.Expr, error) {
+	form, err := p.prefixed(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	span := p.spanTo(prefix, form)
+	switch prefix.Type {
+	case lex.TOKEN_QUOTE:
+		return ast.Quote{Form: form, Span: span}, nil
+	case lex.TOKEN_BACKQUOTE:
+		return ast.Quasiquote{Form: form, Span: span}, nil
+	case lex.TOKEN_UNQUOTE:
+		return ast.Unquote{Form: form, Span: span}, nil
+	case lex.TOKEN_AT:
+		return ast.Deref{Form: form, Span: span}, nil
+	}
+	return ast.UnquoteSplice{Form: form, Span: span}, nil
+}
+
+// withMetadata parses the metadata following a caret and attaches it to the form that comes next.
+func (p *Parser) withMetadata(caret lex.Token) (ast.Expr, error) {
+	data, err := p.metadata(caret)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := p.prefixed(caret)
+	if err != nil {
+		return nil, err
+	}
+
+	// The metadata of the name of a definition is closer to it, so it takes precedence.
+	switch form := form.(type) {
+	case ast.Def:
+		form.Meta = p.mergeMetadata(data, form.Meta)
+		return form, nil
+	case ast.Fun:
+		form.Meta = p.mergeMetadata(data, form.Meta)
+		return form, nil
+	case ast.Struct:
+		form.Meta = p.mergeMetadata(data, form.Meta)
+		return form, nil
+	}
+	return ast.Meta{Data: *data, Form: form, Span: p.spanTo(caret, form)}, nil
+}
+
+// metadata parses the metadata following a caret, then the metadata of the carets immediately
+// following it if any, merged into a single map spanning all of them. Entries closer to the form
+// take precedence.
+func (p *Parser) metadata(caret lex.Token) (*ast.Map, error) {
+	res := &ast.Map{Entries: []ast.Entry{}}
+	first := start(caret)
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := p.prefixed(caret)
+		if err != nil {
+			return nil, err
+		}
+		switch data := data.(type) {
+		case ast.Map:
+			res.Entries = merge(res.Entries, data.Entries)
+		case ast.Keyword:
+			value := ast.Bool{Value: true, Span: p.node(data.Pos(), data.End())}
+			res.Entries = merge(res.Entries, []ast.Entry{{Key: data, Value: value}})
+		default:
+			return nil, &ParseError{tok, ExpectedMetadata.WithLiteral(tok.Literal)}
+		}
+		next, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if next.Type != lex.TOKEN_CARET {
+			res.Span = p.node(first, data.End())
+			return res, nil
+		}
+		caret, _ = p.next()
+	}
+}
+
+// nameMetadata parses the optional metadata preceding the name of a definition, returning nil when
+// there is none.
+func (p *Parser) nameMetadata() (*ast.Map, error) {
+	tok, err := p.peek()
+	if err != nil || tok.Type != lex.TOKEN_CARET {
+		return nil, err
+	}
+
+	p.next()
+	return p.metadata(tok)
+}
+
+// mergeMetadata returns the metadata of a definition preceded by outer metadata, the entries of
+// closer taking precedence. The result spans both, and is nil when both are nil.
+func (p *Parser) mergeMetadata(outer, closer *ast.Map) *ast.Map {
+	switch {
+	case outer == nil:
+		return closer
+	case closer == nil:
+		return outer
+	}
+	return &ast.Map{
+		Entries: merge(outer.Entries, closer.Entries),
+		Span:    p.node(outer.Pos(), closer.End()),
+	}
+}
+
+// merge returns the entries of both lists, those of closer replacing the entries of outer with the
+// same key.
+func merge(outer, closer []ast.Entry) []ast.Entry {
+	res := slices.Clone(outer)
+	for _, entry := range closer {
+		i := slices.IndexFunc(res, func(other ast.Entry) bool { return value(other.Key) == value(entry.Key) })
+		if i < 0 {
+			res = append(res, entry)
+		} else {
+			res[i] = entry
+		}
+	}
+	return res
+}
+
+// closes tells whether tok is the closing delimiter of the sequence opened by open, and fails when
+// tok is EOF or another closing delimiter.
+func closes(tok lex.Token, closer lex.TokenType, open lex.Token) (bool, error) {
+	switch tok.Type {
+	case closer:
+		return true, nil
+	case lex.TOKEN_EOF:
+		return false, &ParseError{open, EofInForm}
+	case lex.TOKEN_RPAREN, lex.TOKEN_RBRACKET, lex.TOKEN_RBRACE:
+		return false, &ParseError{tok, MismatchedCloser.WithLiteral(tok.Literal)}
+	}
+	return false, nil
+}
+
+// formsUntil parses forms until the given closing delimiter, which is consumed.
+// open is the token opening the sequence.
+func (p *Parser) formsUntil(closer lex.TokenType, open lex.Token) ([]ast.Expr, error) {
+	forms := []ast.Expr{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if closed, err := closes(tok, closer, open); err != nil {
+			return nil, err
+		} else if closed {
+			p.next()
+			return forms, nil
+		}
+
+		form, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+		forms = append(forms, form)
+	}
+}
+
+// list parses a parenthesized form, either a call or a special form.
+func (p *Parser) list(open lex.Token) (ast.Expr, error) {
+	head, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case lex.TOKEN_RPAREN:
+		return nil, &ParseError{open, EmptyCall}
+	case lex.TOKEN_SYMBOL:
+		if special, ok := specialForms[head.Literal]; ok {
+			p.next()
+			return special(p, open)
+		}
+	}
+
+	function, err := p.form()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := p.formsUntil(lex.TOKEN_RPAREN, open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Call{Function: function, Arguments: args, Span: p.span(open)}, nil
+}
+
+// mapLiteral parses the key/value pairs of a map literal.
+// Keys must be atoms so that they can be compared when the map is built.
+func (p *Parser) mapLiteral(open lex.Token) (ast.Expr, error) {
+	res := ast.Map{Entries: []ast.Entry{}}
+	keys := map[any]bool{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if closed, err := closes(tok, lex.TOKEN_RBRACE, open); err != nil {
+			return nil, err
+		} else if closed {
+			p.next()
+			res.Span = p.span(open)
+			return res, nil
+		}
+
+		key, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAtom(key) {
+			return nil, &ParseError{tok, NonAtomKey}
+		}
+		if keys[value(key)] {
+			return nil, &ParseError{tok, DuplicateKey.WithLiteral(tok.Literal)}
+		}
+		keys[value(key)] = true
+
+		closer, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if closed, err := closes(closer, lex.TOKEN_RBRACE, open); err != nil {
+			return nil, err
+		} else if closed {
+			return nil, &ParseError{closer, OddMap}
+		}
+
+		val, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+		res.Entries = append(res.Entries, ast.Entry{Key: key, Value: val})
+	}
+}
+
+// setLiteral parses the elements of a set literal, which must be atoms like map keys.
+func (p *Parser) setLiteral(open lex.Token) (ast.Expr, error) {
+	res := ast.Set{Elements: []ast.Expr{}}
+	elements := map[any]bool{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if closed, err := closes(tok, lex.TOKEN_RBRACE, open); err != nil {
+			return nil, err
+		} else if closed {
+			p.next()
+			res.Span = p.span(open)
+			return res, nil
+		}
+
+		element, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAtom(element) {
+			return nil, &ParseError{tok, NonAtomElement}
+		}
+		if elements[value(element)] {
+			return nil, &ParseError{tok, DuplicateElement.WithLiteral(tok.Literal)}
+		}
+		elements[value(element)] = true
+		res.Elements = append(res.Elements, element)
+	}
+}
+
+// isAtom tells whether a form can be compared, as required by map keys and set elements.
+func isAtom(form ast.Expr) bool {
+	switch form.(type) {
+	case ast.Int64, ast.BigInt, ast.Float64, ast.String, ast.Rune, ast.Bool, ast.Symbol, ast.Keyword:
+		return true
+	}
+	return false
+}
+
+// bigKey is the value of a big int literal, whose digits are compared rather than its pointer.
+type bigKey string
+
+// value returns an atom without its position, so that atoms can be compared by value.
+func value(atom ast.Expr) any {
+	switch atom := atom.(type) {
+	case ast.Int64:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.BigInt:
+		return bigKey(atom.Value.String())
+	case ast.Float64:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.String:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.Rune:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.Bool:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.Symbol:
+		atom.Span = ast.Span{}
+		return atom
+	case ast.Keyword:
+		atom.Span = ast.Span{}
+		return atom
+	}
+	return atom
+}
+
+// feature is the reader conditional feature of this implementation.
+// The :default feature applies when no other feature does.
+const feature = "harp"
+
+// conditional parses the list of feature/form pairs following #? and returns the form of the first
+// feature that applies, ok being false when none does.
+//
+// A conditional reads as the form it selects, wherever a form is expected, fields being accessed on
+// the whole conditional as in #?(:harp [1 2]).x. It cannot stand for the syntax of a special form
+// though, like a defined name, a binding or parameter vector or a when clause, which is reported as
+// ConditionalSyntax. In the head of a list, it selects the function of a call, never a special form.
+func (p *Parser) conditional(open lex.Token) (form ast.Expr, ok bool, err error) {
+	if _, err := p.expect(lex.TOKEN_LPAREN, open, ExpectedList); err != nil {
+		return nil, false, err
+	}
+
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch tok.Type {
+		case lex.TOKEN_RPAREN:
+			p.next()
+			return form, ok, nil
+		case lex.TOKEN_EOF:
+			return nil, false, &ParseError{open, EofInForm}
+		}
+
+		key, err := p.form()
+		if err != nil {
+			return nil, false, err
+		}
+		keyword, isKeyword := key.(ast.Keyword)
+		if !isKeyword {
+			return nil, false, &ParseError{tok, NonKeywordFeature.WithLiteral(tok.Literal)}
+		}
+
+		closer, err := p.peek()
+		if err != nil {
+			return nil, false, err
+		}
+		switch closer.Type {
+		case lex.TOKEN_RPAREN:
+			return nil, false, &ParseError{closer, OddConditional}
+		case lex.TOKEN_EOF:
+			return nil, false, &ParseError{open, EofInForm}
+		}
+
+		value, err := p.form()
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok && (keyword.Name == feature || keyword.Name == "default") {
+			form, ok = value, true
+		}
+	}
+}
+
+///////////////////
+// Special forms //
+
+// specialForm parses the remainder of a special form, open being its opening parenthesis and the
+// head symbol being already consumed.
+type specialForm func(p *Parser, open lex.Token) (ast.Expr, error)
+
+// specialForms maps the head symbols of special forms to their parsers.
+// It is populated in init to avoid an initialization cycle through form.
+var specialForms map[string]specialForm
+
+func init() {
+	specialForms = map[string]specialForm{
+		"break":    parseBreak,
+		"continue": parseContinue,
+		"def":      parseDef,
+		"fun":      parseFun,
+		"lambda":   parseLambda,
+		"let":      parseLet,
+		"loop":     parseLoop,
+		"set":      parseSet,
+		"struct":   parseStruct,
+		"tie":      parseTie,
+		"when":     parseWhen,
+	}
+}
+
+// (break) or (break value)
+func parseBreak(p *Parser, open lex.Token) (ast.Expr, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Type == lex.TOKEN_RPAREN {
+		p.next()
+		return ast.Break{Span: p.span(open)}, nil
+	}
+
+	value, err := p.form()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(open); err != nil {
+		return nil, err
+	}
+
+	return ast.Break{Value: value, Span: p.span(open)}, nil
+}
+
+// (continue)
+func parseContinue(p *Parser, open lex.Token) (ast.Expr, error) {
+	if err := p.end(open); err != nil {
+		return nil, err
+	}
+
+	return ast.Continue{Span: p.span(open)}, nil
+}
+
+// (def ^metadata name value), where ^metadata is optional
+func parseDef(p *Parser, open lex.Token) (ast.Expr, error) {
+	meta, err := p.nameMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	name, value, err := p.nameAndValue(open)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(open); err != nil {
+		return nil, err
+	}
+
+	return ast.Def{Name: name, Value: value, Meta: meta, Span: p.span(open)}, nil
+}
+
+// (fun ^metadata name [parameters... & rest] body...), where ^metadata and & rest are optional
+func parseFun(p *Parser, open lex.Token) (ast.Expr, error) {
+	meta, err := p.nameMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := p.symbol(open)
+	if err != nil {
+		return nil, err
+	}
+
+	params, rest, body, err := p.parametersAndBody(open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Fun{Name: name, Meta: meta, Parameters: params, Rest: rest, Body: body, Span: p.span(open)}, nil
+}
+
+// (lambda [parameters... & rest] body...), where & rest is optional
+func parseLambda(p *Parser, open lex.Token) (ast.Expr, error) {
+	params, rest, body, err := p.parametersAndBody(open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Lambda{Parameters: params, Rest: rest, Body: body, Span: p.span(open)}, nil
+}
+
+// (let [name value...] body...)
+func parseLet(p *Parser, open lex.Token) (ast.Expr, error) {
+	bindings, err := p.bindings(open)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.formsUntil(lex.TOKEN_RPAREN, open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Let{Bindings: bindings, Body: body, Span: p.span(open)}, nil
+}
+
+// (loop [name value...] condition body...)
+func parseLoop(p *Parser, open lex.Token) (ast.Expr, error) {
+	bindings, err := p.bindings(open)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := p.required(open)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.formsUntil(lex.TOKEN_RPAREN, open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Loop{Bindings: bindings, Condition: condition, Body: body, Span: p.span(open)}, nil
+}
+
+// (set name value) or (set name.field... value)
+func parseSet(p *Parser, open lex.Token) (ast.Expr, error) {
+	name, err := p.symbol(open)
+	if err != nil {
+		return nil, err
+	}
+	target, err := p.accesses(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.required(open)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(open); err != nil {
+		return nil, err
+	}
+
+	return ast.Assign{Target: target, Value: value, Span: p.span(open)}, nil
+}
+
+// (struct ^metadata name [field default]...), where ^metadata is optional
+func parseStruct(p *Parser, open lex.Token) (ast.Expr, error) {
+	meta, err := p.nameMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := p.symbol(open)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []ast.Binding{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == lex.TOKEN_RPAREN {
+			p.next()
+			return ast.Struct{Name: name, Meta: meta, Fields: fields, Span: p.span(open)}, nil
+		}
+
+		field, err := p.expect(lex.TOKEN_LBRACKET, open, ExpectedVector)
+		if err != nil {
+			return nil, err
+		}
+
+		variable, value, err := p.nameAndValue(field)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(lex.TOKEN_RBRACKET, field, TooManyForms); err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, ast.Binding{Variable: variable, Value: value, Span: p.span(field)})
+	}
+}
+
+// (tie function args...)
+func parseTie(p *Parser, open lex.Token) (ast.Expr, error) {
+	function, err := p.required(open)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := p.formsUntil(lex.TOKEN_RPAREN, open)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Tie{Function: function, Args: args, Span: p.span(open)}, nil
+}
+
+// (when [condition body...]... [else body...])
+func parseWhen(p *Parser, open lex.Token) (ast.Expr, error) {
+	res := ast.When{Clauses: []ast.WhenClause{}}
+	seenElse := false
+
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == lex.TOKEN_RPAREN {
+			p.next()
+			res.Span = p.span(open)
+			return res, nil
+		}
+
+		clause, err := p.expect(lex.TOKEN_LBRACKET, open, ExpectedVector)
+		if err != nil {
+			return nil, err
+		}
+		if seenElse {
+			return nil, &ParseError{clause, MisplacedElse}
+		}
+
+		head, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if head.Type == lex.TOKEN_RBRACKET {
+			return nil, &ParseError{clause, EmptyClause}
+		}
+
+		if head.Type == lex.TOKEN_SYMBOL && head.Literal == "else" {
+			p.next()
+			seenElse = true
+			if res.Else, err = p.formsUntil(lex.TOKEN_RBRACKET, clause); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		condition, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := p.formsUntil(lex.TOKEN_RBRACKET, clause)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Clauses = append(res.Clauses, ast.WhenClause{Condition: condition, Body: body, Span: p.span(clause)})
+	}
+}
+
+/////////////////////////////
+// Special forms utilities //
+
+// end consumes the closing parenthesis of a special form.
+func (p *Parser) end(open lex.Token) error {
+	_, err := p.expect(lex.TOKEN_RPAREN, open, TooManyForms)
+	return err
+}
+
+// required parses a form that must be present before the end of the special form.
+func (p *Parser) required(open lex.Token) (ast.Expr, error) {
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.Type {
+	case lex.TOKEN_EOF:
+		return nil, &ParseError{open, EofInForm}
+	case lex.TOKEN_RPAREN, lex.TOKEN_RBRACKET:
+		return nil, &ParseError{tok, MissingForm}
+	}
+
+	return p.form()
+}
+
+// symbol parses a symbol that is not a boolean literal.
+func (p *Parser) symbol(open lex.Token) (ast.Symbol, error) {
+	tok, err := p.expect(lex.TOKEN_SYMBOL, open, ExpectedSymbol)
+	if err != nil {
+		return ast.Symbol{}, err
+	}
+	if tok.Literal == "true" || tok.Literal == "false" {
+		return ast.Symbol{}, &ParseError{tok, ExpectedSymbol.WithLiteral(tok.Literal)}
+	}
+
+	return ast.Symbol{Name: tok.Literal, Span: p.node(start(tok), stop(tok))}, nil
+}
+
+// nameAndValue parses a symbol followed by a required form.
+func (p *Parser) nameAndValue(open lex.Token) (ast.Symbol, ast.Expr, error) {
+	name, err := p.symbol(open)
+	if err != nil {
+		return name, nil, err
+	}
+
+	value, err := p.required(open)
+	return name, value, err
+}
+
+// parametersAndBody parses a vector of parameter symbols, optionally ending with & and the rest
+// parameter, followed by the forms of a body.
+func (p *Parser) parametersAndBody(open lex.Token) ([]ast.Symbol, *ast.Symbol, []ast.Expr, error) {
+	vec, err := p.expect(lex.TOKEN_LBRACKET, open, ExpectedVector)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	params := []ast.Symbol{}
+	var rest *ast.Symbol
+	for rest == nil {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if tok.Type == lex.TOKEN_RBRACKET {
+			p.next()
+			break
+		}
+
+		if tok.Type == lex.TOKEN_AMPERSAND {
+			p.next()
+			param, err := p.symbol(vec)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if _, err := p.expect(lex.TOKEN_RBRACKET, vec, ParameterAfterRest); err != nil {
+				return nil, nil, nil, err
+			}
+			rest = &param
+			continue
+		}
+
+		param, err := p.symbol(vec)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		params = append(params, param)
+	}
+
+	body, err := p.formsUntil(lex.TOKEN_RPAREN, open)
+	return params, rest, body, err
+}
+
+// bindings parses a vector of alternating names and values.
+func (p *Parser) bindings(open lex.Token) ([]ast.Binding, error) {
+	vec, err := p.expect(lex.TOKEN_LBRACKET, open, ExpectedVector)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []ast.Binding{}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == lex.TOKEN_RBRACKET {
+			p.next()
+			return res, nil
+		}
+
+		name, err := p.symbol(vec)
+		if err != nil {
+			return nil, err
+		}
+
+		tok, err = p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == lex.TOKEN_RBRACKET {
+			return nil, &ParseError{tok, OddBindings}
+		}
+
+		value, err := p.form()
+		if err != nil {
+			return nil, err
+		}
+		span := p.node(name.Pos(), value.End())
+		res = append(res, ast.Binding{Variable: name, Value: value, Span: span})
+	}
+}
@@ -0,0 +1,636 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Shorthands for atoms, struct literals of imported types must have keyed fields.
+func i64(value int64) ast.Int64     { return ast.Int64{Value: value} }
+func f64(value float64) ast.Float64 { return ast.Float64{Value: value} }
+func str(value string) ast.String   { return ast.String{Value: value} }
+func sym(name string) ast.Symbol    { return ast.Symbol{Name: name} }
+func kw(name string) ast.Keyword    { return ast.Keyword{Name: name} }
+func bigint(digits string) ast.BigInt {
+	value, _ := new(big.Int).SetString(digits, 10)
+	return ast.BigInt{Value: value}
+}
+func array(elements ...ast.Expr) ast.Array {
+	return ast.Array{Elements: append([]ast.Expr{}, elements...)}
+}
+func set(elements ...ast.Expr) ast.Set { return ast.Set{Elements: append([]ast.Expr{}, elements...)} }
+
+// hash builds a map from alternating keys and values.
+func hash(pairs ...ast.Expr) ast.Map {
+	res := ast.Map{Entries: []ast.Entry{}}
+	for i := 0; i < len(pairs); i += 2 {
+		res.Entries = append(res.Entries, ast.Entry{Key: pairs[i], Value: pairs[i+1]})
+	}
+	return res
+}
+
+// identities returns the identifiers of the nodes of a tree, in the order of their fields.
+func identities(tree any) []ast.NodeID {
+	res := []ast.NodeID{}
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer:
+			if !v.IsNil() {
+				visit(v.Elem())
+			}
+		case reflect.Slice:
+			for i := range v.Len() {
+				visit(v.Index(i))
+			}
+		case reflect.Struct:
+			if span, ok := v.Interface().(ast.Span); ok {
+				res = append(res, span.ID)
+				return
+			}
+			for i := range v.NumField() {
+				visit(v.Field(i))
+			}
+		}
+	}
+	visit(reflect.ValueOf(tree))
+	return res
+}
+
+func TestParser(t *testing.T) {
+	zeros := strings.Repeat("0", 64)
+	tests := []struct {
+		name     string
+		input    string
+		expected []ast.Expr
+	}{
+		{
+			name:  "Atoms",
+			input: `1 2.5 "a\tb" x true false`,
+			expected: []ast.Expr{
+				i64(1), f64(2.5), str("a\tb"), sym("x"), ast.Bool{Value: true}, ast.Bool{Value: false},
+			},
+		},
+		{
+			name:     "Integers with a base prefix",
+			input:    "0xff 0o17 0b101 0x7FFFFFFFFFFFFFFF",
+			expected: []ast.Expr{i64(255), i64(15), i64(5), i64(1<<63 - 1)},
+		},
+		{
+			name:  "Integers beyond int64",
+			input: "9223372036854775808 99999999999999999999 0x8000000000000000 0b1" + zeros,
+			expected: []ast.Expr{
+				bigint("9223372036854775808"), bigint("99999999999999999999"), bigint("9223372036854775808"),
+				bigint("18446744073709551616"),
+			},
+		},
+		{
+			name:     "Floats with an exponent",
+			input:    "1e3 2.5e-1 .5E1",
+			expected: []ast.Expr{f64(1000), f64(0.25), f64(5)},
+		},
+		{
+			name:     "String escapes",
+			input:    `"\x41\101\u00e9\n"`,
+			expected: []ast.Expr{str("AAé\n")},
+		},
+		{
+			name:     "Characters",
+			input:    `\a \space \u00e9`,
+			expected: []ast.Expr{ast.Rune{Value: 'a'}, ast.Rune{Value: ' '}, ast.Rune{Value: 'é'}},
+		},
+		{
+			name:     "Script with a shebang",
+			input:    "#!/usr/bin/env harp\n1",
+			expected: []ast.Expr{i64(1)},
+		},
+		{
+			name:  "Quotation",
+			input: "'x `(f ,a ,@b)",
+			expected: []ast.Expr{
+				ast.Quote{Form: sym("x")},
+				ast.Quasiquote{Form: ast.Call{Function: sym("f"), Arguments: []ast.Expr{
+					ast.Unquote{Form: sym("a")},
+					ast.UnquoteSplice{Form: sym("b")},
+				}}},
+			},
+		},
+		{
+			name:  "Deref",
+			input: "@x `(f , @a)",
+			expected: []ast.Expr{
+				ast.Deref{Form: sym("x")},
+				ast.Quasiquote{Form: ast.Call{Function: sym("f"), Arguments: []ast.Expr{
+					ast.Unquote{Form: ast.Deref{Form: sym("a")}},
+				}}},
+			},
+		},
+		{
+			name:     "Set",
+			input:    "#{1 :a \"b\"} #{}",
+			expected: []ast.Expr{set(i64(1), kw("a"), str("b")), set()},
+		},
+		{
+			name:  "Rune and big int atoms",
+			input: `{\a 1 99999999999999999999 2} #{\a 99999999999999999999 \b}`,
+			expected: []ast.Expr{
+				hash(ast.Rune{Value: 'a'}, i64(1), bigint("99999999999999999999"), i64(2)),
+				set(ast.Rune{Value: 'a'}, bigint("99999999999999999999"), ast.Rune{Value: 'b'}),
+			},
+		},
+		{
+			name:     "Discarded forms",
+			input:    "#_ x (f #_ (g 1) a #_ #_ b c) #_ d",
+			expected: []ast.Expr{ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("a")}}},
+		},
+		{
+			name:  "Reader conditionals",
+			input: "#?(:other 1 :harp 2 :default 3) [#?(:default 4) #?(:other 5) 6] #?(:harp #?(:harp 7))",
+			expected: []ast.Expr{
+				i64(2),
+				array(i64(4), i64(6)),
+				i64(7),
+			},
+		},
+		{
+			name:  "Reader conditionals in forms",
+			input: "#?(:harp [1 2]).x #?(:harp a.b).c (let [x #?(:harp 1)] x) (#?(:harp f) 1)",
+			expected: []ast.Expr{
+				ast.Access{Target: array(i64(1), i64(2)), Field: sym("x")},
+				ast.Access{Target: ast.Access{Target: sym("a"), Field: sym("b")}, Field: sym("c")},
+				ast.Let{Bindings: []ast.Binding{{Variable: sym("x"), Value: i64(1)}}, Body: []ast.Expr{sym("x")}},
+				ast.Call{Function: sym("f"), Arguments: []ast.Expr{i64(1)}},
+			},
+		},
+		{
+			name:  "Metadata",
+			input: `^:private ^{:doc "X." :private false} x ^{} [1]`,
+			expected: []ast.Expr{
+				ast.Meta{Data: hash(kw("private"), ast.Bool{Value: false}, kw("doc"), str("X.")), Form: sym("x")},
+				ast.Meta{Data: hash(), Form: array(i64(1))},
+			},
+		},
+		{
+			name: "Metadata of definitions",
+			input: `^{:doc "Outer." :a 1} (def ^{:doc "Inner."} x 1) (fun ^:private f [])
+				^:deprecated (struct P [x 0])`,
+			expected: []ast.Expr{
+				ast.Def{
+					Name:  sym("x"),
+					Value: i64(1),
+					Meta:  &ast.Map{Entries: hash(kw("doc"), str("Inner."), kw("a"), i64(1)).Entries},
+				},
+				ast.Fun{
+					Name:       sym("f"),
+					Meta:       &ast.Map{Entries: hash(kw("private"), ast.Bool{Value: true}).Entries},
+					Parameters: []ast.Symbol{},
+					Body:       []ast.Expr{},
+				},
+				ast.Struct{
+					Name:   sym("P"),
+					Meta:   &ast.Map{Entries: hash(kw("deprecated"), ast.Bool{Value: true}).Entries},
+					Fields: []ast.Binding{{Variable: sym("x"), Value: i64(0)}},
+				},
+			},
+		},
+		{
+			name:     "Raw string",
+			input:    `#"a\n` + "\n" + `"b"#`,
+			expected: []ast.Expr{str("a\\n\n\"b")},
+		},
+		{
+			name:     "Heredoc",
+			input:    "(f <<~END\n  a\n    \"b\"\n  END)",
+			expected: []ast.Expr{ast.Call{Function: sym("f"), Arguments: []ast.Expr{str("a\n  \"b\"\n")}}},
+		},
+		{
+			name:     "Empty input",
+			input:    " ; Only a comment.\n",
+			expected: []ast.Expr{},
+		},
+		{
+			name:  "Calls",
+			input: "(f) (f 1 (g x)) ((f 1) 2)",
+			expected: []ast.Expr{
+				ast.Call{Function: sym("f"), Arguments: []ast.Expr{}},
+				ast.Call{Function: sym("f"), Arguments: []ast.Expr{
+					i64(1), ast.Call{Function: sym("g"), Arguments: []ast.Expr{sym("x")}},
+				}},
+				ast.Call{
+					Function:  ast.Call{Function: sym("f"), Arguments: []ast.Expr{i64(1)}},
+					Arguments: []ast.Expr{i64(2)},
+				},
+			},
+		},
+		{
+			name:  "Collections",
+			input: "[1 [x]] [] {a 1 \"b\" [2]} {}",
+			expected: []ast.Expr{
+				array(i64(1), array(sym("x"))),
+				array(),
+				hash(sym("a"), i64(1), str("b"), array(i64(2))),
+				hash(),
+			},
+		},
+		{
+			name:  "Keywords",
+			input: "(f :opt {:k 1})",
+			expected: []ast.Expr{
+				ast.Call{Function: sym("f"), Arguments: []ast.Expr{
+					ast.Keyword{Name: "opt"},
+					hash(kw("k"), i64(1)),
+				}},
+			},
+		},
+		{
+			name:  "Comments inside forms",
+			input: "(f ; First argument.\n 1)",
+			expected: []ast.Expr{
+				ast.Call{Function: sym("f"), Arguments: []ast.Expr{i64(1)}},
+			},
+		},
+		{
+			name:  "Def and set",
+			input: "(def x 1) (set x (f x))",
+			expected: []ast.Expr{
+				ast.Def{Name: sym("x"), Value: i64(1)},
+				ast.Assign{Target: sym("x"), Value: ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("x")}}},
+			},
+		},
+		{
+			name:  "Field access",
+			input: "p.x (f a).b.c (set p.x 1) 'p.x a .5",
+			expected: []ast.Expr{
+				ast.Access{Target: sym("p"), Field: sym("x")},
+				ast.Access{
+					Target: ast.Access{Target: ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("a")}}, Field: sym("b")},
+					Field:  sym("c"),
+				},
+				ast.Assign{Target: ast.Access{Target: sym("p"), Field: sym("x")}, Value: i64(1)},
+				ast.Quote{Form: ast.Access{Target: sym("p"), Field: sym("x")}},
+				sym("a"),
+				f64(0.5),
+			},
+		},
+		{
+			name:  "Fun and lambda",
+			input: "(fun add [a b] (f a) (g b)) (lambda [] 1) (lambda [x])",
+			expected: []ast.Expr{
+				ast.Fun{
+					Name:       sym("add"),
+					Parameters: []ast.Symbol{{Name: "a"}, {Name: "b"}},
+					Body: []ast.Expr{
+						ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("a")}},
+						ast.Call{Function: sym("g"), Arguments: []ast.Expr{sym("b")}},
+					},
+				},
+				ast.Lambda{Parameters: []ast.Symbol{}, Body: []ast.Expr{i64(1)}},
+				ast.Lambda{Parameters: []ast.Symbol{{Name: "x"}}, Body: []ast.Expr{}},
+			},
+		},
+		{
+			name:  "Rest parameters",
+			input: "(fun f [a & more] more) (lambda [& all])",
+			expected: []ast.Expr{
+				ast.Fun{
+					Name:       sym("f"),
+					Parameters: []ast.Symbol{{Name: "a"}},
+					Rest:       &ast.Symbol{Name: "more"},
+					Body:       []ast.Expr{sym("more")},
+				},
+				ast.Lambda{Parameters: []ast.Symbol{}, Rest: &ast.Symbol{Name: "all"}, Body: []ast.Expr{}},
+			},
+		},
+		{
+			name:  "Let",
+			input: "(let [x 1 y [x]] y)",
+			expected: []ast.Expr{
+				ast.Let{
+					Bindings: []ast.Binding{
+						{Variable: sym("x"), Value: i64(1)},
+						{Variable: sym("y"), Value: array(sym("x"))},
+					},
+					Body: []ast.Expr{sym("y")},
+				},
+			},
+		},
+		{
+			name:  "Loop, break and continue",
+			input: "(loop [i 0] (f i) (continue) (break) (break i))",
+			expected: []ast.Expr{
+				ast.Loop{
+					Bindings:  []ast.Binding{{Variable: sym("i"), Value: i64(0)}},
+					Condition: ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("i")}},
+					Body:      []ast.Expr{ast.Continue{}, ast.Break{}, ast.Break{Value: sym("i")}},
+				},
+			},
+		},
+		{
+			name:  "When",
+			input: "(when [(f x) 1 2] [y] [else 3]) (when)",
+			expected: []ast.Expr{
+				ast.When{
+					Clauses: []ast.WhenClause{
+						{
+							Condition: ast.Call{Function: sym("f"), Arguments: []ast.Expr{sym("x")}},
+							Body:      []ast.Expr{i64(1), i64(2)},
+						},
+						{Condition: sym("y"), Body: []ast.Expr{}},
+					},
+					Else: []ast.Expr{i64(3)},
+				},
+				ast.When{Clauses: []ast.WhenClause{}},
+			},
+		},
+		{
+			name:  "Struct and tie",
+			input: "(struct Point [x 0] [y 0]) (tie f 1 2)",
+			expected: []ast.Expr{
+				ast.Struct{
+					Name: sym("Point"),
+					Fields: []ast.Binding{
+						{Variable: sym("x"), Value: i64(0)},
+						{Variable: sym("y"), Value: i64(0)},
+					},
+				},
+				ast.Tie{Function: sym("f"), Args: []ast.Expr{i64(1), i64(2)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(lex.NewLexer(tt.input)).Parse()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			for _, diff := range ast.DiffAll(tt.expected, got) {
+				t.Errorf("expected and got differ at %s", diff)
+			}
+
+			// The printer must produce source that parses back into the same forms, whether they are
+			// broken across lines or not.
+			for _, printer := range []ast.Printer{ast.DefaultPrinter, {Indent: 1, Width: 1}} {
+				printed := printer.PrintAll(got)
+				reparsed, err := NewParser(lex.NewLexer(printed)).Parse()
+				if err != nil {
+					t.Fatalf("unexpected error when parsing printed forms:\n%s\n%s", printed, err)
+				}
+				if diffs := ast.DiffAll(got, reparsed); len(diffs) > 0 {
+					t.Errorf("printed forms do not parse back:\n%s\nfirst difference at %s", printed, diffs[0])
+				}
+			}
+		})
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+		reason ParseFailure
+	}{
+		{"Unclosed call", "(f\n 1", 1, 0, EofInForm},
+		{"Unexpected closer", "1 )", 1, 2, UnexpectedCloser},
+		{"Mismatched closer", "(f 1]", 1, 4, MismatchedCloser},
+		{"Unsupported token", "(f . x)", 1, 3, UnsupportedToken},
+		{"Quote at EOF", "(f '", 1, 3, EofInForm},
+		{"Deref at EOF", "@", 1, 0, EofInForm},
+		{"Quoted closer", "(f ,@)", 1, 5, UnexpectedCloser},
+		{"Empty call", "\n  ()", 2, 2, EmptyCall},
+		{"Invalid escape", `"\q"`, 1, 1, InvalidString},
+		{"Incomplete escape after text", `(f "ab\x4")`, 1, 6, InvalidString},
+		{"Def without name", "(def 1 2)", 1, 5, ExpectedSymbol},
+		{"Def with boolean name", "(def true 2)", 1, 5, ExpectedSymbol},
+		{"Def without value", "(def x)", 1, 6, MissingForm},
+		{"Def with extra form", "(def x 1 2)", 1, 9, TooManyForms},
+		{"Fun without parameters", "(fun f x)", 1, 7, ExpectedVector},
+		{"Non-symbol parameter", "(lambda [x 1] x)", 1, 11, ExpectedSymbol},
+		{"Rest without name", "(lambda [&] 1)", 1, 10, ExpectedSymbol},
+		{"Parameter after rest", "(lambda [& r x] 1)", 1, 13, ParameterAfterRest},
+		{"Ampersand outside parameters", "(f & x)", 1, 3, UnsupportedToken},
+		{"Odd bindings", "(let [x 1 y] y)", 1, 11, OddBindings},
+		{"Loop without condition", "(loop [])", 1, 8, MissingForm},
+		{"Odd map", "{a 1 b}", 1, 6, OddMap},
+		{"Non-atom key", "{[a] 1}", 1, 1, NonAtomKey},
+		{"Duplicate key", "{a 1 a 2}", 1, 5, DuplicateKey},
+		{"Empty when clause", "(when [])", 1, 6, EmptyClause},
+		{"Clause after else", "(when [else 1] [x 2])", 1, 15, MisplacedElse},
+		{"Struct field without default", "(struct P [x])", 1, 12, MissingForm},
+		{"Unclosed special form", "(let [x 1]", 1, 0, EofInForm},
+		{"Non-atom set element", "#{a (f)}", 1, 4, NonAtomElement},
+		{"Duplicate set element", "#{a b a}", 1, 6, DuplicateElement},
+		{"Duplicate rune key", `{\a 1 \a 2}`, 1, 6, DuplicateKey},
+		{"Duplicate big int element", "#{99999999999999999999 1 99999999999999999999}", 1, 25, DuplicateElement},
+		{"Unclosed set", "(f #{a)", 1, 6, MismatchedCloser},
+		{"Map with a mismatched closer", "{:a 1)", 1, 5, MismatchedCloser},
+		{"Map with a mismatched closer after a key", "{:a]", 1, 3, MismatchedCloser},
+		{"Set with a mismatched closer", "#{1 2]", 1, 5, MismatchedCloser},
+		{"Discard at EOF", "(f)\n#_", 2, 0, EofInForm},
+		{"Discarded closer", "(f #_)", 1, 5, UnexpectedCloser},
+		{"Error in discarded form", "#_ (f 1]", 1, 7, MismatchedCloser},
+		{"Conditional without list", "#? [:harp 1]", 1, 3, ExpectedList},
+		{"Non-keyword feature", "#?(harp 1)", 1, 3, NonKeywordFeature},
+		{"Odd conditional", "#?(:harp 1 :default)", 1, 19, OddConditional},
+		{"Unclosed conditional", "#?(:harp", 1, 0, EofInForm},
+		{"Conditional binding vector", "(let #?(:harp [x 1]) x)", 1, 5, ConditionalSyntax},
+		{"Conditional parameter", "(fun f [#?(:harp x)])", 1, 8, ConditionalSyntax},
+		{"Conditional name", "(def #?(:harp x) 1)", 1, 5, ConditionalSyntax},
+		{"Conditional after the last form", "(def x 1 #?(:harp 2))", 1, 9, TooManyForms},
+		{"Vector as metadata", "^[:a] x", 1, 1, ExpectedMetadata},
+		{"Metadata at EOF", "^:a", 1, 0, EofInForm},
+		{"Metadata of nothing", "(f ^:a)", 1, 6, UnexpectedCloser},
+		{"Metadata before a definition name", "(def ^x y 1)", 1, 6, ExpectedMetadata},
+		{"Field after a space", "(f a). x", 1, 5, ExpectedField},
+		{"Field that is not a symbol", "(f a).:x", 1, 5, ExpectedField},
+		{"Field at EOF", "(f a).", 1, 5, ExpectedField},
+		{"Assigned field of a call", "(set (f).x 1)", 1, 5, ExpectedSymbol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(lex.NewLexer(tt.input)).Parse()
+			perr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("expected a parse error, got: %v", err)
+			}
+
+			if !perr.Reason.Same(tt.reason) {
+				t.Errorf("expected failure:\n> %s\ngot:\n> %s", tt.reason, perr.Reason)
+			}
+			if perr.Line != tt.line || perr.Column != tt.column {
+				t.Errorf(
+					"expected error at line %d column %d, got line %d column %d",
+					tt.line, tt.column, perr.Line, perr.Column,
+				)
+			}
+		})
+	}
+}
+
+func TestParserLexicalError(t *testing.T) {
+	_, err := NewParser(lex.NewLexer("(f 1.2.3)")).Parse()
+	lerr, ok := err.(*lex.LexicalError)
+	if !ok {
+		t.Fatalf("expected a lexical error, got: %v", err)
+	}
+
+	if lerr.Reason != lex.TwoDotsInFloat {
+		t.Errorf("expected failure:\n> %s\ngot:\n> %s", lex.TwoDotsInFloat, lerr.Reason)
+	}
+}
+
+func TestParserUnterminatedComment(t *testing.T) {
+	for _, input := range []string{"#| unterminated", "(def x 1) #| unterminated"} {
+		for _, keep := range []bool{true, false} {
+			_, err := NewParser(lex.NewLexer(input, lex.WithComments(keep))).Parse()
+			if !errors.Is(err, lex.EofInComment) {
+				t.Errorf("expected %q to fail with %q when keeping comments is %t, got: %v",
+					input, lex.EofInComment, keep, err)
+			}
+		}
+	}
+}
+
+func TestParseFailureCodes(t *testing.T) {
+	_, err := NewParser(lex.NewLexer("(f 1]")).Parse()
+	if !errors.Is(err, MismatchedCloser) || errors.Is(err, UnexpectedCloser) {
+		t.Errorf("expected %q to match only %q", err, MismatchedCloser)
+	}
+
+	var failure ParseFailure
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected %q to contain a parse failure", err)
+	}
+	if failure.Code() != "PAR0003" || failure.Detail() != `"]"` {
+		t.Errorf("expected code PAR0003 and detail, got %q and %q", failure.Code(), failure.Detail())
+	}
+
+	seen := map[string]ParseFailure{}
+	for failure, code := range parseCodes {
+		if other, ok := seen[code]; ok {
+			t.Errorf("code %s is shared by %q and %q", code, failure, other)
+		}
+		seen[code] = failure
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "(let [x 1]\n  (f x <<END\nab\nEND))\n'(g [é 2])"
+	tree, err := NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	let := tree[0].(ast.Let)
+	call := let.Body[0].(ast.Call)
+	quote := tree[1].(ast.Quote)
+	array := quote.Form.(ast.Call).Arguments[0].(ast.Array)
+
+	tests := []struct {
+		name       string
+		node       ast.Node
+		start, end string
+	}{
+		{"Let", let, "1:0", "4:5"},
+		{"Binding", let.Bindings[0], "1:6", "1:9"},
+		{"Symbol", call.Arguments[0].(ast.Symbol), "2:5", "2:6"},
+		{"Heredoc", call.Arguments[1].(ast.String), "2:7", "4:3"},
+		{"Call", call, "2:2", "4:4"},
+		{"Quote", quote, "5:0", "5:10"},
+		{"Array with a wide rune", array, "5:4", "5:9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if start, end := tt.node.Pos().String(), tt.node.End().String(); start != tt.start || end != tt.end {
+				t.Errorf("expected %s-%s, got %s-%s", tt.start, tt.end, start, end)
+			}
+		})
+	}
+
+	if got := array.End().Offset - array.Pos().Offset; got != len("[é 2]") {
+		t.Errorf("expected the array to span %d bytes, got %d", len("[é 2]"), got)
+	}
+}
+
+func TestNodeIdentifiers(t *testing.T) {
+	input := "(def ^:private x 1)\n(let [y '(f x)] {:a [y #{2}]})"
+	parse := func() []ast.Expr {
+		tree, err := NewParser(lex.NewLexer(input)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+
+	tree := parse()
+	ids := identities(tree)
+	seen := map[ast.NodeID]bool{}
+	for _, id := range ids {
+		if !id.IsValid() || seen[id] {
+			t.Errorf("expected distinct valid identifiers, got %v", ids)
+			break
+		}
+		seen[id] = true
+	}
+
+	if again := identities(parse()); !reflect.DeepEqual(again, ids) {
+		t.Errorf("expected the same identifiers when parsing again, got:\n%v\nthen:\n%v", ids, again)
+	}
+
+	// Children are completed, and thus identified, before their parent.
+	let := tree[1].(ast.Let)
+	if body := let.Body[0].(ast.Map); body.ID <= let.Bindings[0].ID || let.ID <= body.ID {
+		t.Errorf("expected the binding, the body and the let to be identified in order, got %d %d %d",
+			let.Bindings[0].ID, body.ID, let.ID)
+	}
+}
+
+func TestNestedConditionals(t *testing.T) {
+	// Each conditional is parsed once: nesting is linear, and every node gets a single identifier.
+	depth := 40
+	input := strings.Repeat("#?(:other x :harp ", depth) + "1" + strings.Repeat(")", depth)
+	tree, err := NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ast.NodeID(3*depth + 1) // The keywords and symbols of every level, then 1.
+	if len(tree) != 1 || tree[0].(ast.Int64).ID != expected {
+		t.Errorf("expected 1 identified by %d, got %#v", expected, tree)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `(def ^{:doc "Doubles."} double (lambda [x] (* x 2)))
+(fun ^:private f [a & more] (when [(< a 1) 'a] [(> a 2) @b] [else 3.5 #{"set"}]))
+^:deprecated (struct Point [x 0] [y 0])
+(loop [i 0] (< i 10) (set i (+ i 1)) (break i) (continue))
+(let [m {:k [1 2]}] (tie m :k) ^:k v ` + "`(a ,b ,@c))\n(+ 1 99999999999999999999)"
+	forms, err := NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(forms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ast.UnmarshalExprs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, forms) {
+		t.Errorf("expected the forms to survive a round trip through JSON, got:\n%s\ninstead of:\n%s",
+			ast.PrintAll(got), ast.PrintAll(forms))
+	}
+}
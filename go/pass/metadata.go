@@ -0,0 +1,32 @@
+package pass
+
+import "mooss/harp/ast"
+
+func init() {
+	Register(Rewriting("strip-metadata", "remove the metadata of forms and definitions", stripMetadata))
+}
+
+// stripMetadata removes metadata, which is only meant for tools and does not change the value of
+// forms.
+func stripMetadata(expr ast.Expr) ast.Expr {
+	switch node := expr.(type) {
+	case ast.Meta:
+		return node.Form
+	case ast.Def:
+		if node.Meta != nil {
+			node.Meta = nil
+			return node
+		}
+	case ast.Fun:
+		if node.Meta != nil {
+			node.Meta = nil
+			return node
+		}
+	case ast.Struct:
+		if node.Meta != nil {
+			node.Meta = nil
+			return node
+		}
+	}
+	return expr
+}
@@ -0,0 +1,137 @@
+// Package pass composes the transformations applied to syntax trees between parsing and evaluation,
+// like desugaring or constant folding.
+// Each transformation is a Pass registered under a name, so that a pipeline can be assembled from
+// names and every pass can be tested on its own.
+package pass
+
+import (
+	"fmt"
+	"mooss/harp/ast"
+	"sort"
+	"strings"
+)
+
+////////////
+// Errors //
+////////////
+
+// Failure describes why a pipeline cannot be built.
+// It can be followed by additional information specified after `: `.
+type Failure string
+
+func (f Failure) Error() string {
+	return string(f)
+}
+
+// Is makes errors.Is compare failures by kind, ignoring the additional information.
+func (f Failure) Is(target error) bool {
+	other, ok := target.(Failure)
+	return ok && f.Cause() == other.Cause()
+}
+
+func (f Failure) Cause() string {
+	cause, _, _ := strings.Cut(string(f), ": ")
+	return cause
+}
+
+const UnknownPass Failure = "met unknown pass"
+
+// with adds the faulty value to a failure.
+func (f Failure) with(value string) Failure {
+	return Failure(string(f) + ": " + value)
+}
+
+// Error is the error returned by a pass of a pipeline.
+type Error struct {
+	Pass string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pass %s: %s", e.Pass, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+////////////
+// Passes //
+////////////
+
+// Pass transforms the top-level forms of a file.
+type Pass struct {
+	Name string
+	// Doc is a one-line description of the pass.
+	Doc string
+	Run func(forms []ast.Expr) ([]ast.Expr, error)
+}
+
+// Rewriting returns a pass rewriting each form with fn, as done by ast.Rewrite.
+func Rewriting(name, doc string, fn func(ast.Expr) ast.Expr) *Pass {
+	return &Pass{Name: name, Doc: doc, Run: func(forms []ast.Expr) ([]ast.Expr, error) {
+		res := make([]ast.Expr, len(forms))
+		for i, form := range forms {
+			res[i] = ast.Rewrite(form, fn)
+		}
+		return res, nil
+	}}
+}
+
+// registry holds the passes that can be part of a pipeline, indexed by name. Passes are registered
+// by the init functions of the files implementing them.
+var registry = map[string]*Pass{}
+
+// Register makes passes available to pipelines, replacing the passes with the same names.
+func Register(passes ...*Pass) {
+	for _, pass := range passes {
+		registry[pass.Name] = pass
+	}
+}
+
+// Lookup returns the registered pass named name.
+func Lookup(name string) (*Pass, bool) {
+	pass, ok := registry[name]
+	return pass, ok
+}
+
+// Names returns the names of the registered passes, sorted.
+func Names() []string {
+	res := make([]string, 0, len(registry))
+	for name := range registry {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+///////////////
+// Pipelines //
+///////////////
+
+// Pipeline is a sequence of passes, each one transforming the result of the previous one.
+type Pipeline []*Pass
+
+// NewPipeline returns the pipeline made of the registered passes with the given names, in order.
+func NewPipeline(names ...string) (Pipeline, error) {
+	res := make(Pipeline, len(names))
+	for i, name := range names {
+		pass, ok := Lookup(name)
+		if !ok {
+			return nil, UnknownPass.with(name)
+		}
+		res[i] = pass
+	}
+	return res, nil
+}
+
+// Run applies the passes in order, stopping at the first error.
+func (pl Pipeline) Run(forms []ast.Expr) ([]ast.Expr, error) {
+	for _, pass := range pl {
+		var err error
+		if forms, err = pass.Run(forms); err != nil {
+			return nil, &Error{pass.Name, err}
+		}
+	}
+	return forms, nil
+}
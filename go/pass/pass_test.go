@@ -0,0 +1,81 @@
+package pass
+
+import (
+	"errors"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"slices"
+	"testing"
+)
+
+func parseForms(t *testing.T, input string) []ast.Expr {
+	t.Helper()
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return forms
+}
+
+func TestStripMetadata(t *testing.T) {
+	forms := parseForms(t, "(def ^:private x ^{:doc \"D.\"} [^:k y])\n(fun ^:inline f [] 1)")
+	pipeline, err := NewPipeline("strip-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pipeline.Run(forms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "(def x [y])\n(fun f [] 1)\n"; ast.PrintAll(got) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, ast.PrintAll(got))
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	failure := errors.New("no symbols allowed")
+	Register(
+		Rewriting("test-double", "double integers", func(expr ast.Expr) ast.Expr {
+			if n, ok := expr.(ast.Int64); ok {
+				n.Value *= 2
+				return n
+			}
+			return expr
+		}),
+		&Pass{Name: "test-reject", Run: func(forms []ast.Expr) ([]ast.Expr, error) {
+			for _, form := range forms {
+				if _, ok := form.(ast.Symbol); ok {
+					return nil, failure
+				}
+			}
+			return forms, nil
+		}},
+	)
+	defer delete(registry, "test-double")
+	defer delete(registry, "test-reject")
+
+	if names := Names(); !slices.Contains(names, "test-double") || !slices.IsSorted(names) {
+		t.Errorf("expected the sorted names of the passes, got %v", names)
+	}
+
+	pipeline, err := NewPipeline("test-double", "test-reject", "test-double")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pipeline.Run(parseForms(t, "[1 2] 3"))
+	if err != nil || ast.PrintAll(got) != "[4 8]\n12\n" {
+		t.Errorf("expected the passes to be applied in order, got %q (%v)", ast.PrintAll(got), err)
+	}
+
+	_, err = pipeline.Run(parseForms(t, "x"))
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Pass != "test-reject" || !errors.Is(err, failure) {
+		t.Errorf("expected the error of test-reject, got %v", err)
+	}
+
+	if _, err := NewPipeline("test-double", "missing"); !errors.Is(err, UnknownPass) {
+		t.Errorf("expected %q, got %v", UnknownPass, err)
+	}
+}
@@ -0,0 +1,293 @@
+// Package position converts between the ways of locating a character in source text: byte offsets,
+// as carried by tokens, and lines with columns counted in runes, as shown in diagnostics, or in
+// UTF-16 code units, as exchanged with editors through the Language Server Protocol.
+// A LineIndex is built once per text and maps an offset to its line with a binary search.
+//
+// Conversions are checked: a location that does not exist in the text, or that falls inside of a
+// character, is an error rather than being silently clamped.
+package position
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+////////////
+// Errors //
+////////////
+
+// Failure describes why a location cannot be converted.
+// It can be followed by additional information specified after `: `.
+type Failure string
+
+func (f Failure) Error() string {
+	return string(f)
+}
+
+// Is makes errors.Is compare failures by kind, ignoring the additional information.
+func (f Failure) Is(target error) bool {
+	other, ok := target.(Failure)
+	return ok && f.Cause() == other.Cause()
+}
+
+func (f Failure) Cause() string {
+	cause, _, _ := strings.Cut(string(f), ": ")
+	return cause
+}
+
+const (
+	OffsetOutOfRange Failure = "offset is outside of the text"
+	LineOutOfRange   Failure = "line is outside of the text"
+	ColumnOutOfRange Failure = "column is past the end of the line"
+	InsideCharacter  Failure = "location is inside of a character"
+)
+
+// with adds the faulty value to a failure.
+func (f Failure) with(value int) Failure {
+	return Failure(string(f) + ": " + strconv.Itoa(value))
+}
+
+///////////////
+// Encodings //
+///////////////
+
+// Encoding is the unit in which columns are counted.
+type Encoding uint8
+
+const (
+	// Runes counts Unicode code points, like the columns of tokens (with a tab width of 1).
+	Runes Encoding = iota
+	// UTF16 counts UTF-16 code units, the default encoding of the Language Server Protocol.
+	UTF16
+	// Bytes counts UTF-8 bytes.
+	Bytes
+)
+
+var encodingNames = [...]string{
+	Runes: "runes",
+	UTF16: "utf-16",
+	Bytes: "bytes",
+}
+
+func (enc Encoding) String() string {
+	if int(enc) < len(encodingNames) {
+		return encodingNames[enc]
+	}
+	return "unknown encoding"
+}
+
+// width returns the number of units of a rune.
+func (enc Encoding) width(r rune, size int) int {
+	switch enc {
+	case UTF16:
+		if n := utf16.RuneLen(r); n > 0 {
+			return n
+		}
+		return 1 // Invalid UTF-8 is decoded as U+FFFD.
+	case Bytes:
+		return size
+	}
+	return 1
+}
+
+// count returns the number of units of text.
+func (enc Encoding) count(text string) int {
+	if enc == Bytes {
+		return len(text)
+	}
+
+	res := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		res += enc.width(r, size)
+		i += size
+	}
+	return res
+}
+
+// offset returns the byte offset of column in line.
+func (enc Encoding) offset(line string, column int) (int, error) {
+	if column < 0 {
+		return 0, ColumnOutOfRange.with(column)
+	}
+
+	units := 0
+	for i := 0; i < len(line); {
+		if units == column {
+			return i, nil
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		units += enc.width(r, size)
+		i += size
+		if units > column {
+			return 0, InsideCharacter.with(column)
+		}
+	}
+
+	if units == column {
+		return len(line), nil
+	}
+	return 0, ColumnOutOfRange.with(column)
+}
+
+////////////////
+// Line index //
+////////////////
+
+// Position locates a character by its line, starting at 1 like the lines of tokens, and its column,
+// starting at 0 and counted in some encoding.
+// Language Server Protocol positions start their lines at 0 and must be shifted accordingly.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// LineIndex holds the offsets where the lines of a text start, so that an offset is mapped to its
+// line with a binary search rather than by scanning the text from its start.
+// It is built once per text and does not keep the text, columns are therefore counted in bytes.
+// Only line feeds end lines, like in the lexer: a carriage return is a character of its line.
+type LineIndex struct {
+	// starts are the offsets of the first byte of every line.
+	starts []int
+
+	// size is the length of the indexed text.
+	size int
+}
+
+// NewLineIndex indexes the lines of content.
+func NewLineIndex(content string) *LineIndex {
+	starts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineIndex{starts: starts, size: len(content)}
+}
+
+// Lines returns the number of lines of the text, a text ending with a newline having an empty last
+// line.
+func (idx *LineIndex) Lines() int {
+	return len(idx.starts)
+}
+
+// Bounds returns the offsets of the first byte of a line and of its newline, or of the end of the
+// text for the last line.
+func (idx *LineIndex) Bounds(line int) (start, end int, err error) {
+	if line < 1 || line > len(idx.starts) {
+		return 0, 0, LineOutOfRange.with(line)
+	}
+
+	start, end = idx.starts[line-1], idx.size
+	if line < len(idx.starts) {
+		end = idx.starts[line] - 1
+	}
+	return start, end, nil
+}
+
+// Position returns the position of offset with a column in bytes, offset being at most the length
+// of the text.
+func (idx *LineIndex) Position(offset int) (Position, error) {
+	if offset < 0 || offset > idx.size {
+		return Position{}, OffsetOutOfRange.with(offset)
+	}
+
+	line := sort.Search(len(idx.starts), func(i int) bool { return idx.starts[i] > offset })
+	return Position{Line: line, Column: offset - idx.starts[line-1]}, nil
+}
+
+// Offset returns the offset of a position whose column is in bytes, which may be the end of its
+// line.
+func (idx *LineIndex) Offset(pos Position) (int, error) {
+	start, end, err := idx.Bounds(pos.Line)
+	if err != nil {
+		return 0, err
+	}
+	if pos.Column < 0 || start+pos.Column > end {
+		return 0, ColumnOutOfRange.with(pos.Column)
+	}
+	return start + pos.Column, nil
+}
+
+//////////
+// Text //
+//////////
+
+// Text is a source text along with its line index, converting between offsets and the columns of
+// any encoding.
+type Text struct {
+	content string
+	index   *LineIndex
+}
+
+// NewText indexes the lines of content.
+func NewText(content string) *Text {
+	return &Text{content: content, index: NewLineIndex(content)}
+}
+
+// Content returns the indexed text.
+func (t *Text) Content() string {
+	return t.content
+}
+
+// Index returns the line index of the text.
+func (t *Text) Index() *LineIndex {
+	return t.index
+}
+
+// Lines returns the number of lines of the text, a text ending with a newline having an empty last
+// line.
+func (t *Text) Lines() int {
+	return t.index.Lines()
+}
+
+// Line returns the content of a line without its newline, or an empty string when there is no such
+// line.
+func (t *Text) Line(line int) string {
+	start, end, err := t.index.Bounds(line)
+	if err != nil {
+		return ""
+	}
+	return t.content[start:end]
+}
+
+// Position returns the position of the character starting at offset, or of the end of the text
+// when offset is its length.
+func (t *Text) Position(offset int, enc Encoding) (Position, error) {
+	if offset >= 0 && offset < len(t.content) && !utf8.RuneStart(t.content[offset]) {
+		return Position{}, InsideCharacter.with(offset)
+	}
+
+	pos, err := t.index.Position(offset)
+	if err != nil {
+		return Position{}, err
+	}
+	pos.Column = enc.count(t.content[offset-pos.Column : offset])
+	return pos, nil
+}
+
+// Offset returns the byte offset of a position, which may be the end of its line.
+func (t *Text) Offset(pos Position, enc Encoding) (int, error) {
+	start, end, err := t.index.Bounds(pos.Line)
+	if err != nil {
+		return 0, err
+	}
+
+	column, err := enc.offset(t.content[start:end], pos.Column)
+	if err != nil {
+		return 0, err
+	}
+	return start + column, nil
+}
+
+// Convert changes the encoding of the column of a position.
+func (t *Text) Convert(pos Position, from, to Encoding) (Position, error) {
+	offset, err := t.Offset(pos, from)
+	if err != nil {
+		return Position{}, err
+	}
+	return t.Position(offset, to)
+}
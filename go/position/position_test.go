@@ -0,0 +1,129 @@
+package position
+
+import (
+	"errors"
+	"testing"
+)
+
+// text mixes ASCII, a 2-byte rune (é), a 3-byte rune (€) and a rune outside of the BMP (𝄞), which
+// takes 4 bytes and 2 UTF-16 code units.
+const text = "(def é\n  \"€𝄞\")\n"
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		runes  Position
+		utf16  Position
+		bytes  Position
+	}{
+		{"Start", 0, Position{1, 0}, Position{1, 0}, Position{1, 0}},
+		{"Wide rune", 5, Position{1, 5}, Position{1, 5}, Position{1, 5}},
+		{"End of line", 7, Position{1, 6}, Position{1, 6}, Position{1, 7}},
+		{"Second line", 11, Position{2, 3}, Position{2, 3}, Position{2, 3}},
+		{"After a surrogate pair", 18, Position{2, 5}, Position{2, 6}, Position{2, 10}},
+		{"End of text", len(text), Position{3, 0}, Position{3, 0}, Position{3, 0}},
+	}
+
+	index := NewText(text)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for enc, expected := range map[Encoding]Position{Runes: tt.runes, UTF16: tt.utf16, Bytes: tt.bytes} {
+				got, err := index.Position(tt.offset, enc)
+				if err != nil || got != expected {
+					t.Errorf("expected %v in %s, got %v (%v)", expected, enc, got, err)
+				}
+
+				offset, err := index.Offset(expected, enc)
+				if err != nil || offset != tt.offset {
+					t.Errorf("expected offset %d for %v in %s, got %d (%v)", tt.offset, expected, enc, offset, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	index := NewText(text)
+	got, err := index.Convert(Position{2, 6}, UTF16, Runes)
+	if err != nil || got != (Position{2, 5}) {
+		t.Errorf("expected 2:5, got %v (%v)", got, err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	index := NewText(text)
+	tests := []struct {
+		name     string
+		convert  func() error
+		expected Failure
+	}{
+		{"Negative offset", func() error { _, err := index.Position(-1, Runes); return err }, OffsetOutOfRange},
+		{"Offset past the end", func() error { _, err := index.Position(len(text)+1, Runes); return err }, OffsetOutOfRange},
+		{"Offset inside of a rune", func() error { _, err := index.Position(6, Runes); return err }, InsideCharacter},
+		{"Line 0", func() error { _, err := index.Offset(Position{0, 0}, Runes); return err }, LineOutOfRange},
+		{"Line past the end", func() error { _, err := index.Offset(Position{4, 0}, Runes); return err }, LineOutOfRange},
+		{"Column past the end", func() error { _, err := index.Offset(Position{1, 7}, Runes); return err }, ColumnOutOfRange},
+		{"Inside of a surrogate pair", func() error { _, err := index.Offset(Position{2, 5}, UTF16); return err }, InsideCharacter},
+		{"Inside of a UTF-8 sequence", func() error { _, err := index.Offset(Position{1, 6}, Bytes); return err }, InsideCharacter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.convert(); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLine(t *testing.T) {
+	index := NewText(text)
+	if index.Lines() != 3 {
+		t.Errorf("expected 3 lines, got %d", index.Lines())
+	}
+	for line, expected := range map[int]string{0: "", 1: "(def é", 2: `  "€𝄞")`, 3: "", 4: ""} {
+		if got := index.Line(line); got != expected {
+			t.Errorf("expected line %d to be %q, got %q", line, expected, got)
+		}
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	index := NewLineIndex("ab\n\ncd\r\n")
+	tests := []struct {
+		name     string
+		offset   int
+		expected Position
+	}{
+		{"Start", 0, Position{1, 0}},
+		{"Newline", 2, Position{1, 2}},
+		{"Empty line", 3, Position{2, 0}},
+		{"Carriage return", 6, Position{3, 2}},
+		{"End of text", 8, Position{4, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := index.Position(tt.offset)
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, got, err)
+			}
+
+			offset, err := index.Offset(tt.expected)
+			if err != nil || offset != tt.offset {
+				t.Errorf("expected offset %d, got %d (%v)", tt.offset, offset, err)
+			}
+		})
+	}
+
+	if start, end, err := index.Bounds(3); start != 4 || end != 7 || err != nil {
+		t.Errorf("expected line 3 to span 4:7, got %d:%d (%v)", start, end, err)
+	}
+	if _, err := index.Offset(Position{1, 3}); !errors.Is(err, ColumnOutOfRange) {
+		t.Errorf("expected %q past the end of a line, got %v", ColumnOutOfRange, err)
+	}
+	if _, err := index.Position(9); !errors.Is(err, OffsetOutOfRange) {
+		t.Errorf("expected %q past the end of the text, got %v", OffsetOutOfRange, err)
+	}
+}
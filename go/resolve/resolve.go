@@ -0,0 +1,322 @@
+// Package resolve links the symbols of a syntax tree to their definitions, following the lexical
+// scoping of the evaluator: definitions live in the scope of the file, of a function or of a let or
+// loop, and a symbol refers to the closest definition of its name.
+//
+// The body of a function runs after it is defined, so it sees every definition of the enclosing
+// scopes, including the definitions that follow it: a function can call a function defined later
+// in the file. Other forms only see the definitions preceding them.
+//
+// Results are recorded in tables keyed by node, so only the nodes built by a parser are resolved.
+package resolve
+
+import (
+	"mooss/harp/ast"
+	"slices"
+	"sort"
+)
+
+/////////////////
+// Definitions //
+/////////////////
+
+// Kind is the kind of form defining a name.
+type Kind uint8
+
+const (
+	// Predeclared names are defined by the environment, like builtins.
+	Predeclared Kind = iota
+	// Global names are defined by def.
+	Global
+	// Function names are defined by fun.
+	Function
+	// Parameter names are defined by the parameters of fun and lambda.
+	Parameter
+	// Local names are defined by the bindings of let and loop.
+	Local
+	// Struct names are defined by struct.
+	Struct
+)
+
+var kindNames = [...]string{
+	Predeclared: "predeclared",
+	Global:      "global",
+	Function:    "function",
+	Parameter:   "parameter",
+	Local:       "local",
+	Struct:      "struct",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown kind"
+}
+
+// Definition is a name defined in a scope.
+type Definition struct {
+	Name string
+	Kind Kind
+
+	// Symbol is where the name is defined, the zero Symbol for predeclared names.
+	Symbol ast.Symbol
+
+	// Scope is the scope where the name is defined.
+	Scope *Scope
+
+	// Uses are the symbols referring to the definition, in source order.
+	Uses []ast.Symbol
+}
+
+// Shadowing is a definition hiding a definition of the same name from an enclosing scope.
+type Shadowing struct {
+	Definition *Definition
+	Shadowed   *Definition
+}
+
+////////////
+// Scopes //
+////////////
+
+// Scope holds the names defined by a form.
+type Scope struct {
+	Parent *Scope
+
+	// Node is the form introducing the scope (fun, lambda, let or loop), nil for the scope of
+	// predeclared names and for the scope of the file.
+	Node ast.Expr
+
+	// names are the definitions of the scope, the last definition of a name replacing the others.
+	names map[string]*Definition
+
+	// deferred resolve the bodies of the functions defined in the scope, once all its definitions
+	// are known.
+	deferred []func()
+}
+
+func newScope(parent *Scope, node ast.Expr) *Scope {
+	return &Scope{Parent: parent, Node: node, names: map[string]*Definition{}}
+}
+
+// Lookup returns the closest definition of name, from this scope or an enclosing one.
+func (s *Scope) Lookup(name string) (*Definition, bool) {
+	for cur := s; cur != nil; cur = cur.Parent {
+		if def, ok := cur.names[name]; ok {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names defined in this very scope, sorted.
+func (s *Scope) Names() []string {
+	res := make([]string, 0, len(s.names))
+	for name := range s.names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+////////////////
+// Resolution //
+////////////////
+
+// Info is the result of the resolution of a file.
+type Info struct {
+	// Universe is the scope of the predeclared names, the parent of File.
+	Universe *Scope
+	// File is the scope of the top-level definitions.
+	File *Scope
+
+	// Defs maps the symbols defining names to their definition.
+	Defs ast.Table[*Definition]
+	// Uses maps the symbols referring to names to their definition.
+	Uses ast.Table[*Definition]
+	// Scopes maps the forms introducing a scope to it.
+	Scopes ast.Table[*Scope]
+
+	// Unbound are the symbols referring to names that are not defined, in source order.
+	Unbound []ast.Symbol
+	// Shadowings are the definitions hiding other definitions, in source order.
+	Shadowings []Shadowing
+}
+
+// Resolve resolves the top-level forms of a file, in an environment defining the predeclared
+// names.
+func Resolve(forms []ast.Expr, predeclared []string) *Info {
+	universe := newScope(nil, nil)
+	for _, name := range predeclared {
+		universe.names[name] = &Definition{Name: name, Kind: Predeclared, Scope: universe}
+	}
+
+	r := &resolver{info: &Info{
+		Universe: universe,
+		File:     newScope(universe, nil),
+		Defs:     ast.Table[*Definition]{},
+		Uses:     ast.Table[*Definition]{},
+		Scopes:   ast.Table[*Scope]{},
+	}}
+	r.scope = r.info.File
+	r.walk(forms)
+	r.close()
+
+	slices.SortFunc(r.info.Unbound, byOffset)
+	slices.SortFunc(r.info.Shadowings, func(a, b Shadowing) int {
+		return byOffset(a.Definition.Symbol, b.Definition.Symbol)
+	})
+	return r.info
+}
+
+func byOffset(a, b ast.Symbol) int {
+	return a.Pos().Offset - b.Pos().Offset
+}
+
+// resolver is the Visitor resolving the symbols of the forms that do not introduce a scope.
+type resolver struct {
+	info  *Info
+	scope *Scope
+}
+
+func (r *resolver) walk(forms []ast.Expr) {
+	for _, form := range forms {
+		ast.Walk(r, form)
+	}
+}
+
+func (r *resolver) Visit(expr ast.Expr) ast.Visitor {
+	switch expr := expr.(type) {
+	case ast.Symbol:
+		r.use(expr)
+	case ast.Assign:
+		ast.Walk(r, expr.Value)
+		ast.Walk(r, expr.Target)
+		return nil
+	case ast.Def:
+		ast.Walk(r, expr.Value)
+		r.define(expr.Name, Global)
+		return nil
+	case ast.Fun:
+		r.define(expr.Name, Function)
+		r.function(expr, expr.Parameters, expr.Rest, expr.Body)
+		return nil
+	case ast.Lambda:
+		r.function(expr, expr.Parameters, expr.Rest, expr.Body)
+		return nil
+	case ast.Let:
+		r.open(expr)
+		r.bind(expr.Bindings)
+		r.walk(expr.Body)
+		r.close()
+		return nil
+	case ast.Loop:
+		r.open(expr)
+		r.bind(expr.Bindings)
+		ast.Walk(r, expr.Condition)
+		r.walk(expr.Body)
+		r.close()
+		return nil
+	case ast.Struct:
+		r.define(expr.Name, Struct)
+		for _, field := range expr.Fields { // Field names are not variables.
+			ast.Walk(r, field.Value)
+		}
+		return nil
+	case ast.Access: // Field names are not variables.
+		ast.Walk(r, expr.Target)
+		return nil
+	case ast.Meta: // Metadata is not evaluated.
+		ast.Walk(r, expr.Form)
+		return nil
+	case ast.Quote:
+		return nil
+	case ast.Quasiquote:
+		ast.Walk(unquotes{r}, expr.Form)
+		return nil
+	}
+	return r
+}
+
+// unquotes is the Visitor of quasiquoted forms, where only unquoted forms are resolved.
+type unquotes struct {
+	r *resolver
+}
+
+func (u unquotes) Visit(expr ast.Expr) ast.Visitor {
+	switch expr := expr.(type) {
+	case ast.Unquote:
+		ast.Walk(u.r, expr.Form)
+		return nil
+	case ast.UnquoteSplice:
+		ast.Walk(u.r, expr.Form)
+		return nil
+	}
+	return u
+}
+
+// open enters the scope introduced by node.
+func (r *resolver) open(node ast.Expr) {
+	r.scope = newScope(r.scope, node)
+	r.info.Scopes.Set(node, r.scope)
+}
+
+// close resolves the bodies of the functions defined in the current scope, now that all its
+// definitions are known, then leaves the scope.
+func (r *resolver) close() {
+	scope := r.scope
+	for len(scope.deferred) > 0 {
+		body := scope.deferred[0]
+		scope.deferred = scope.deferred[1:]
+		body()
+		r.scope = scope
+	}
+	r.scope = scope.Parent
+}
+
+// function defers the resolution of a function to the end of the current scope.
+func (r *resolver) function(node ast.Expr, params []ast.Symbol, rest *ast.Symbol, body []ast.Expr) {
+	scope := r.scope
+	scope.deferred = append(scope.deferred, func() {
+		r.scope = scope
+		r.open(node)
+		for _, param := range params {
+			r.define(param, Parameter)
+		}
+		if rest != nil {
+			r.define(*rest, Parameter)
+		}
+		r.walk(body)
+		r.close()
+	})
+}
+
+// bind defines bindings in order, each value seeing the previous bindings.
+func (r *resolver) bind(bindings []ast.Binding) {
+	for _, binding := range bindings {
+		ast.Walk(r, binding.Value)
+		r.define(binding.Variable, Local)
+	}
+}
+
+func (r *resolver) define(symbol ast.Symbol, kind Kind) {
+	def := &Definition{Name: symbol.Name, Kind: kind, Symbol: symbol, Scope: r.scope}
+	if shadowed, ok := r.scope.Parent.Lookup(symbol.Name); ok {
+		if _, redefined := r.scope.names[symbol.Name]; !redefined {
+			r.info.Shadowings = append(r.info.Shadowings, Shadowing{def, shadowed})
+		}
+	}
+	r.scope.names[symbol.Name] = def
+	r.info.Defs.Set(symbol, def)
+}
+
+func (r *resolver) use(symbol ast.Symbol) {
+	def, ok := r.scope.Lookup(symbol.Name)
+	if !ok {
+		r.info.Unbound = append(r.info.Unbound, symbol)
+		return
+	}
+	// Bodies are resolved after the forms following them, keep the uses in source order.
+	i, _ := slices.BinarySearchFunc(def.Uses, symbol, byOffset)
+	def.Uses = slices.Insert(def.Uses, i, symbol)
+	r.info.Uses.Set(symbol, def)
+}
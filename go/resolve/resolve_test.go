@@ -0,0 +1,185 @@
+package resolve
+
+import (
+	"fmt"
+	"mooss/harp/ast"
+	"mooss/harp/lex"
+	"mooss/harp/parse"
+	"slices"
+	"testing"
+)
+
+var predeclared = []string{"+", "print", "nil"}
+
+func resolve(t *testing.T, input string) *Info {
+	t.Helper()
+	forms, err := parse.NewParser(lex.NewLexer(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Resolve(forms, predeclared)
+}
+
+// describe renders a definition as its kind and the position of its symbol.
+func describe(def *Definition) string {
+	return fmt.Sprintf("%s %s", def.Kind, def.Symbol.Pos())
+}
+
+func TestUses(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// expected maps the positions of the uses to the description of their definition.
+		expected map[string]string
+	}{
+		{
+			name:     "Predeclared",
+			input:    "(print nil)",
+			expected: map[string]string{"1:1": "predeclared -", "1:7": "predeclared -"},
+		},
+		{
+			name:     "Global",
+			input:    "(def x 1)\n(+ x 1)",
+			expected: map[string]string{"2:1": "predeclared -", "2:3": "global 1:5"},
+		},
+		{
+			name:     "Sequential bindings",
+			input:    "(let [x 1 y x] y)",
+			expected: map[string]string{"1:12": "local 1:6", "1:15": "local 1:10"},
+		},
+		{
+			name:     "Parameters",
+			input:    "(fun f [a & r] (f a r))",
+			expected: map[string]string{"1:16": "function 1:5", "1:18": "parameter 1:8", "1:20": "parameter 1:12"},
+		},
+		{
+			name:     "Function body sees later definitions",
+			input:    "(fun f [] (g))\n(fun g [] 1)",
+			expected: map[string]string{"1:11": "function 2:5"},
+		},
+		{
+			name:     "Shadowed binding",
+			input:    "(def x 1)\n(let [x x] x)",
+			expected: map[string]string{"2:8": "global 1:5", "2:11": "local 2:6"},
+		},
+		{
+			name:     "Loop",
+			input:    "(loop [i 0] i (set i (+ i 1)))",
+			expected: map[string]string{"1:12": "local 1:7", "1:19": "local 1:7", "1:22": "predeclared -", "1:24": "local 1:7"},
+		},
+		{
+			name:     "Quotation",
+			input:    "(def x 1)\n'(y x)\n`(y ,x ,@x)",
+			expected: map[string]string{"3:5": "global 1:5", "3:9": "global 1:5"},
+		},
+		{
+			name:     "Metadata",
+			input:    "(def x 1)\n^{:doc y} x",
+			expected: map[string]string{"2:10": "global 1:5"},
+		},
+		{
+			name:     "Fields",
+			input:    "(def p 1)\n(set p.x p.y)",
+			expected: map[string]string{"2:5": "global 1:5", "2:9": "global 1:5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := resolve(t, tt.input)
+			if len(info.Unbound) > 0 {
+				t.Errorf("unexpected unbound symbols: %v", info.Unbound)
+			}
+
+			got := map[string]string{}
+			for _, def := range info.Defs {
+				for _, use := range def.Uses {
+					got[use.Pos().String()] = describe(def)
+				}
+			}
+			for _, name := range predeclared {
+				def, _ := info.Universe.Lookup(name)
+				for _, use := range def.Uses {
+					got[use.Pos().String()] = describe(def)
+				}
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected:\n%v\ngot:\n%v", tt.expected, got)
+			}
+			for id, def := range info.Uses {
+				if !slices.ContainsFunc(def.Uses, func(use ast.Symbol) bool { return use.ID == id }) {
+					t.Errorf("expected %s to list its use %d", def.Name, id)
+				}
+			}
+		})
+	}
+}
+
+func TestUnbound(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Undefined", "(f 1)", []string{"f 1:1"}},
+		{"Defined later", "(print x)\n(def x 1)", []string{"x 1:7"}},
+		{"Own value", "(def x (+ x 1))", []string{"x 1:10"}},
+		{"Out of scope", "(let [x 1] x)\nx", []string{"x 2:0"}},
+		{"Later binding", "(let [x y y 1] x)", []string{"y 1:8"}},
+		{"Assigned", "(set x 1)", []string{"x 1:5"}},
+		{"Field of unbound", "(set p.x q.y)", []string{"p 1:5", "q 1:9"}},
+		{"Source order", "(fun f [] a)\n(b)", []string{"a 1:10", "b 2:1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, symbol := range resolve(t, tt.input).Unbound {
+				got = append(got, fmt.Sprintf("%s %s", symbol.Name, symbol.Pos()))
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestShadowings(t *testing.T) {
+	input := "(def x 1)\n(def x 2)\n(fun f [x print] (let [f 1] f))"
+	got := []string{}
+	for _, shadowing := range resolve(t, input).Shadowings {
+		got = append(got, describe(shadowing.Definition)+" hides "+describe(shadowing.Shadowed))
+	}
+
+	// Redefining x in the same scope does not hide the first definition.
+	expected := []string{
+		"parameter 3:8 hides global 2:5",
+		"parameter 3:10 hides predeclared -",
+		"local 3:23 hides function 3:5",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	forms, err := parse.NewParser(lex.NewLexer("(fun f [a] (let [b a] b))")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := Resolve(forms, nil)
+
+	fun := forms[0].(ast.Fun)
+	let := fun.Body[0].(ast.Let)
+	scope, ok := info.Scopes.Get(let)
+	if !ok {
+		t.Fatal("expected let to introduce a scope")
+	}
+	if outer, _ := info.Scopes.Get(fun); scope.Parent != outer || outer.Parent != info.File {
+		t.Error("expected the scope of let to be nested in the scope of the function")
+	}
+	if !slices.Equal(scope.Names(), []string{"b"}) || !slices.Equal(info.File.Names(), []string{"f"}) {
+		t.Errorf("expected b in let and f in the file, got %v and %v", scope.Names(), info.File.Names())
+	}
+}
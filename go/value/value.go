@@ -0,0 +1,262 @@
+// Package value defines the runtime values of Harp: how they are represented, which ones are
+// truthy, when two values are equal and can be used as map keys, and how they are printed.
+package value
+
+import (
+	"fmt"
+	"math/big"
+	"mooss/harp/ast"
+	"mooss/harp/env"
+	"mooss/harp/lex"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Value is a runtime value, represented by a plain Go value:
+//   - nil, bool, int64, float64, string, byte and rune for primitives, Keyword for keywords and
+//     Symbol for quoted symbols,
+//   - *big.Int for the ints outside the range of int64, see Int, and *big.Rat for the rationals
+//     that are not ints, see Rat,
+//   - []any for arrays, map[any]any for maps and map[any]struct{} for sets,
+//   - *Closure and *Builtin for functions,
+//   - *StructType for the types defined by struct and *Struct for their instances,
+//   - pointers to Go types implementing fmt.Stringer for values provided by builtins (e.g. caches).
+//
+// Plain values keep the evaluator and the builtins free of boxing: an int is stored in arrays and
+// passed to Go functions as is. As a consequence, Value is not an interface with methods, since
+// int64 or []any cannot implement one. The operations common to all values are the functions of
+// this package instead: Truthy, Equal, Hashable and String.
+type Value = any
+
+// Keyword is the value of a keyword, it holds the name without the leading colon.
+type Keyword string
+
+// Symbol is the value of a quoted symbol.
+type Symbol string
+
+// Closure is a function defined in Harp code.
+// It captures the environment where it was created, so that free symbols of its body are resolved
+// lexically.
+type Closure struct {
+	// Name is the name of the function, empty for lambdas.
+	Name string
+
+	Parameters []ast.Symbol
+	// Rest receives the arguments following the parameters as an array, nil for a fixed arity.
+	Rest *ast.Symbol
+	Body []ast.Expr
+	Env  *env.Environment
+}
+
+// Builtin is a function implemented in Go.
+type Builtin struct {
+	Name string
+	Fun  func(args []any) (any, error)
+	// Calls implements instead of Fun the builtins calling the functions they are given, which they
+	// do with call, so that these calls are a part of the call chain of the builtin.
+	Calls func(call Caller, args []any) (any, error)
+}
+
+// Caller calls a function value with already evaluated arguments.
+type Caller func(function any, args []any) (any, error)
+
+// StructType is a type defined by struct, shared by its instances.
+type StructType struct {
+	Name   string
+	Fields []string
+	// Defaults are the default values of the fields, evaluated in Env for each instance that is not
+	// given them, so that instances never share a default value.
+	Defaults []ast.Expr
+	Env      *env.Environment
+}
+
+// Struct is an instance of a struct, holding the values of the fields in the order of its type.
+type Struct struct {
+	Type   *StructType
+	Fields []any
+}
+
+// Field returns the value of the field named name.
+func (s *Struct) Field(name string) (any, bool) {
+	i := slices.Index(s.Type.Fields, name)
+	if i < 0 {
+		return nil, false
+	}
+	return s.Fields[i], true
+}
+
+// SetField sets the value of the field named name, and returns false when there is no such field.
+func (s *Struct) SetField(name string, value any) bool {
+	i := slices.Index(s.Type.Fields, name)
+	if i < 0 {
+		return false
+	}
+	s.Fields[i] = value
+	return true
+}
+
+////////////////
+// Truthiness //
+////////////////
+
+// Truthy returns false for nil and false, true for everything else.
+func Truthy(value Value) bool {
+	return value != nil && value != false
+}
+
+//////////////
+// Equality //
+//////////////
+
+// Equal tells whether two values are equal: collections and structs are equal when their elements
+// are, other values when they are identical. Numbers of different types are never equal, so that
+// 1 and 1.0 are distinct map keys.
+func Equal(a, b Value) bool {
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, Equal)
+	case map[any]any:
+		b, ok := b.(map[any]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case map[any]struct{}:
+		b, ok := b.(map[any]struct{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for element := range a {
+			if _, ok := b[element]; !ok {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		b, ok := b.(*Struct)
+		return ok && a.Type == b.Type && slices.EqualFunc(a.Fields, b.Fields, Equal)
+	case *big.Int:
+		b, ok := b.(*big.Int)
+		return ok && a.Cmp(b) == 0
+	case *big.Rat:
+		b, ok := b.(*big.Rat)
+		return ok && a.Cmp(b) == 0
+	}
+
+	if !Hashable(a) || !Hashable(b) {
+		return false
+	}
+	return a == b
+}
+
+// Hashable tells whether a value can be a map key or a set element, which are compared with Go's
+// ==: collections, structs, big ints and rationals cannot, since they are compared by content.
+func Hashable(value Value) bool {
+	switch value.(type) {
+	case *Struct, *big.Int, *big.Rat:
+		return false
+	}
+	return value == nil || reflect.TypeOf(value).Comparable()
+}
+
+////////////////////////
+// Ints and rationals //
+////////////////////////
+
+// Int returns the value of an integer: an int64 when it fits, so that a *big.Int value is always
+// outside the range of int64 and each integer has a single representation.
+// x must not be modified afterwards since it can be the value.
+func Int(x *big.Int) Value {
+	if x.IsInt64() {
+		return x.Int64()
+	}
+	return x
+}
+
+// Rat returns the value of a rational: an int when its denominator is 1, so that a *big.Rat value
+// is never an int and each rational has a single representation.
+// x must not be modified afterwards since it can be the value.
+func Rat(x *big.Rat) Value {
+	if x.IsInt() {
+		return Int(new(big.Int).Set(x.Num()))
+	}
+	return x
+}
+
+///////////
+// Print //
+///////////
+
+// String returns the representation of a value, as printed by the REPL.
+func String(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(value)
+	case rune:
+		return lex.EncodeChar(value)
+	case Keyword:
+		return ":" + string(value)
+	case Symbol:
+		return string(value)
+	case *big.Int:
+		return value.String()
+	case *big.Rat:
+		return value.String()
+	case float64:
+		res := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(res, ".eIN") { // Keep floats distinguishable from ints.
+			res += ".0"
+		}
+		return res
+	case []any:
+		elements := make([]string, len(value))
+		for i, element := range value {
+			elements[i] = String(element)
+		}
+		return "[" + strings.Join(elements, " ") + "]"
+	case map[any]any:
+		pairs := make([]string, 0, len(value))
+		for key, val := range value {
+			pairs = append(pairs, String(key)+" "+String(val))
+		}
+		slices.Sort(pairs) // Maps are unordered, sorting keeps the output stable.
+		return "{" + strings.Join(pairs, " ") + "}"
+	case map[any]struct{}:
+		elements := make([]string, 0, len(value))
+		for element := range value {
+			elements = append(elements, String(element))
+		}
+		slices.Sort(elements)
+		return "#{" + strings.Join(elements, " ") + "}"
+	case *Closure:
+		if value.Name == "" {
+			return "<lambda>"
+		}
+		return "<fun " + value.Name + ">"
+	case *Builtin:
+		return "<builtin " + value.Name + ">"
+	case *StructType:
+		return "<struct " + value.Name + ">"
+	case *Struct:
+		elements := []string{value.Type.Name}
+		for i, field := range value.Type.Fields {
+			elements = append(elements, ":"+field, String(value.Fields[i]))
+		}
+		return "<" + strings.Join(elements, " ") + ">"
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,162 @@
+package value
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+var point = &StructType{Name: "Point", Fields: []string{"x", "y"}}
+
+// twoTo64 returns a new big int holding 2^64.
+func twoTo64() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), 64)
+}
+
+func TestTruthy(t *testing.T) {
+	for _, v := range []Value{nil, false} {
+		if Truthy(v) {
+			t.Errorf("expected %s to be falsy", String(v))
+		}
+	}
+	for _, v := range []Value{true, int64(0), "", []any{}, Keyword("false")} {
+		if !Truthy(v) {
+			t.Errorf("expected %s to be truthy", String(v))
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	builtin := &Builtin{Name: "f"}
+	tests := []struct {
+		name  string
+		a, b  Value
+		equal bool
+	}{
+		{"Nil", nil, nil, true},
+		{"Ints", int64(1), int64(1), true},
+		{"Int and float", int64(1), 1.0, false},
+		{"NaN", math.NaN(), math.NaN(), false},
+		{"Keyword and string", Keyword("a"), "a", false},
+		{"Keyword and symbol", Keyword("a"), Symbol("a"), false},
+		{"Arrays", []any{int64(1), []any{"a"}}, []any{int64(1), []any{"a"}}, true},
+		{"Arrays of different lengths", []any{int64(1)}, []any{int64(1), int64(2)}, false},
+		{"Nil and empty array", nil, []any{}, false},
+		{"Maps", map[any]any{"a": []any{nil}}, map[any]any{"a": []any{nil}}, true},
+		{"Maps with different values", map[any]any{"a": int64(1)}, map[any]any{"a": int64(2)}, false},
+		{"Maps with different keys", map[any]any{"a": int64(1)}, map[any]any{"b": int64(1)}, false},
+		{"Sets", map[any]struct{}{"a": {}}, map[any]struct{}{"a": {}}, true},
+		{"Sets with different elements", map[any]struct{}{"a": {}}, map[any]struct{}{"b": {}}, false},
+		{"Map and set", map[any]any{}, map[any]struct{}{}, false},
+		{"Structs", &Struct{point, []any{int64(1), int64(2)}}, &Struct{point, []any{int64(1), int64(2)}}, true},
+		{"Structs with different fields", &Struct{point, []any{int64(1), nil}}, &Struct{point, []any{nil, nil}}, false},
+		{
+			"Structs of different types",
+			&Struct{point, []any{nil, nil}},
+			&Struct{&StructType{Name: "Point", Fields: []string{"x", "y"}}, []any{nil, nil}},
+			false,
+		},
+		{"Big ints", twoTo64(), twoTo64(), true},
+		{"Big int and int", twoTo64(), int64(0), false},
+		{"Rationals", big.NewRat(1, 2), big.NewRat(2, 4), true},
+		{"Rational and float", big.NewRat(1, 2), 0.5, false},
+		{"Same function", builtin, builtin, true},
+		{"Different functions", builtin, &Builtin{Name: "f"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b); got != tt.equal {
+				t.Errorf("expected %t, got %t", tt.equal, got)
+			}
+			if got := Equal(tt.b, tt.a); got != tt.equal {
+				t.Errorf("expected %t in reverse, got %t", tt.equal, got)
+			}
+		})
+	}
+}
+
+func TestHashable(t *testing.T) {
+	for _, v := range []Value{nil, int64(1), 1.5, "a", Keyword("a"), Symbol("a"), '\n', &Builtin{}} {
+		if !Hashable(v) {
+			t.Errorf("expected %s to be hashable", String(v))
+		}
+	}
+	unhashable := []Value{
+		[]any{}, map[any]any{}, map[any]struct{}{}, &Struct{point, []any{nil, nil}}, twoTo64(), big.NewRat(1, 2),
+	}
+	for _, v := range unhashable {
+		if Hashable(v) {
+			t.Errorf("expected %s not to be hashable", String(v))
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value    Value
+		expected string
+	}{
+		{nil, "nil"},
+		{"a\n", `"a\n"`},
+		{'λ', `\λ`},
+		{Keyword("k"), ":k"},
+		{Symbol("s"), "s"},
+		{2.0, "2.0"},
+		{twoTo64(), "18446744073709551616"},
+		{big.NewRat(-2, 6), "-1/3"},
+		{math.Inf(1), "+Inf"},
+		{[]any{int64(1), true}, "[1 true]"},
+		{map[any]any{"b": int64(2), "a": int64(1)}, `{"a" 1 "b" 2}`},
+		{map[any]struct{}{Keyword("b"): {}, Keyword("a"): {}}, "#{:a :b}"},
+		{&Closure{}, "<lambda>"},
+		{&Closure{Name: "f"}, "<fun f>"},
+		{&Builtin{Name: "str"}, "<builtin str>"},
+		{&Struct{point, []any{int64(1), []any{}}}, "<Point :x 1 :y []>"},
+		{point, "<struct Point>"},
+	}
+
+	for _, tt := range tests {
+		if got := String(tt.value); got != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, got)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	s := &Struct{point, []any{int64(1), int64(2)}}
+	if y, ok := s.Field("y"); !ok || y != int64(2) {
+		t.Errorf("expected y to be 2, got %v (%t)", y, ok)
+	}
+	if _, ok := s.Field("z"); ok {
+		t.Error("expected no z field")
+	}
+
+	if !s.SetField("x", int64(3)) || s.Fields[0] != int64(3) {
+		t.Errorf("expected x to be set to 3, got %v", s.Fields[0])
+	}
+	if s.SetField("z", int64(3)) {
+		t.Error("expected no z field to set")
+	}
+}
+
+func TestInt(t *testing.T) {
+	if got := Int(big.NewInt(-5)); got != int64(-5) {
+		t.Errorf("expected a small big int to be demoted, got %#v", got)
+	}
+	if got, ok := Int(twoTo64()).(*big.Int); !ok || got.Cmp(twoTo64()) != 0 {
+		t.Errorf("expected 2^64 to stay a big int, got %#v", got)
+	}
+}
+
+func TestRat(t *testing.T) {
+	if got := Rat(big.NewRat(4, 2)); got != int64(2) {
+		t.Errorf("expected 4/2 to be demoted to 2, got %#v", got)
+	}
+	if got := Rat(new(big.Rat).SetFrac(twoTo64(), big.NewInt(1))); !Equal(got, twoTo64()) {
+		t.Errorf("expected 2^64/1 to be demoted to a big int, got %s", String(got))
+	}
+	if got, ok := Rat(big.NewRat(1, 3)).(*big.Rat); !ok || got.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("expected 1/3 to stay a rational, got %#v", got)
+	}
+}